@@ -0,0 +1,128 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package auditlog writes an append-only record of scaling decisions and
+// portal mutations as JSON lines, for customers who need an audit trail of
+// infrastructure changes. The log is rotated by size; rotated files can
+// optionally be uploaded to an S3-compatible bucket.
+package auditlog
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	klog "k8s.io/klog/v2"
+)
+
+// Record is one audit entry.
+type Record struct {
+	Time    time.Time         `json:"time"`
+	Action  string            `json:"action"`
+	Details map[string]string `json:"details,omitempty"`
+}
+
+var (
+	mutex    sync.Mutex
+	path     string
+	maxSize  int64
+	file     *os.File
+	size     int64
+	uploader *s3Uploader
+)
+
+// Enable starts audit logging to the given file. The file is rotated once it
+// grows beyond maxSizeMB megabytes.
+func Enable(logPath string, maxSizeMB int) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+	opened, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log %s: %v", logPath, err)
+	}
+	info, err := opened.Stat()
+	if err != nil {
+		opened.Close()
+		return fmt.Errorf("failed to stat audit log %s: %v", logPath, err)
+	}
+	path = logPath
+	maxSize = int64(maxSizeMB) * 1024 * 1024
+	file = opened
+	size = info.Size()
+	return nil
+}
+
+// EnableS3Upload makes every rotated audit log file get uploaded to an
+// S3-compatible bucket. Credentials are read from the AUDIT_LOG_S3_ACCESS_KEY
+// and AUDIT_LOG_S3_SECRET_KEY environment variables.
+func EnableS3Upload(endpoint string, bucket string, region string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	uploader = &s3Uploader{
+		endpoint:  endpoint,
+		bucket:    bucket,
+		region:    region,
+		accessKey: os.Getenv("AUDIT_LOG_S3_ACCESS_KEY"),
+		secretKey: os.Getenv("AUDIT_LOG_S3_SECRET_KEY"),
+	}
+}
+
+// Log appends one audit record. It is a no-op until Enable has been called.
+// Failures are logged and never affect the main loop.
+func Log(action string, details map[string]string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	if file == nil {
+		return
+	}
+	line, err := json.Marshal(Record{Time: time.Now(), Action: action, Details: details})
+	if err != nil {
+		klog.Errorf("Failed to marshal audit record: %v", err)
+		return
+	}
+	line = append(line, '\n')
+	written, err := file.Write(line)
+	if err != nil {
+		klog.Errorf("Failed to write audit record: %v", err)
+		return
+	}
+	size += int64(written)
+	if size >= maxSize {
+		rotate()
+	}
+}
+
+// rotate renames the current log aside and opens a fresh one. Callers must
+// hold the mutex.
+func rotate() {
+	rotatedPath := fmt.Sprintf("%s.%s", path, time.Now().UTC().Format("20060102T150405Z"))
+	file.Close()
+	if err := os.Rename(path, rotatedPath); err != nil {
+		klog.Errorf("Failed to rotate audit log: %v", err)
+	} else if uploader != nil {
+		go uploader.upload(rotatedPath)
+	}
+	opened, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		klog.Errorf("Failed to reopen audit log after rotation: %v", err)
+		file = nil
+		return
+	}
+	file = opened
+	size = 0
+}
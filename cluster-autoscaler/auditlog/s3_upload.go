@@ -0,0 +1,74 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auditlog
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
+	klog "k8s.io/klog/v2"
+)
+
+// s3Uploader uploads rotated audit log files to an S3-compatible bucket using
+// signature v4 authentication, so it works against S3, MinIO and similar
+// object stores.
+type s3Uploader struct {
+	endpoint  string
+	bucket    string
+	region    string
+	accessKey string
+	secretKey string
+}
+
+// upload puts the given file in the bucket under its base name. Failures are
+// logged; the rotated file stays on disk either way.
+func (u *s3Uploader) upload(path string) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		klog.Errorf("Failed to read rotated audit log %s for upload: %v", path, err)
+		return
+	}
+	url := fmt.Sprintf("%s/%s/%s", u.endpoint, u.bucket, filepath.Base(path))
+	request, err := http.NewRequest("PUT", url, bytes.NewReader(content))
+	if err != nil {
+		klog.Errorf("Failed to build audit log upload request: %v", err)
+		return
+	}
+	signer := v4.NewSigner(credentials.NewStaticCredentials(u.accessKey, u.secretKey, ""))
+	if _, err := signer.Sign(request, bytes.NewReader(content), "s3", u.region, time.Now()); err != nil {
+		klog.Errorf("Failed to sign audit log upload request: %v", err)
+		return
+	}
+	client := &http.Client{Timeout: 60 * time.Second}
+	response, err := client.Do(request)
+	if err != nil {
+		klog.Errorf("Failed to upload audit log %s: %v", path, err)
+		return
+	}
+	defer response.Body.Close()
+	if response.StatusCode >= 300 {
+		klog.Errorf("Audit log upload of %s rejected: %s", path, response.Status)
+		return
+	}
+	klog.V(1).Infof("Uploaded audit log %s to %s", path, url)
+}
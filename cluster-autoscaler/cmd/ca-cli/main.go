@@ -0,0 +1,159 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// ca-cli is a small companion command line tool for the autoscaler. It reads
+// the status endpoint and prints worker pool sizes, pending pods, blocked
+// scale downs with their reasons and in-flight deletions as tables, so an
+// operator does not have to parse the raw JSON.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"k8s.io/autoscaler/cluster-autoscaler/statusreport"
+)
+
+var (
+	address     = flag.String("address", "http://localhost:8085", "Base URL of the autoscaler metrics endpoint serving /statusz.")
+	token       = flag.String("token", "", "Bearer token to send in the Authorization header, matching the autoscaler --metrics-auth-token flag.")
+	timeout     = flag.Duration("timeout", 10*time.Second, "Timeout for the status request.")
+	explainNode = flag.String("explain-node", "", "If set, print why this node is or is not being scaled down instead of the full status.")
+)
+
+func fetchJSON(path string, out interface{}) error {
+	request, err := http.NewRequest("GET", *address+path, nil)
+	if err != nil {
+		return err
+	}
+	if *token != "" {
+		request.Header.Set("Authorization", "Bearer "+*token)
+	}
+	client := &http.Client{Timeout: *timeout}
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("endpoint returned %s", response.Status)
+	}
+	if err := json.NewDecoder(response.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %v", err)
+	}
+	return nil
+}
+
+func formatTime(t time.Time, now time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return fmt.Sprintf("%s (%s ago)", t.Format(time.RFC3339), now.Sub(t).Round(time.Second))
+}
+
+func printReport(report *statusreport.Report) {
+	now := time.Now()
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+
+	fmt.Printf("Status as of %s\n", formatTime(report.Time, now))
+
+	fmt.Println("\nWorker pools:")
+	fmt.Fprintln(writer, "POOL\tNODES")
+	for pool, size := range report.WorkerPoolSizes {
+		fmt.Fprintf(writer, "%s\t%d\n", pool, size)
+	}
+	writer.Flush()
+
+	fmt.Println("\nScale activity:")
+	fmt.Fprintf(writer, "Last scale up\t%s\n", formatTime(report.LastScaleUpTime, now))
+	fmt.Fprintf(writer, "Last scale down\t%s\n", formatTime(report.LastScaleDownDeleteTime, now))
+	fmt.Fprintf(writer, "Last scale down failure\t%s\n", formatTime(report.LastScaleDownFailTime, now))
+	fmt.Fprintf(writer, "Scale down in cooldown\t%v\n", report.ScaleDownInCooldown)
+	writer.Flush()
+
+	fmt.Printf("\nPending pods (%d):\n", len(report.PendingPods))
+	for _, pod := range report.PendingPods {
+		fmt.Printf("  %s\n", pod)
+	}
+
+	fmt.Printf("\nScale down candidates (%d):\n", len(report.UnneededNodes))
+	fmt.Fprintln(writer, "NODE\tUNNEEDED FOR")
+	for _, node := range report.UnneededNodes {
+		fmt.Fprintf(writer, "%s\t%s\n", node.Name, now.Sub(node.Since).Round(time.Second))
+	}
+	writer.Flush()
+
+	fmt.Printf("\nBlocked scale downs (%d):\n", len(report.UnremovableNodes))
+	fmt.Fprintln(writer, "NODE\tREASON")
+	for _, node := range report.UnremovableNodes {
+		fmt.Fprintf(writer, "%s\t%s\n", node.Name, node.Reason)
+	}
+	writer.Flush()
+
+	fmt.Printf("\nDeletions in progress (%d):\n", len(report.DeletionsInProgress))
+	fmt.Fprintln(writer, "NODE\tELAPSED")
+	for _, deletion := range report.DeletionsInProgress {
+		fmt.Fprintf(writer, "%s\t%s\n", deletion.Node, deletion.Elapsed)
+	}
+	writer.Flush()
+}
+
+func printExplanation(explanation *statusreport.NodeExplanation) {
+	now := time.Now()
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(writer, "Node\t%s\n", explanation.Node)
+	fmt.Fprintf(writer, "CPU utilization\t%.2f\n", explanation.CpuUtilization)
+	fmt.Fprintf(writer, "Memory utilization\t%.2f\n", explanation.MemUtilization)
+	if explanation.UnneededSince != nil {
+		fmt.Fprintf(writer, "Unneeded since\t%s\n", formatTime(*explanation.UnneededSince, now))
+	} else {
+		fmt.Fprintf(writer, "Unneeded since\t-\n")
+	}
+	reason := explanation.Reason
+	if reason == "" {
+		reason = "-"
+	}
+	fmt.Fprintf(writer, "Blocking reason\t%s\n", reason)
+	if explanation.BlockingPod != "" {
+		fmt.Fprintf(writer, "Blocking pod\t%s (%s)\n", explanation.BlockingPod, explanation.BlockingPodReason)
+	}
+	fmt.Fprintf(writer, "Scale down in cooldown\t%v\n", explanation.ScaleDownInCooldown)
+	writer.Flush()
+}
+
+func main() {
+	flag.Parse()
+	if *explainNode != "" {
+		explanation := &statusreport.NodeExplanation{}
+		if err := fetchJSON("/explain?node="+*explainNode, explanation); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to read explanation for node %s from %s: %v\n", *explainNode, *address, err)
+			os.Exit(1)
+		}
+		printExplanation(explanation)
+		return
+	}
+	report := &statusreport.Report{}
+	if err := fetchJSON("/statusz", report); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read autoscaler status from %s: %v\n", *address, err)
+		os.Exit(1)
+	}
+	printReport(report)
+}
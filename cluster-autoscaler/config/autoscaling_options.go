@@ -30,6 +30,24 @@ type GpuLimits struct {
 	Max int64
 }
 
+const (
+	// LoopOverrunSkip drops an autoscaling iteration that fires while the
+	// previous one is still running.
+	LoopOverrunSkip = "skip"
+	// LoopOverrunQueue delays such an iteration until the previous one
+	// finishes instead of dropping it.
+	LoopOverrunQueue = "queue"
+)
+
+const (
+	// PreDeletionHookSkip deletes a node anyway when its pre-deletion hooks
+	// fail or time out.
+	PreDeletionHookSkip = "skip"
+	// PreDeletionHookAbort keeps a node when its pre-deletion hooks fail or
+	// time out, so it is retried in a later iteration.
+	PreDeletionHookAbort = "abort"
+)
+
 // NodeGroupAutoscalingOptions contain various options to customize how autoscaling of
 // a given NodeGroup works. Different options can be used for each NodeGroup.
 type NodeGroupAutoscalingOptions struct {
@@ -44,6 +62,8 @@ type NodeGroupAutoscalingOptions struct {
 	ScaleDownUnneededTime time.Duration
 	// ScaleDownUnreadyTime represents how long an unready node should be unneeded before it is eligible for scale down
 	ScaleDownUnreadyTime time.Duration
+	// DisableSoftTaint turns off soft tainting of unneeded nodes for this NodeGroup.
+	DisableSoftTaint bool
 }
 
 // AutoscalingOptions contain various options to customize how autoscaling works
@@ -53,6 +73,22 @@ type AutoscalingOptions struct {
 	NodeGroupDefaults NodeGroupAutoscalingOptions
 	// MaxEmptyBulkDelete is a number of empty nodes that can be removed at the same time.
 	MaxEmptyBulkDelete int
+	// MaxScaleUpIncrement is the maximum number of nodes a single scale up can request
+	// from the portal. Zero means no limit.
+	MaxScaleUpIncrement int
+	// SpotWorkerPool is the name of a spot/preemptible worker pool scale ups should
+	// prefer for pods with no pool requirement. Empty string disables spot usage.
+	SpotWorkerPool string
+	// SpotFallbackPool is the on-demand pool used when the spot pool cannot fulfill
+	// a scale up within SpotFallbackTimeout. Empty string means the default pool.
+	SpotFallbackPool string
+	// SpotFallbackTimeout is how long CA waits for spot nodes to join before
+	// requesting the missing capacity from SpotFallbackPool.
+	SpotFallbackTimeout time.Duration
+	// WarmPoolSize is the number of pre-provisioned, cordoned standby nodes CA
+	// maintains. Scale up uncordons warm nodes before calling the portal and scale
+	// down refills the warm pool before deleting nodes. Zero disables the warm pool.
+	WarmPoolSize int
 	// MaxNodesTotal sets the maximum number of nodes in the whole cluster
 	MaxNodesTotal int
 	// MaxCoresTotal sets the maximum number of cores in the whole cluster
@@ -71,10 +107,24 @@ type AutoscalingOptions struct {
 	EstimatorName string
 	// ExpanderNames sets the chain of node group expanders to be used in scale up
 	ExpanderNames string
+	// ExpanderNamesPerPool overrides the expander chain for individual worker pools,
+	// as comma separated pool:expander entries. Pools not listed use ExpanderNames.
+	ExpanderNamesPerPool string
 	// GRPCExpanderCert is the location of the cert passed to the gRPC server for TLS when using the gRPC expander
 	GRPCExpanderCert string
 	// GRPCExpanderURL is the url of the gRPC server when using the gRPC expander
 	GRPCExpanderURL string
+	// WebhookExpanderURL is the HTTP endpoint the options are POSTed to as JSON when using the webhook expander
+	WebhookExpanderURL string
+	// ExpanderRandomSeed fixes the seed of the random expander for reproducible selection. Zero uses a time-based seed.
+	ExpanderRandomSeed int64
+	// ExpanderChainTimeBudget bounds the total time the expander chain may spend per
+	// selection; on exhaustion the remaining expanders are skipped and ties are broken
+	// randomly. Zero means no bound.
+	ExpanderChainTimeBudget time.Duration
+	// ExpanderCacheTTL is how long an expander choice is reused when the option set
+	// derived from the pending pods is unchanged across loops. Zero disables caching.
+	ExpanderCacheTTL time.Duration
 	// IgnoreDaemonSetsUtilization is whether CA will ignore DaemonSet pods when calculating resource utilization for scaling down
 	IgnoreDaemonSetsUtilization bool
 	// IgnoreMirrorPodsUtilization is whether CA will ignore Mirror pods when calculating resource utilization for scaling down
@@ -82,6 +132,90 @@ type AutoscalingOptions struct {
 	// MaxGracefulTerminationSec is maximum number of seconds scale down waits for pods to terminate before
 	// removing the node from cloud provider.
 	MaxGracefulTerminationSec int
+	// MaxDrainParallelism is the maximum number of nodes that can be drained in parallel
+	// when several nodes are selected for removal in one scale down iteration.
+	MaxDrainParallelism int
+	// ForceDrainTimeout is the time CA waits for a drained node to become empty before
+	// force deleting the remaining pods. Zero disables force draining.
+	ForceDrainTimeout time.Duration
+	// ScaleDownRecentPodActivityWindow makes CA skip removal of nodes on which pods
+	// terminated within the window, so capacity periodically reused e.g. by CronJobs
+	// is not deleted. Zero disables the check.
+	ScaleDownRecentPodActivityWindow time.Duration
+	// ScaleDownSimulationTimeout is the maximum time per loop spent simulating
+	// node drains for scale down. Candidates not checked within the budget are
+	// deferred to the next loop. Zero disables the limit.
+	ScaleDownSimulationTimeout time.Duration
+	// ScaleDownAllowOwnerlessPods lets scale down remove nodes running pods
+	// without a controller. Such pods are not recreated elsewhere, so by
+	// default they block the removal of their node.
+	ScaleDownAllowOwnerlessPods bool
+	// NodeGroupLimitDriftGracePeriod is how long the worker count has to stay
+	// outside the configured min/max before the limits are enforced through the
+	// portal, so transient states like a rebooting node do not trigger scale
+	// operations that have to be reversed. Zero enforces immediately.
+	NodeGroupLimitDriftGracePeriod time.Duration
+	// ProactiveConsolidationEnabled turns on the periodic bin-packing pass that
+	// drains whole sets of underutilized workers whose pods fit on the
+	// remaining nodes and shrinks the pool through the portal.
+	ProactiveConsolidationEnabled bool
+	// ConsolidationInterval is how often the consolidation pass runs.
+	ConsolidationInterval time.Duration
+	// ConsolidationUtilizationThreshold is the utilization below which a worker
+	// is considered for consolidation.
+	ConsolidationUtilizationThreshold float64
+	// NodeAutoProvisioningEnabled turns on automatic creation of new worker
+	// groups for pending pods that fit no existing pool, and their deletion
+	// once they have been empty for a while.
+	NodeAutoProvisioningEnabled bool
+	// AutoProvisioningFlavorAllowlist restricts which flavors the
+	// auto-provisioner may use. Empty means every configured flavor is allowed.
+	AutoProvisioningFlavorAllowlist []string
+	// AutoProvisioningMaxFlavorCostPerHour is the highest hourly price of a
+	// flavor the auto-provisioner may pick. Zero means no limit.
+	AutoProvisioningMaxFlavorCostPerHour float64
+	// PredictiveScalingEnabled turns on recording of per-hour demand and
+	// pre-scaling of the worker pool shortly before recurring peaks.
+	PredictiveScalingEnabled bool
+	// PredictiveScalingLeadTime is how far ahead of a predicted peak the pool
+	// is scaled up.
+	PredictiveScalingLeadTime time.Duration
+	// FlavorRightSizingWindow is how long utilization is accumulated per worker
+	// pool before a flavor recommendation is published. Zero disables the
+	// analyzer.
+	FlavorRightSizingWindow time.Duration
+	// LoopOverrunPolicy decides what happens when an autoscaling iteration is
+	// triggered while the previous one is still running, e.g. blocked waiting
+	// on the portal. LoopOverrunSkip drops the new iteration,
+	// LoopOverrunQueue runs it as soon as the previous one finishes.
+	LoopOverrunPolicy string
+	// PreDeletionWebhookURL, when set, is POSTed the name of every node about
+	// to be deleted so external systems can deregister it.
+	PreDeletionWebhookURL string
+	// PreDeletionJobImage, when set, is run as a Job pinned to every node
+	// about to be deleted; the deletion waits for the Job to complete.
+	PreDeletionJobImage string
+	// PreDeletionHookAnnotation, when set, is the node annotation the deletion
+	// waits for before proceeding, so agents on the node can signal that they
+	// have shut down cleanly.
+	PreDeletionHookAnnotation string
+	// PreDeletionHookTimeout bounds how long the pre-deletion hooks of a
+	// single node may take in total.
+	PreDeletionHookTimeout time.Duration
+	// PreDeletionHookFailPolicy decides what happens when a node's
+	// pre-deletion hooks fail or time out: PreDeletionHookSkip deletes the
+	// node anyway, PreDeletionHookAbort keeps it.
+	PreDeletionHookFailPolicy string
+	// NodeRepairEnabled turns on automatic replacement of unhealthy worker
+	// nodes: stuck NotReady or under resource pressure beyond
+	// NodeRepairUnhealthyThreshold.
+	NodeRepairEnabled bool
+	// NodeRepairUnhealthyThreshold is how long a worker must be unhealthy
+	// before it is replaced.
+	NodeRepairUnhealthyThreshold time.Duration
+	// CostReportInterval is how often the cost and savings summary is
+	// published. Zero disables the report.
+	CostReportInterval time.Duration
 	//  Maximum time CA waits for node to be provisioned
 	MaxNodeProvisionTime time.Duration
 	// MaxTotalUnreadyPercentage is the maximum percentage of unready nodes after which CA halts operations
@@ -98,6 +232,9 @@ type AutoscalingOptions struct {
 	NodeGroups []string
 	// ScaleDownEnabled is used to allow CA to scale down the cluster
 	ScaleDownEnabled bool
+	// ScaleDownCordonOnly makes CA cordon and soft taint unneeded nodes without
+	// deleting them through the portal, leaving the actual removal to an external system.
+	ScaleDownCordonOnly bool
 	// ScaleDownDelayAfterAdd sets the duration from the last scale up to the time when CA starts to check scale down options
 	ScaleDownDelayAfterAdd time.Duration
 	// ScaleDownDelayAfterDelete sets the duration between scale down attempts if scale down removes one or more nodes
@@ -147,8 +284,42 @@ type AutoscalingOptions struct {
 	MaxBulkSoftTaintCount int
 	// MaxBulkSoftTaintTime sets the maximum duration of single run of PreferNoSchedule tainting.
 	MaxBulkSoftTaintTime time.Duration
+	// SoftTaintInterval sets the minimum time between two runs of PreferNoSchedule tainting.
+	// Value of 0 means unneeded nodes are soft tainted every loop.
+	SoftTaintInterval time.Duration
+	// DeletionCandidateTaintKey overrides the key of the soft taint put on unneeded nodes.
+	// Empty string keeps the default key.
+	DeletionCandidateTaintKey string
+	// PublishScaleDownCandidateLabel keeps a node label in sync with the
+	// deletion candidate soft taint, so descheduler policies can select
+	// candidate nodes and rebalance their workloads before deletion.
+	PublishScaleDownCandidateLabel bool
+	// ToBeDeletedTaintKey overrides the key of the taint put on nodes being deleted.
+	// Empty string keeps the default key.
+	ToBeDeletedTaintKey string
+	// ToBeDeletedTaintEffect overrides the effect of the taint put on nodes being
+	// deleted, either NoSchedule or NoExecute. Empty string keeps NoSchedule.
+	ToBeDeletedTaintEffect string
 	// IgnoredTaints is a list of taints to ignore when considering a node template for scheduling.
 	IgnoredTaints []string
+	// ScaleDownExcludeTaintKeys is a list of taint keys; a node carrying any of
+	// them is never considered for scale down.
+	ScaleDownExcludeTaintKeys []string
+	// NodeResourcesFromAllocatable makes node resource accounting use
+	// allocatable instead of capacity, excluding what is reserved for the
+	// system and the kubelet.
+	NodeResourcesFromAllocatable bool
+	// EquivalenceIgnoredTolerationKeys is a list of toleration keys dropped
+	// before comparing pods for equivalence grouping, typically keys injected
+	// by admission webhooks.
+	EquivalenceIgnoredTolerationKeys []string
+	// EquivalenceIgnoredContainerNames is a list of container names (including
+	// init containers) dropped before comparing pods for equivalence grouping,
+	// typically sidecars injected by admission webhooks.
+	EquivalenceIgnoredContainerNames []string
+	// EquivalenceIgnoredLabelKeys is a list of pod label keys dropped before
+	// comparing pods for equivalence grouping.
+	EquivalenceIgnoredLabelKeys []string
 	// BalancingExtraIgnoredLabels is a list of labels to additionally ignore when comparing if two node groups are similar.
 	// Labels in BasicIgnoredLabels and the cloud provider-specific ignored labels are always ignored.
 	BalancingExtraIgnoredLabels []string
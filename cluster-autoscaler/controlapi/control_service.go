@@ -0,0 +1,282 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package controlapi exposes a small gRPC service that lets platform tooling
+// manage the autoscaler at runtime: pause and resume scaling, force an
+// immediate loop iteration, arm a debugging snapshot and read the current
+// status. Messages are encoded as JSON instead of protobuf so the fork does
+// not need protoc in its build pipeline.
+package controlapi
+
+import (
+	ctx "context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	klog "k8s.io/klog/v2"
+
+	core_utils "k8s.io/autoscaler/cluster-autoscaler/core/utils"
+	"k8s.io/autoscaler/cluster-autoscaler/statusreport"
+)
+
+// jsonCodec encodes gRPC messages as JSON.
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+func (jsonCodec) Name() string                               { return "json" }
+
+// Empty is the request and response of control methods without a payload.
+type Empty struct{}
+
+// PreScaleRequest announces an expected replica increase before the pods
+// exist, e.g. from an HPA controller or a KEDA scaler, so nodes can be
+// provisioned ahead of the burst.
+type PreScaleRequest struct {
+	// Pool is the worker pool the replicas will land in. Empty targets the
+	// default pool.
+	Pool string `json:"pool,omitempty"`
+	// Replicas is how many additional replicas are expected.
+	Replicas int `json:"replicas"`
+	// CPUMilli and MemoryBytes are the requests of a single replica. When both
+	// are zero the signal is worth one worker node.
+	CPUMilli    int64 `json:"cpuMilli,omitempty"`
+	MemoryBytes int64 `json:"memoryBytes,omitempty"`
+	// TTLSeconds is how long the signal stays valid if scaling is paused or
+	// delayed. Zero defaults to 10 minutes.
+	TTLSeconds int `json:"ttlSeconds,omitempty"`
+}
+
+// StartRefreshRequest starts a rolling node refresh: every worker present when
+// the refresh starts is replaced by a fresh node, a batch at a time, so image
+// or flavor upgrades reuse the autoscaler's drain and cooldown logic.
+type StartRefreshRequest struct {
+	// Pool restricts the refresh to one worker pool. Empty refreshes all
+	// workers.
+	Pool string `json:"pool,omitempty"`
+	// BatchSize is how many nodes are replaced per iteration. Zero defaults
+	// to 1.
+	BatchSize int `json:"batchSize,omitempty"`
+}
+
+// StatusResponse carries the latest status report and the pause state.
+type StatusResponse struct {
+	Paused bool                `json:"paused"`
+	Report statusreport.Report `json:"report"`
+}
+
+// Server implements the autoscaler control service.
+type Server struct {
+	mutex           sync.Mutex
+	paused          bool
+	forceRefresh    func()
+	triggerSnapshot func()
+}
+
+// NewServer creates a control service. forceRefresh requests an immediate main
+// loop iteration, triggerSnapshot arms debugging snapshot collection for the
+// next iteration.
+func NewServer(forceRefresh func(), triggerSnapshot func()) *Server {
+	return &Server{
+		forceRefresh:    forceRefresh,
+		triggerSnapshot: triggerSnapshot,
+	}
+}
+
+// IsPaused tells whether scaling has been paused through the control API.
+func (s *Server) IsPaused() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.paused
+}
+
+// Pause suspends all scale up and scale down activity until Resume is called.
+func (s *Server) Pause(_ ctx.Context, _ *Empty) (*Empty, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if !s.paused {
+		klog.V(0).Info("Scaling paused through the control API")
+	}
+	s.paused = true
+	return &Empty{}, nil
+}
+
+// Resume re-enables scaling after a Pause.
+func (s *Server) Resume(_ ctx.Context, _ *Empty) (*Empty, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.paused {
+		klog.V(0).Info("Scaling resumed through the control API")
+	}
+	s.paused = false
+	return &Empty{}, nil
+}
+
+// ForceRefresh requests an immediate main loop iteration instead of waiting
+// for the next scan interval.
+func (s *Server) ForceRefresh(_ ctx.Context, _ *Empty) (*Empty, error) {
+	s.forceRefresh()
+	return &Empty{}, nil
+}
+
+// TriggerSnapshot arms debugging snapshot collection for the next iteration.
+func (s *Server) TriggerSnapshot(_ ctx.Context, _ *Empty) (*Empty, error) {
+	s.triggerSnapshot()
+	return &Empty{}, nil
+}
+
+// PreScale queues a pre-scale signal and requests an immediate iteration, so
+// nodes for the announced burst are provisioned before the pods are created.
+func (s *Server) PreScale(_ ctx.Context, request *PreScaleRequest) (*Empty, error) {
+	if request.Replicas <= 0 {
+		return nil, fmt.Errorf("replicas must be positive, got %v", request.Replicas)
+	}
+	ttl := time.Duration(request.TTLSeconds) * time.Second
+	if ttl <= 0 {
+		ttl = 10 * time.Minute
+	}
+	core_utils.AddPreScaleSignal(core_utils.PreScaleSignal{
+		Pool:        request.Pool,
+		Replicas:    request.Replicas,
+		CPUMilli:    request.CPUMilli,
+		MemoryBytes: request.MemoryBytes,
+		Expires:     time.Now().Add(ttl),
+	})
+	s.forceRefresh()
+	return &Empty{}, nil
+}
+
+// StartRefresh queues a rolling node refresh and requests an immediate
+// iteration. A refresh that is already running is not restarted.
+func (s *Server) StartRefresh(_ ctx.Context, request *StartRefreshRequest) (*Empty, error) {
+	if request.BatchSize < 0 {
+		return nil, fmt.Errorf("batchSize must not be negative, got %v", request.BatchSize)
+	}
+	klog.V(0).Infof("Rolling refresh requested through the control API for pool %q", request.Pool)
+	core_utils.RequestRefresh(core_utils.RefreshRequest{
+		Pool:      request.Pool,
+		BatchSize: request.BatchSize,
+	})
+	s.forceRefresh()
+	return &Empty{}, nil
+}
+
+// CancelRefresh aborts the running rolling refresh, leaving already replaced
+// nodes in place.
+func (s *Server) CancelRefresh(_ ctx.Context, _ *Empty) (*Empty, error) {
+	klog.V(0).Info("Rolling refresh cancellation requested through the control API")
+	core_utils.RequestRefreshCancel()
+	s.forceRefresh()
+	return &Empty{}, nil
+}
+
+// GetStatus returns the latest status report and the pause state.
+func (s *Server) GetStatus(_ ctx.Context, _ *Empty) (*StatusResponse, error) {
+	return &StatusResponse{
+		Paused: s.IsPaused(),
+		Report: statusreport.Current(),
+	}, nil
+}
+
+func unaryHandler(handle func(ctx.Context, *Empty) (interface{}, error)) func(interface{}, ctx.Context, func(interface{}) error, grpc.UnaryServerInterceptor) (interface{}, error) {
+	return func(_ interface{}, c ctx.Context, dec func(interface{}) error, _ grpc.UnaryServerInterceptor) (interface{}, error) {
+		request := &Empty{}
+		if err := dec(request); err != nil {
+			return nil, err
+		}
+		return handle(c, request)
+	}
+}
+
+func preScaleHandler(s *Server) func(interface{}, ctx.Context, func(interface{}) error, grpc.UnaryServerInterceptor) (interface{}, error) {
+	return func(_ interface{}, c ctx.Context, dec func(interface{}) error, _ grpc.UnaryServerInterceptor) (interface{}, error) {
+		request := &PreScaleRequest{}
+		if err := dec(request); err != nil {
+			return nil, err
+		}
+		return s.PreScale(c, request)
+	}
+}
+
+func startRefreshHandler(s *Server) func(interface{}, ctx.Context, func(interface{}) error, grpc.UnaryServerInterceptor) (interface{}, error) {
+	return func(_ interface{}, c ctx.Context, dec func(interface{}) error, _ grpc.UnaryServerInterceptor) (interface{}, error) {
+		request := &StartRefreshRequest{}
+		if err := dec(request); err != nil {
+			return nil, err
+		}
+		return s.StartRefresh(c, request)
+	}
+}
+
+func (s *Server) serviceDesc() *grpc.ServiceDesc {
+	return &grpc.ServiceDesc{
+		ServiceName: "clusterautoscaler.ControlService",
+		HandlerType: (*interface{})(nil),
+		Methods: []grpc.MethodDesc{
+			{MethodName: "Pause", Handler: unaryHandler(func(c ctx.Context, request *Empty) (interface{}, error) { return s.Pause(c, request) })},
+			{MethodName: "Resume", Handler: unaryHandler(func(c ctx.Context, request *Empty) (interface{}, error) { return s.Resume(c, request) })},
+			{MethodName: "ForceRefresh", Handler: unaryHandler(func(c ctx.Context, request *Empty) (interface{}, error) { return s.ForceRefresh(c, request) })},
+			{MethodName: "TriggerSnapshot", Handler: unaryHandler(func(c ctx.Context, request *Empty) (interface{}, error) { return s.TriggerSnapshot(c, request) })},
+			{MethodName: "PreScale", Handler: preScaleHandler(s)},
+			{MethodName: "StartRefresh", Handler: startRefreshHandler(s)},
+			{MethodName: "CancelRefresh", Handler: unaryHandler(func(c ctx.Context, request *Empty) (interface{}, error) { return s.CancelRefresh(c, request) })},
+			{MethodName: "GetStatus", Handler: unaryHandler(func(c ctx.Context, request *Empty) (interface{}, error) { return s.GetStatus(c, request) })},
+		},
+	}
+}
+
+// Serve starts the control service on the given address, secured with mutual
+// TLS. Only clients presenting a certificate signed by the given CA are
+// accepted. Serve blocks, so it is meant to run in its own goroutine.
+func (s *Server) Serve(address string, certFile string, keyFile string, clientCAFile string) error {
+	certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load control API server certificate: %v", err)
+	}
+	caPEM, err := ioutil.ReadFile(clientCAFile)
+	if err != nil {
+		return fmt.Errorf("failed to read control API client CA: %v", err)
+	}
+	caPool := x509.NewCertPool()
+	if !caPool.AppendCertsFromPEM(caPEM) {
+		return fmt.Errorf("no certificates found in control API client CA file %s", clientCAFile)
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    caPool,
+	}
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return fmt.Errorf("failed to listen on control API address %s: %v", address, err)
+	}
+	grpcServer := grpc.NewServer(
+		grpc.Creds(credentials.NewTLS(tlsConfig)),
+		grpc.ForceServerCodec(jsonCodec{}),
+	)
+	grpcServer.RegisterService(s.serviceDesc(), s)
+	klog.V(1).Infof("Control API listening on %s", address)
+	return grpcServer.Serve(listener)
+}
@@ -0,0 +1,318 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/autoscaler/cluster-autoscaler/auditlog"
+	"k8s.io/autoscaler/cluster-autoscaler/context"
+	"k8s.io/autoscaler/cluster-autoscaler/core/utils"
+	"k8s.io/autoscaler/cluster-autoscaler/metrics"
+
+	apiv1 "k8s.io/api/core/v1"
+	kube_client "k8s.io/client-go/kubernetes"
+	klog "k8s.io/klog/v2"
+)
+
+// autoProvisionedPoolPrefix marks worker pools created by the auto-provisioner,
+// so only those are considered for automatic deletion when they become empty.
+const autoProvisionedPoolPrefix = "auto-"
+
+// AutoProvisioner creates new worker groups through the portal for pending pods
+// that fit no existing pool, e.g. a pod requesting more memory than any current
+// flavor offers. It picks the cheapest allowed flavor that fits the pod and
+// deletes the pools it created once they have been empty for a while.
+type AutoProvisioner struct {
+	context *context.AutoscalingContext
+	// emptySince is when each auto-provisioned pool was first observed without
+	// any non daemon set pod, or absent while the pool is in use.
+	emptySince map[string]time.Time
+}
+
+// NewAutoProvisioner builds an AutoProvisioner for the given autoscaling context.
+func NewAutoProvisioner(context *context.AutoscalingContext) *AutoProvisioner {
+	return &AutoProvisioner{
+		context:    context,
+		emptySince: make(map[string]time.Time),
+	}
+}
+
+// podRequests sums the cpu (milli) and memory (bytes) requests of all
+// containers of the pod.
+func podRequests(pod *apiv1.Pod) (cpuMilli int64, memoryBytes int64) {
+	for _, container := range pod.Spec.Containers {
+		if cpu, found := container.Resources.Requests[apiv1.ResourceCPU]; found {
+			cpuMilli += cpu.MilliValue()
+		}
+		if memory, found := container.Resources.Requests[apiv1.ResourceMemory]; found {
+			memoryBytes += memory.Value()
+		}
+	}
+	return cpuMilli, memoryBytes
+}
+
+// fitsAnyWorker reports whether some existing worker node is large enough for
+// the pod's requests, ignoring current usage; pods that fail this check can
+// never be helped by scaling an existing pool.
+func fitsAnyWorker(pod *apiv1.Pod, nodes []*apiv1.Node) bool {
+	cpuMilli, memoryBytes := podRequests(pod)
+	for _, node := range nodes {
+		if !utils.IsWorkerNode(node) {
+			continue
+		}
+		if node.Status.Allocatable.Cpu().MilliValue() >= cpuMilli &&
+			node.Status.Allocatable.Memory().Value() >= memoryBytes {
+			return true
+		}
+	}
+	return false
+}
+
+// flavorAllowed reports whether the flavor may be used for auto-provisioning
+// under the configured allowlist and cost limit.
+func (p *AutoProvisioner) flavorAllowed(flavor utils.Flavor) bool {
+	options := p.context.AutoscalingOptions
+	if maxCost := options.AutoProvisioningMaxFlavorCostPerHour; maxCost > 0 && flavor.PricePerHour > maxCost {
+		return false
+	}
+	if len(options.AutoProvisioningFlavorAllowlist) == 0 {
+		return true
+	}
+	for _, name := range options.AutoProvisioningFlavorAllowlist {
+		if name == flavor.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// ProvisionForMisfitPods creates or grows auto-provisioned worker groups for
+// pending pods that fit no existing worker node. It returns the pods it could
+// not help, which should go through the regular scale up, and whether any
+// portal operation was submitted.
+func (p *AutoProvisioner) ProvisionForMisfitPods(
+	currentTime time.Time,
+	pods []*apiv1.Pod,
+	nodes []*apiv1.Node,
+	kubeclient kube_client.Interface,
+	accessToken string,
+	vpcID string,
+	idCluster string,
+	clusterIDPortal string,
+	env string,
+) ([]*apiv1.Pod, bool) {
+	if !p.context.AutoscalingOptions.NodeAutoProvisioningEnabled {
+		return pods, false
+	}
+
+	misfits := make([]*apiv1.Pod, 0)
+	remaining := make([]*apiv1.Pod, 0, len(pods))
+	for _, pod := range pods {
+		if fitsAnyWorker(pod, nodes) {
+			remaining = append(remaining, pod)
+		} else {
+			misfits = append(misfits, pod)
+		}
+	}
+	if len(misfits) == 0 {
+		return pods, false
+	}
+
+	// The catalog is sorted by price, so the first fitting flavor is the
+	// cheapest allowed one.
+	catalog := utils.GetFlavorCatalog(kubeclient)
+	podsByFlavor := make(map[string][]*apiv1.Pod)
+	flavorByName := make(map[string]utils.Flavor)
+	for _, pod := range misfits {
+		cpuMilli, memoryBytes := podRequests(pod)
+		assigned := false
+		for _, flavor := range catalog {
+			if !p.flavorAllowed(flavor) {
+				continue
+			}
+			if flavor.CPUMilli >= cpuMilli && flavor.MemoryBytes >= memoryBytes {
+				podsByFlavor[flavor.Name] = append(podsByFlavor[flavor.Name], pod)
+				flavorByName[flavor.Name] = flavor
+				assigned = true
+				break
+			}
+		}
+		if !assigned {
+			klog.Warningf("Pod %s/%s fits no existing worker and no allowed flavor, leaving it to the regular scale up", pod.Namespace, pod.Name)
+			remaining = append(remaining, pod)
+		}
+	}
+	if len(podsByFlavor) == 0 {
+		return remaining, false
+	}
+
+	domainAPI := utils.GetDomainApiConformEnv(env)
+	if !utils.CheckStatusCluster(domainAPI, vpcID, accessToken, clusterIDPortal) {
+		klog.V(1).Infof("Auto-provisioning: another action is being performed, waiting for scaling ...")
+		return pods, false
+	}
+
+	submitted := false
+	for flavorName, flavorPods := range podsByFlavor {
+		flavor := flavorByName[flavorName]
+		workerCount := workersNeededForPods(flavor, flavorPods)
+		poolName := autoProvisionedPoolPrefix + flavorName
+		klog.V(1).Infof("Auto-provisioning %v workers of flavor %q in pool %q for %v pending pods", workerCount, flavorName, poolName, len(flavorPods))
+		auditlog.Log("auto-provision-decision", map[string]string{
+			"workerPool":  poolName,
+			"flavor":      flavorName,
+			"workerCount": strconv.Itoa(workerCount),
+			"pendingPods": strconv.Itoa(len(flavorPods)),
+		})
+		if poolExists(nodes, poolName) {
+			utils.PerformScaleUpForPool(domainAPI, vpcID, accessToken, workerCount, idCluster, clusterIDPortal, poolName)
+		} else {
+			utils.PerformCreateWorkerGroup(domainAPI, vpcID, accessToken, idCluster, clusterIDPortal, poolName, flavorName, workerCount)
+		}
+		metrics.RegisterNodesCreated(poolName, workerCount)
+		metrics.ObserveScaleCostDelta("scale_up", flavor.PricePerHour*float64(workerCount))
+		delete(p.emptySince, poolName)
+		submitted = true
+	}
+	if !submitted || utils.InReadOnlyMode() {
+		return remaining, false
+	}
+
+	portalWaitStart := time.Now()
+	for {
+		time.Sleep(30 * time.Second)
+		if utils.CheckStatusCluster(domainAPI, vpcID, accessToken, clusterIDPortal) {
+			klog.V(1).Infof("Status of cluster is SUCCEEDED")
+			break
+		}
+		klog.V(1).Infof("Status of cluster is SCALING")
+		if utils.CheckErrorStatusCluster(domainAPI, vpcID, accessToken, clusterIDPortal) {
+			klog.Errorf("Auto-provisioning through the portal failed, the pods are left to the next iteration")
+			break
+		}
+	}
+	metrics.UpdateDurationFromStart(metrics.ScaleUpPortalWait, portalWaitStart)
+	return remaining, true
+}
+
+// workersNeededForPods greedily packs the pods into workers of the given
+// flavor and returns how many workers are needed.
+func workersNeededForPods(flavor utils.Flavor, pods []*apiv1.Pod) int {
+	workers := 0
+	var freeCPU, freeMemory int64
+	for _, pod := range pods {
+		cpuMilli, memoryBytes := podRequests(pod)
+		if workers == 0 || cpuMilli > freeCPU || memoryBytes > freeMemory {
+			workers++
+			freeCPU = flavor.CPUMilli
+			freeMemory = flavor.MemoryBytes
+		}
+		freeCPU -= cpuMilli
+		freeMemory -= memoryBytes
+	}
+	return workers
+}
+
+// poolExists reports whether some node already belongs to the given worker pool.
+func poolExists(nodes []*apiv1.Node, poolName string) bool {
+	for _, node := range nodes {
+		if node.Labels[utils.WorkerPoolLabel] == poolName {
+			return true
+		}
+	}
+	return false
+}
+
+// CleanupEmptyPools deletes auto-provisioned worker groups that have run
+// without any non daemon set pod for the configured unneeded time.
+func (p *AutoProvisioner) CleanupEmptyPools(
+	currentTime time.Time,
+	kubeclient kube_client.Interface,
+	accessToken string,
+	vpcID string,
+	idCluster string,
+	clusterIDPortal string,
+	env string,
+) {
+	if !p.context.AutoscalingOptions.NodeAutoProvisioningEnabled {
+		return
+	}
+	allNodeInfos, err := p.context.ClusterSnapshot.NodeInfos().List()
+	if err != nil {
+		klog.Errorf("Auto-provisioning: failed to list node infos from snapshot: %v", err)
+		return
+	}
+
+	poolEmpty := make(map[string]bool)
+	poolNodes := make(map[string][]string)
+	for _, nodeInfo := range allNodeInfos {
+		node := nodeInfo.Node()
+		poolName := node.Labels[utils.WorkerPoolLabel]
+		if !strings.HasPrefix(poolName, autoProvisionedPoolPrefix) {
+			continue
+		}
+		if _, found := poolEmpty[poolName]; !found {
+			poolEmpty[poolName] = true
+		}
+		poolNodes[poolName] = append(poolNodes[poolName], node.Name)
+		for _, podInfo := range nodeInfo.Pods {
+			pod := podInfo.Pod
+			if len(pod.OwnerReferences) > 0 && pod.OwnerReferences[0].Kind == "DaemonSet" {
+				continue
+			}
+			poolEmpty[poolName] = false
+			break
+		}
+	}
+
+	for poolName, empty := range poolEmpty {
+		if !empty {
+			delete(p.emptySince, poolName)
+			continue
+		}
+		if _, found := p.emptySince[poolName]; !found {
+			p.emptySince[poolName] = currentTime
+			continue
+		}
+		unneededTime := p.context.AutoscalingOptions.NodeGroupDefaults.ScaleDownUnneededTime
+		if p.emptySince[poolName].Add(unneededTime).After(currentTime) {
+			continue
+		}
+		domainAPI := utils.GetDomainApiConformEnv(env)
+		if !utils.CheckStatusCluster(domainAPI, vpcID, accessToken, clusterIDPortal) {
+			klog.V(1).Infof("Auto-provisioning: another action is being performed, deferring deletion of pool %q", poolName)
+			return
+		}
+		klog.V(1).Infof("Auto-provisioned pool %q has been empty since %v, deleting it", poolName, p.emptySince[poolName])
+		auditlog.Log("auto-provision-delete-decision", map[string]string{
+			"workerPool": poolName,
+			"nodes":      strings.Join(poolNodes[poolName], ","),
+		})
+		nodesToDelete := runPreDeletionHooks(p.context.AutoscalingOptions, kubeclient, poolNodes[poolName])
+		if len(nodesToDelete) < len(poolNodes[poolName]) {
+			klog.V(1).Infof("Auto-provisioning: pre-deletion hooks kept some nodes of pool %q, deferring its deletion", poolName)
+			continue
+		}
+		drainWorkerNodes(kubeclient, p.context.Recorder, nodesToDelete,
+			p.context.AutoscalingOptions.MaxDrainParallelism, p.context.AutoscalingOptions.ForceDrainTimeout)
+		utils.PerformDeleteWorkerGroup(domainAPI, vpcID, accessToken, idCluster, clusterIDPortal, poolName)
+		delete(p.emptySince, poolName)
+	}
+}
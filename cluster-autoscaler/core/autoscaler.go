@@ -17,6 +17,7 @@ limitations under the License.
 package core
 
 import (
+	"strings"
 	"time"
 
 	"k8s.io/autoscaler/cluster-autoscaler/config"
@@ -24,6 +25,7 @@ import (
 	"k8s.io/autoscaler/cluster-autoscaler/debuggingsnapshot"
 	"k8s.io/autoscaler/cluster-autoscaler/estimator"
 	"k8s.io/autoscaler/cluster-autoscaler/expander"
+	"k8s.io/autoscaler/cluster-autoscaler/expander/factory"
 	ca_processors "k8s.io/autoscaler/cluster-autoscaler/processors"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/errors"
@@ -98,14 +100,16 @@ func initializeDefaultOptions(opts *AutoscalerOptions) error {
 	// if opts.CloudProvider == nil {
 	// 	opts.CloudProvider = cloudBuilder.NewCloudProvider(opts.AutoscalingOptions)
 	// }
-	// if opts.ExpanderStrategy == nil {
-	// 	expanderStrategy, err := factory.ExpanderStrategyFromStrings(strings.Split(opts.ExpanderNames, ","), opts.CloudProvider,
-	// 		opts.AutoscalingKubeClients, opts.KubeClient, opts.ConfigNamespace, opts.GRPCExpanderCert, opts.GRPCExpanderURL)
-	// 	if err != nil {
-	// 		return err
-	// 	}
-	// 	opts.ExpanderStrategy = expanderStrategy
-	// }
+	if opts.ExpanderStrategy == nil {
+		expanderStrategy, err := factory.ExpanderStrategyWithPoolOverrides(strings.Split(opts.ExpanderNames, ","),
+			opts.AutoscalingKubeClients, opts.KubeClient, opts.ConfigNamespace, opts.GRPCExpanderCert, opts.GRPCExpanderURL,
+			opts.WebhookExpanderURL, opts.ExpanderRandomSeed, opts.ExpanderChainTimeBudget, opts.ExpanderCacheTTL,
+			opts.ExpanderNamesPerPool)
+		if err != nil {
+			return err
+		}
+		opts.ExpanderStrategy = expanderStrategy
+	}
 	if opts.EstimatorBuilder == nil {
 		estimatorBuilder, err := estimator.NewEstimatorBuilder(opts.EstimatorName)
 		if err != nil {
@@ -0,0 +1,224 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/autoscaler/cluster-autoscaler/auditlog"
+	"k8s.io/autoscaler/cluster-autoscaler/context"
+	"k8s.io/autoscaler/cluster-autoscaler/core/utils"
+	"k8s.io/autoscaler/cluster-autoscaler/metrics"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator"
+
+	policyv1 "k8s.io/api/policy/v1beta1"
+	kube_client "k8s.io/client-go/kubernetes"
+	klog "k8s.io/klog/v2"
+)
+
+// Consolidator implements the opt-in proactive consolidation mode. Instead of
+// waiting for single nodes to become empty-ish, it periodically looks for a set
+// of underutilized workers whose pods can be repacked onto the remaining nodes,
+// drains them together and shrinks the pool via the portal in one operation.
+// Since the portal always removes the highest numbered workers, the set it can
+// act on is the contiguous run of underutilized workers at the top of the
+// numbering.
+type Consolidator struct {
+	context      *context.AutoscalingContext
+	usageTracker *simulator.UsageTracker
+	lastRun      time.Time
+}
+
+// NewConsolidator builds a Consolidator for the given autoscaling context.
+func NewConsolidator(context *context.AutoscalingContext) *Consolidator {
+	return &Consolidator{
+		context:      context,
+		usageTracker: simulator.NewUsageTracker(),
+	}
+}
+
+// TryToConsolidate runs one consolidation pass. It returns true if the pool was
+// shrunk through the portal, so the caller can start the scale down cooldown.
+func (c *Consolidator) TryToConsolidate(
+	currentTime time.Time,
+	pdbs []*policyv1.PodDisruptionBudget,
+	kubeclient kube_client.Interface,
+	accessToken string,
+	vpcID string,
+	idCluster string,
+	clusterIDPortal string,
+	env string,
+) bool {
+	options := c.context.AutoscalingOptions
+	if !options.ProactiveConsolidationEnabled {
+		return false
+	}
+	if c.lastRun.Add(options.ConsolidationInterval).After(currentTime) {
+		return false
+	}
+	c.lastRun = currentTime
+
+	allNodeInfos, err := c.context.ClusterSnapshot.NodeInfos().List()
+	if err != nil {
+		klog.Errorf("Consolidation: failed to list node infos from snapshot: %v", err)
+		return false
+	}
+
+	minSizeNodeGroup, minSizeErr := utils.GetMinSizeNodeGroup(kubeclient)
+	if minSizeErr != nil {
+		klog.Warningf("Consolidation: failed to read node group min size: %v", minSizeErr)
+		return false
+	}
+
+	workerNames := make([]string, 0, len(allNodeInfos))
+	underutilized := make(map[string]bool)
+	for _, node := range filterOutMasters(allNodeInfos) {
+		if isNodeBeingDeleted(node, currentTime) {
+			continue
+		}
+		workerNames = append(workerNames, node.Name)
+		if hasNoScaleDownAnnotation(node) {
+			continue
+		}
+		nodeInfo, err := c.context.ClusterSnapshot.NodeInfos().Get(node.Name)
+		if err != nil {
+			continue
+		}
+		utilInfo, err := simulator.CalculateUtilization(node, nodeInfo, c.context.IgnoreDaemonSetsUtilization, c.context.IgnoreMirrorPodsUtilization, currentTime)
+		if err != nil {
+			klog.Warningf("Consolidation: failed to calculate utilization for %s: %v", node.Name, err)
+			continue
+		}
+		if utilInfo.Utilization < options.ConsolidationUtilizationThreshold {
+			underutilized[node.Name] = true
+		}
+	}
+
+	maxRemovals := len(workerNames) - minSizeNodeGroup
+	if maxParallel := options.MaxDrainParallelism; maxRemovals > maxParallel && maxParallel >= 1 {
+		maxRemovals = maxParallel
+	}
+	if maxRemovals <= 0 || len(underutilized) == 0 {
+		return false
+	}
+
+	// The portal removes workers from the top of the numbering down, so the
+	// candidate set is the contiguous run of underutilized workers with the
+	// highest numbers.
+	workerNameByNumber := make(map[int]string, len(workerNames))
+	for _, nodeName := range workerNames {
+		if idx := strings.LastIndex(nodeName, "worker"); idx >= 0 {
+			if number, err := strconv.Atoi(nodeName[idx+len("worker"):]); err == nil {
+				workerNameByNumber[number] = nodeName
+			}
+		}
+	}
+	candidateNames := make([]string, 0, maxRemovals)
+	for i := 0; i < maxRemovals; i++ {
+		nodeName, found := workerNameByNumber[len(workerNames)-i]
+		if !found || !underutilized[nodeName] {
+			break
+		}
+		candidateNames = append(candidateNames, nodeName)
+	}
+	if len(candidateNames) == 0 {
+		return false
+	}
+
+	// Simulate removing the whole set at once; pods of every removed node have
+	// to fit on the nodes that stay.
+	nodesToRemove, _, _, simErr := simulator.FindNodesToRemove(
+		candidateNames,
+		workerNames,
+		c.context.ListerRegistry,
+		c.context.ClusterSnapshot,
+		c.context.PredicateChecker,
+		nil,
+		c.usageTracker,
+		time.Now(),
+		pdbs,
+		time.Time{})
+	if simErr != nil {
+		klog.Errorf("Consolidation: simulation failed: %v", simErr)
+		return false
+	}
+	removable := make(map[string]bool, len(nodesToRemove))
+	for _, nodeToRemove := range nodesToRemove {
+		removable[nodeToRemove.Node.Name] = true
+	}
+	for i, workerName := range candidateNames {
+		if !removable[workerName] || !checkWorkerNodeCanBeScaleDown(kubeclient, workerName, options.ScaleDownAllowOwnerlessPods) {
+			candidateNames = candidateNames[:i]
+			break
+		}
+	}
+	if len(candidateNames) == 0 {
+		klog.V(4).Infof("Consolidation: no removable set of underutilized workers found")
+		return false
+	}
+
+	if utils.InReadOnlyMode() {
+		klog.V(1).Infof("Read-only mode: consolidation would scale down %v nodes: %s", len(candidateNames), strings.Join(candidateNames, ", "))
+		auditlog.Log("consolidation-recommendation", map[string]string{
+			"nodes": strings.Join(candidateNames, ","),
+			"count": strconv.Itoa(len(candidateNames)),
+		})
+		return false
+	}
+
+	domainAPI := utils.GetDomainApiConformEnv(env)
+	if !utils.CheckStatusCluster(domainAPI, vpcID, accessToken, clusterIDPortal) {
+		klog.V(1).Infof("Consolidation: another action is being performed, waiting for scaling ...")
+		return false
+	}
+
+	klog.V(1).Infof("Consolidation: scaling down %v underutilized nodes: %s", len(candidateNames), strings.Join(candidateNames, ", "))
+	auditlog.Log("consolidation-decision", map[string]string{
+		"nodes": strings.Join(candidateNames, ","),
+		"count": strconv.Itoa(len(candidateNames)),
+	})
+
+	candidateNames = runPreDeletionHooks(options, kubeclient, candidateNames)
+	if len(candidateNames) == 0 {
+		return false
+	}
+	drainWorkerNodes(kubeclient, c.context.Recorder, candidateNames, options.MaxDrainParallelism, options.ForceDrainTimeout)
+	utils.PerformScaleDown(domainAPI, vpcID, accessToken, len(candidateNames), idCluster, clusterIDPortal)
+	portalWaitStart := time.Now()
+	for {
+		time.Sleep(30 * time.Second)
+		if utils.CheckStatusCluster(domainAPI, vpcID, accessToken, clusterIDPortal) {
+			klog.V(1).Infof("Status of cluster is SUCCEEDED")
+			break
+		}
+		klog.V(1).Infof("Status of cluster is SCALING")
+		if utils.CheckErrorStatusCluster(domainAPI, vpcID, accessToken, clusterIDPortal) {
+			utils.PerformScaleDown(domainAPI, vpcID, accessToken, len(candidateNames), idCluster, clusterIDPortal)
+			for {
+				time.Sleep(30 * time.Second)
+				if utils.CheckStatusCluster(domainAPI, vpcID, accessToken, clusterIDPortal) {
+					break
+				}
+			}
+			break
+		}
+	}
+	metrics.UpdateDurationFromStart(metrics.ScaleDownPortalWait, portalWaitStart)
+	return true
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"sort"
+	"time"
+
+	"k8s.io/autoscaler/cluster-autoscaler/context"
+	core_utils "k8s.io/autoscaler/cluster-autoscaler/core/utils"
+	"k8s.io/autoscaler/cluster-autoscaler/statusreport"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/events"
+
+	apiv1 "k8s.io/api/core/v1"
+	kube_client "k8s.io/client-go/kubernetes"
+	klog "k8s.io/klog/v2"
+)
+
+// poolCostSample integrates the running cost of one worker pool across loops.
+type poolCostSample struct {
+	// nodeHours and cost accumulate actual usage since the window started.
+	nodeHours float64
+	cost      float64
+	// peakNodes is the largest node count seen in the window; the savings
+	// estimate compares actual usage against running at that peak throughout.
+	peakNodes     int
+	peakNodeHours float64
+	peakCost      float64
+}
+
+// CostReporter periodically summarizes the node-hours and estimated money the
+// autoscaler spent and saved per worker pool, based on the flavor pricing from
+// the autoscaling configmap. The summary is surfaced as an event, in the
+// status report and in the log, giving customers a tangible measure of
+// autoscaling value.
+type CostReporter struct {
+	context     *context.AutoscalingContext
+	windowStart time.Time
+	lastSample  time.Time
+	samples     map[string]*poolCostSample
+}
+
+// NewCostReporter builds a CostReporter for the given autoscaling context.
+func NewCostReporter(context *context.AutoscalingContext) *CostReporter {
+	return &CostReporter{
+		context: context,
+		samples: map[string]*poolCostSample{},
+	}
+}
+
+// Observe integrates the current node counts into the running window and
+// publishes a report when the window closes. Called once per loop iteration.
+func (r *CostReporter) Observe(currentTime time.Time, allNodes []*apiv1.Node, kubeclient kube_client.Interface) {
+	interval := r.context.AutoscalingOptions.CostReportInterval
+	if interval <= 0 {
+		return
+	}
+	if r.windowStart.IsZero() {
+		r.windowStart = currentTime
+		r.lastSample = currentTime
+		return
+	}
+	elapsedHours := currentTime.Sub(r.lastSample).Hours()
+	r.lastSample = currentTime
+	if elapsedHours <= 0 {
+		return
+	}
+
+	poolCounts := make(map[string]int)
+	for _, node := range allNodes {
+		if !core_utils.IsWorkerNode(node) {
+			continue
+		}
+		poolCounts[node.Labels[core_utils.WorkerPoolLabel]]++
+	}
+	prices := core_utils.GetWorkerHourlyPrices(kubeclient)
+	for poolName, count := range poolCounts {
+		sample, found := r.samples[poolName]
+		if !found {
+			sample = &poolCostSample{}
+			r.samples[poolName] = sample
+		}
+		if count > sample.peakNodes {
+			sample.peakNodes = count
+		}
+		price := core_utils.WorkerHourlyPrice(prices, poolName)
+		sample.nodeHours += float64(count) * elapsedHours
+		sample.cost += float64(count) * elapsedHours * price
+		sample.peakNodeHours += float64(sample.peakNodes) * elapsedHours
+		sample.peakCost += float64(sample.peakNodes) * elapsedHours * price
+	}
+
+	if currentTime.Sub(r.windowStart) < interval {
+		return
+	}
+	r.publish(currentTime)
+	r.windowStart = currentTime
+	r.samples = map[string]*poolCostSample{}
+}
+
+// publish emits the report for the closing window.
+func (r *CostReporter) publish(currentTime time.Time) {
+	ledger := core_utils.SnapshotAndResetCostLedger()
+	poolNames := make(map[string]bool)
+	for poolName := range r.samples {
+		poolNames[poolName] = true
+	}
+	for poolName := range ledger {
+		poolNames[poolName] = true
+	}
+
+	entries := make([]statusreport.CostReportEntry, 0, len(poolNames))
+	for poolName := range poolNames {
+		entry := statusreport.CostReportEntry{Pool: poolName}
+		if ledgerEntry, found := ledger[poolName]; found {
+			entry.NodesAdded = ledgerEntry.NodesAdded
+			entry.NodesRemoved = ledgerEntry.NodesRemoved
+		}
+		if sample, found := r.samples[poolName]; found {
+			entry.NodeHours = sample.nodeHours
+			entry.EstimatedCost = sample.cost
+			entry.EstimatedSavings = sample.peakCost - sample.cost
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Pool < entries[j].Pool })
+
+	for _, entry := range entries {
+		klog.V(0).Infof("Cost report for pool %q: nodesAdded=%v nodesRemoved=%v nodeHours=%.1f estimatedCost=%.2f estimatedSavings=%.2f",
+			entry.Pool, entry.NodesAdded, entry.NodesRemoved, entry.NodeHours, entry.EstimatedCost, entry.EstimatedSavings)
+		r.context.LogRecorder.Eventf(apiv1.EventTypeNormal, events.CostReport,
+			"pool=%q nodesAdded=%v nodesRemoved=%v nodeHours=%.1f estimatedCost=%.2f estimatedSavings=%.2f",
+			entry.Pool, entry.NodesAdded, entry.NodesRemoved, entry.NodeHours, entry.EstimatedCost, entry.EstimatedSavings)
+	}
+	statusreport.UpdateCostReport(entries)
+}
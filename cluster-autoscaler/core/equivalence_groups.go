@@ -18,7 +18,6 @@ package core
 
 import (
 	"k8s.io/autoscaler/cluster-autoscaler/utils"
-	"reflect"
 
 	apiv1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
@@ -67,7 +66,7 @@ func groupPodsBySchedulingProperties(pods []*apiv1.Pod) map[equivalenceGroupId][
 
 		matchingFound := false
 		for _, g := range equivalenceGroupsByController[controllerRef.UID] {
-			if reflect.DeepEqual(pod.Labels, g.representant.Labels) && utils.PodSpecSemanticallyEqual(pod.Spec, g.representant.Spec) {
+			if utils.PodLabelsSemanticallyEqual(pod.Labels, g.representant.Labels) && utils.PodSpecSemanticallyEqual(pod.Spec, g.representant.Spec) {
 				matchingFound = true
 				podEquivalenceGroups[g.id] = append(podEquivalenceGroups[g.id], pod)
 				break
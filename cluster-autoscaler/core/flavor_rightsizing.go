@@ -0,0 +1,178 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"time"
+
+	"k8s.io/autoscaler/cluster-autoscaler/context"
+	"k8s.io/autoscaler/cluster-autoscaler/core/utils"
+	"k8s.io/autoscaler/cluster-autoscaler/metrics"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator"
+	"k8s.io/autoscaler/cluster-autoscaler/statusreport"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/events"
+
+	apiv1 "k8s.io/api/core/v1"
+	kube_client "k8s.io/client-go/kubernetes"
+	klog "k8s.io/klog/v2"
+)
+
+const (
+	// flavorRecommendLowUtilization is the average utilization below which a
+	// pool is advised to switch to a smaller flavor.
+	flavorRecommendLowUtilization = 0.3
+	// flavorRecommendHighUtilization is the average utilization above which a
+	// pool is advised to switch to a larger flavor. Horizontal scaling already
+	// handles load spikes; sustained high utilization across the whole window
+	// means the flavor itself is too small.
+	flavorRecommendHighUtilization = 0.8
+)
+
+// poolUtilizationSample accumulates per-node utilization observations of one
+// worker pool across loops.
+type poolUtilizationSample struct {
+	sum   float64
+	count int
+	// cpuMilli and memoryBytes track the largest node allocatable seen in the
+	// pool, used to locate the current flavor in the catalog.
+	cpuMilli    int64
+	memoryBytes int64
+}
+
+// FlavorAnalyzer observes long-term utilization per worker pool and recommends
+// switching to a smaller or larger FKE flavor, complementing the horizontal
+// scaling decisions with vertical advice. Recommendations are surfaced as an
+// event, in the status report and as a metric; the analyzer never acts on them.
+type FlavorAnalyzer struct {
+	context     *context.AutoscalingContext
+	windowStart time.Time
+	samples     map[string]*poolUtilizationSample
+}
+
+// NewFlavorAnalyzer builds a FlavorAnalyzer for the given autoscaling context.
+func NewFlavorAnalyzer(context *context.AutoscalingContext) *FlavorAnalyzer {
+	return &FlavorAnalyzer{
+		context: context,
+		samples: make(map[string]*poolUtilizationSample),
+	}
+}
+
+// Observe samples the utilization of every worker node and, once the
+// configured window has passed, publishes flavor recommendations for pools
+// whose average utilization was persistently low or high.
+func (f *FlavorAnalyzer) Observe(currentTime time.Time, kubeclient kube_client.Interface) {
+	window := f.context.AutoscalingOptions.FlavorRightSizingWindow
+	if window <= 0 {
+		return
+	}
+	if f.windowStart.IsZero() {
+		f.windowStart = currentTime
+	}
+
+	allNodeInfos, err := f.context.ClusterSnapshot.NodeInfos().List()
+	if err != nil {
+		klog.Errorf("Flavor analyzer: failed to list node infos from snapshot: %v", err)
+		return
+	}
+	for _, nodeInfo := range allNodeInfos {
+		node := nodeInfo.Node()
+		if !utils.IsWorkerNode(node) || isNodeBeingDeleted(node, currentTime) {
+			continue
+		}
+		utilInfo, err := simulator.CalculateUtilization(node, nodeInfo, f.context.IgnoreDaemonSetsUtilization, f.context.IgnoreMirrorPodsUtilization, currentTime)
+		if err != nil {
+			continue
+		}
+		poolName := node.Labels[utils.WorkerPoolLabel]
+		sample, found := f.samples[poolName]
+		if !found {
+			sample = &poolUtilizationSample{}
+			f.samples[poolName] = sample
+		}
+		sample.sum += utilInfo.Utilization
+		sample.count++
+		if cpuMilli := node.Status.Allocatable.Cpu().MilliValue(); cpuMilli > sample.cpuMilli {
+			sample.cpuMilli = cpuMilli
+		}
+		if memoryBytes := node.Status.Allocatable.Memory().Value(); memoryBytes > sample.memoryBytes {
+			sample.memoryBytes = memoryBytes
+		}
+	}
+
+	if f.windowStart.Add(window).After(currentTime) {
+		return
+	}
+	f.publishRecommendations(kubeclient)
+	f.windowStart = currentTime
+	f.samples = make(map[string]*poolUtilizationSample)
+}
+
+// publishRecommendations turns the accumulated samples into recommendations
+// and emits them as events, status report entries and metrics.
+func (f *FlavorAnalyzer) publishRecommendations(kubeclient kube_client.Interface) {
+	catalog := utils.GetFlavorCatalog(kubeclient)
+	recommendations := make([]statusreport.FlavorRecommendation, 0)
+	for poolName, sample := range f.samples {
+		if sample.count == 0 {
+			continue
+		}
+		average := sample.sum / float64(sample.count)
+		direction := ""
+		if average < flavorRecommendLowUtilization {
+			direction = "smaller"
+		} else if average > flavorRecommendHighUtilization {
+			direction = "larger"
+		}
+		metrics.UpdateFlavorRecommendation(poolName, "smaller", direction == "smaller")
+		metrics.UpdateFlavorRecommendation(poolName, "larger", direction == "larger")
+		if direction == "" {
+			continue
+		}
+		recommended := recommendFlavor(catalog, sample, direction)
+		klog.V(1).Infof("Flavor recommendation: pool=%q direction=%s averageUtilization=%.2f recommendedFlavor=%q", poolName, direction, average, recommended)
+		f.context.LogRecorder.Eventf(apiv1.EventTypeNormal, events.FlavorRecommendation,
+			"pool=%q direction=%s averageUtilization=%.2f recommendedFlavor=%q", poolName, direction, average, recommended)
+		recommendations = append(recommendations, statusreport.FlavorRecommendation{
+			Pool:               poolName,
+			Direction:          direction,
+			AverageUtilization: average,
+			RecommendedFlavor:  recommended,
+		})
+	}
+	statusreport.UpdateFlavorRecommendations(recommendations)
+}
+
+// recommendFlavor picks from the catalog the most expensive flavor strictly
+// smaller than the pool's current nodes, or the cheapest one strictly larger,
+// depending on the direction. An empty result means the catalog offers no
+// suitable flavor.
+func recommendFlavor(catalog []utils.Flavor, sample *poolUtilizationSample, direction string) string {
+	recommended := ""
+	// The catalog is sorted by price ascending.
+	for _, flavor := range catalog {
+		if direction == "smaller" {
+			if flavor.CPUMilli < sample.cpuMilli && flavor.MemoryBytes < sample.memoryBytes {
+				recommended = flavor.Name
+			}
+		} else {
+			if flavor.CPUMilli > sample.cpuMilli && flavor.MemoryBytes > sample.memoryBytes {
+				return flavor.Name
+			}
+		}
+	}
+	return recommended
+}
@@ -0,0 +1,259 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"k8s.io/autoscaler/cluster-autoscaler/auditlog"
+	core_utils "k8s.io/autoscaler/cluster-autoscaler/core/utils"
+	"k8s.io/autoscaler/cluster-autoscaler/metrics"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator"
+
+	kube_client "k8s.io/client-go/kubernetes"
+	klog "k8s.io/klog/v2"
+	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+// poolCapacity aggregates the allocatable and free capacity of one worker
+// pool, plus the largest node as the template for new workers.
+type poolCapacity struct {
+	freeCPUMilli     int64
+	freeMemoryBytes  int64
+	totalCPUMilli    int64
+	totalMemoryBytes int64
+	templateCPUMilli int64
+	templateMemBytes int64
+}
+
+// poolCapacities computes the capacity of every worker pool from the cluster
+// snapshot, skipping nodes that are being deleted.
+func poolCapacities(allNodeInfos []*schedulerframework.NodeInfo, currentTime time.Time) map[string]*poolCapacity {
+	capacities := make(map[string]*poolCapacity)
+	for _, nodeInfo := range allNodeInfos {
+		node := nodeInfo.Node()
+		if !core_utils.IsWorkerNode(node) || isNodeBeingDeleted(node, currentTime) {
+			continue
+		}
+		poolName := node.Labels[core_utils.WorkerPoolLabel]
+		capacity, found := capacities[poolName]
+		if !found {
+			capacity = &poolCapacity{}
+			capacities[poolName] = capacity
+		}
+		cpuMilli := node.Status.Allocatable.Cpu().MilliValue()
+		memoryBytes := node.Status.Allocatable.Memory().Value()
+		capacity.totalCPUMilli += cpuMilli
+		capacity.totalMemoryBytes += memoryBytes
+		if cpuMilli > capacity.templateCPUMilli {
+			capacity.templateCPUMilli = cpuMilli
+		}
+		if memoryBytes > capacity.templateMemBytes {
+			capacity.templateMemBytes = memoryBytes
+		}
+		var requestedCPU, requestedMemory int64
+		for _, podInfo := range nodeInfo.Pods {
+			podCPU, podMemory := podRequests(podInfo.Pod)
+			requestedCPU += podCPU
+			requestedMemory += podMemory
+		}
+		capacity.freeCPUMilli += cpuMilli - requestedCPU
+		capacity.freeMemoryBytes += memoryBytes - requestedMemory
+	}
+	return capacities
+}
+
+// effectiveTarget resolves a headroom target against the pool's total
+// allocatable: per resource the larger of the absolute and percentage values.
+func effectiveTarget(target core_utils.HeadroomTarget, capacity *poolCapacity) (cpuMilli int64, memoryBytes int64) {
+	cpuMilli = target.CPUMilli
+	memoryBytes = target.MemoryBytes
+	if percentCPU := int64(target.Percent * float64(capacity.totalCPUMilli)); percentCPU > cpuMilli {
+		cpuMilli = percentCPU
+	}
+	if percentMemory := int64(target.Percent * float64(capacity.totalMemoryBytes)); percentMemory > memoryBytes {
+		memoryBytes = percentMemory
+	}
+	return cpuMilli, memoryBytes
+}
+
+// enforceHeadroom scales pools up preemptively when their free allocatable
+// capacity has fallen below the configured headroom watermark. It returns true
+// if a portal scale up was submitted.
+func (a *StaticAutoscaler) enforceHeadroom(
+	currentTime time.Time,
+	kubeclient kube_client.Interface,
+	accessToken string,
+	vpcID string,
+	idCluster string,
+	clusterIDPortal string,
+	env string,
+) bool {
+	allNodeInfos, err := a.ClusterSnapshot.NodeInfos().List()
+	if err != nil {
+		klog.Errorf("Headroom: failed to list node infos from snapshot: %v", err)
+		return false
+	}
+	capacities := poolCapacities(allNodeInfos, currentTime)
+
+	nodesByPool := make(map[string]int)
+	for poolName, capacity := range capacities {
+		target := core_utils.GetHeadroomTarget(kubeclient, poolName)
+		if target.IsZero() || capacity.templateCPUMilli == 0 || capacity.templateMemBytes == 0 {
+			continue
+		}
+		targetCPU, targetMemory := effectiveTarget(target, capacity)
+		cpuShort := float64(targetCPU - capacity.freeCPUMilli)
+		memoryShort := float64(targetMemory - capacity.freeMemoryBytes)
+		if cpuShort <= 0 && memoryShort <= 0 {
+			continue
+		}
+		needed := int(math.Ceil(math.Max(cpuShort/float64(capacity.templateCPUMilli), memoryShort/float64(capacity.templateMemBytes))))
+		if needed < 1 {
+			needed = 1
+		}
+		nodesByPool[poolName] = needed
+	}
+	if len(nodesByPool) == 0 {
+		return false
+	}
+
+	maxSizeNodeGroup, maxSizeErr := core_utils.GetMaxSizeNodeGroup(kubeclient)
+	if maxSizeErr != nil {
+		klog.Warningf("Headroom: failed to read node group max size: %v", maxSizeErr)
+		return false
+	}
+	currentWorkers := 0
+	for _, nodeInfo := range allNodeInfos {
+		node := nodeInfo.Node()
+		if core_utils.IsWorkerNode(node) && !isNodeBeingDeleted(node, currentTime) {
+			currentWorkers++
+		}
+	}
+	available := maxSizeNodeGroup - currentWorkers
+	if available <= 0 {
+		klog.V(1).Infof("Headroom: node group max size reached, cannot restore the watermark")
+		return false
+	}
+
+	domainAPI := core_utils.GetDomainApiConformEnv(env)
+	if !core_utils.CheckStatusCluster(domainAPI, vpcID, accessToken, clusterIDPortal) {
+		klog.V(1).Infof("Headroom: another action is being performed, waiting for scaling ...")
+		return false
+	}
+
+	submitted := false
+	for poolName, needed := range nodesByPool {
+		if needed > available {
+			needed = available
+		}
+		if needed == 0 {
+			continue
+		}
+		available -= needed
+		klog.V(1).Infof("Headroom: scaling up %v nodes in pool %q to restore the free capacity watermark", needed, poolName)
+		auditlog.Log("headroom-scale-up", map[string]string{
+			"workerPool": poolName,
+			"nodes":      strconv.Itoa(needed),
+		})
+		if poolName != "" {
+			core_utils.PerformScaleUpForPool(domainAPI, vpcID, accessToken, needed, idCluster, clusterIDPortal, poolName)
+		} else {
+			core_utils.PerformScaleUp(domainAPI, vpcID, accessToken, needed, idCluster, clusterIDPortal)
+		}
+		metrics.RegisterNodesCreated(poolName, needed)
+		submitted = true
+	}
+	if !submitted || core_utils.InReadOnlyMode() {
+		return false
+	}
+
+	portalWaitStart := time.Now()
+	for {
+		time.Sleep(30 * time.Second)
+		if core_utils.CheckStatusCluster(domainAPI, vpcID, accessToken, clusterIDPortal) {
+			klog.V(1).Infof("Status of cluster is SUCCEEDED")
+			break
+		}
+		klog.V(1).Infof("Status of cluster is SCALING")
+		if core_utils.CheckErrorStatusCluster(domainAPI, vpcID, accessToken, clusterIDPortal) {
+			klog.Errorf("Headroom scale up through the portal failed")
+			break
+		}
+	}
+	metrics.UpdateDurationFromStart(metrics.ScaleUpPortalWait, portalWaitStart)
+	return true
+}
+
+// trimForHeadroom cuts the scale down candidate list at the first worker whose
+// removal would push its pool below the configured headroom watermark. The
+// candidates are removed in order, so the free capacity is tracked
+// cumulatively.
+func (sd *ScaleDown) trimForHeadroom(workerNamesToRemove []string, kubeclient kube_client.Interface, currentTime time.Time) []string {
+	if len(workerNamesToRemove) == 0 {
+		return workerNamesToRemove
+	}
+	allNodeInfos, err := sd.context.ClusterSnapshot.NodeInfos().List()
+	if err != nil {
+		klog.Errorf("Headroom: failed to list node infos from snapshot: %v", err)
+		return workerNamesToRemove
+	}
+	capacities := poolCapacities(allNodeInfos, currentTime)
+	targets := make(map[string]core_utils.HeadroomTarget)
+
+	for i, workerName := range workerNamesToRemove {
+		nodeInfo, err := sd.context.ClusterSnapshot.NodeInfos().Get(workerName)
+		if err != nil {
+			continue
+		}
+		node := nodeInfo.Node()
+		poolName := node.Labels[core_utils.WorkerPoolLabel]
+		capacity, found := capacities[poolName]
+		if !found {
+			continue
+		}
+		target, found := targets[poolName]
+		if !found {
+			target = core_utils.GetHeadroomTarget(kubeclient, poolName)
+			targets[poolName] = target
+		}
+		if target.IsZero() {
+			continue
+		}
+		// The node's pods reschedule within the pool, so removing it costs the
+		// pool its whole allocatable.
+		cpuMilli := node.Status.Allocatable.Cpu().MilliValue()
+		memoryBytes := node.Status.Allocatable.Memory().Value()
+		capacity.freeCPUMilli -= cpuMilli
+		capacity.freeMemoryBytes -= memoryBytes
+		capacity.totalCPUMilli -= cpuMilli
+		capacity.totalMemoryBytes -= memoryBytes
+		targetCPU, targetMemory := effectiveTarget(target, capacity)
+		if capacity.freeCPUMilli < targetCPU || capacity.freeMemoryBytes < targetMemory {
+			klog.V(1).Infof("Removing %s would push pool %q below its headroom watermark, keeping it", workerName, poolName)
+			for _, keptName := range workerNamesToRemove[i:] {
+				if keptInfo, err := sd.context.ClusterSnapshot.NodeInfos().Get(keptName); err == nil {
+					sd.addUnremovableNodeReason(keptInfo.Node(), simulator.HeadroomRequired)
+				}
+			}
+			return workerNamesToRemove[:i]
+		}
+	}
+	return workerNamesToRemove
+}
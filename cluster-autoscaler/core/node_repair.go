@@ -0,0 +1,169 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"fmt"
+	"time"
+
+	"k8s.io/autoscaler/cluster-autoscaler/auditlog"
+	"k8s.io/autoscaler/cluster-autoscaler/context"
+	core_utils "k8s.io/autoscaler/cluster-autoscaler/core/utils"
+	"k8s.io/autoscaler/cluster-autoscaler/metrics"
+
+	apiv1 "k8s.io/api/core/v1"
+	kube_client "k8s.io/client-go/kubernetes"
+	klog "k8s.io/klog/v2"
+)
+
+// NodeRepairer implements basic node auto-repair: workers stuck NotReady or
+// with failing kubelet conditions beyond a threshold are drained as far as
+// possible, deleted through the portal and replaced by scaling their pool back
+// up, reusing the ordinary scale down and scale up machinery.
+type NodeRepairer struct {
+	context *context.AutoscalingContext
+}
+
+// NewNodeRepairer builds a NodeRepairer for the given autoscaling context.
+func NewNodeRepairer(context *context.AutoscalingContext) *NodeRepairer {
+	return &NodeRepairer{context: context}
+}
+
+// unhealthyReason reports why the node needs repair, or an empty string if it
+// is healthy or has not been unhealthy for longer than the threshold.
+func unhealthyReason(node *apiv1.Node, currentTime time.Time, threshold time.Duration) string {
+	for _, condition := range node.Status.Conditions {
+		sinceTransition := currentTime.Sub(condition.LastTransitionTime.Time)
+		if sinceTransition < threshold {
+			continue
+		}
+		if condition.Type == apiv1.NodeReady && condition.Status != apiv1.ConditionTrue {
+			return fmt.Sprintf("%v=%v for %v", condition.Type, condition.Status, sinceTransition.Round(time.Second))
+		}
+		switch condition.Type {
+		case apiv1.NodeMemoryPressure, apiv1.NodeDiskPressure, apiv1.NodePIDPressure:
+			if condition.Status == apiv1.ConditionTrue {
+				return fmt.Sprintf("%v=%v for %v", condition.Type, condition.Status, sinceTransition.Round(time.Second))
+			}
+		}
+	}
+	return ""
+}
+
+// RepairUnhealthyNodes replaces at most one unhealthy worker per iteration. It
+// returns true if a repair was performed through the portal, so the caller can
+// start the scaling cooldowns.
+func (r *NodeRepairer) RepairUnhealthyNodes(
+	currentTime time.Time,
+	allNodes []*apiv1.Node,
+	kubeclient kube_client.Interface,
+	accessToken string,
+	vpcID string,
+	idCluster string,
+	clusterIDPortal string,
+	env string,
+) bool {
+	options := r.context.AutoscalingOptions
+	if !options.NodeRepairEnabled {
+		return false
+	}
+
+	var unhealthyNode *apiv1.Node
+	var reason string
+	for _, node := range allNodes {
+		if !core_utils.IsWorkerNode(node) || isNodeBeingDeleted(node, currentTime) {
+			continue
+		}
+		if nodeReason := unhealthyReason(node, currentTime, options.NodeRepairUnhealthyThreshold); nodeReason != "" {
+			unhealthyNode = node
+			reason = nodeReason
+			break
+		}
+	}
+	if unhealthyNode == nil {
+		return false
+	}
+	poolName := unhealthyNode.Labels[core_utils.WorkerPoolLabel]
+
+	if core_utils.InReadOnlyMode() {
+		klog.V(1).Infof("Read-only mode: would replace unhealthy node %s (%s)", unhealthyNode.Name, reason)
+		auditlog.Log("node-repair-recommendation", map[string]string{
+			"node":   unhealthyNode.Name,
+			"reason": reason,
+		})
+		return false
+	}
+
+	domainAPI := core_utils.GetDomainApiConformEnv(env)
+	if !core_utils.CheckStatusCluster(domainAPI, vpcID, accessToken, clusterIDPortal) {
+		klog.V(1).Infof("Node repair: another action is being performed, waiting for scaling ...")
+		return false
+	}
+
+	klog.V(1).Infof("Replacing unhealthy node %s: %s", unhealthyNode.Name, reason)
+	auditlog.Log("node-repair-decision", map[string]string{
+		"node":       unhealthyNode.Name,
+		"workerPool": poolName,
+		"reason":     reason,
+	})
+
+	// Drain what is possible; pods on a broken kubelet may never terminate,
+	// which is why the node is deleted afterwards regardless.
+	drainWorkerNodes(kubeclient, r.context.Recorder, []string{unhealthyNode.Name}, options.MaxDrainParallelism, options.ForceDrainTimeout)
+	metrics.RegisterNodesDeleted(poolName, 1)
+	core_utils.PerformDeleteWorkerNode(domainAPI, vpcID, accessToken, idCluster, clusterIDPortal, unhealthyNode.Name)
+	portalWaitStart := time.Now()
+	for {
+		time.Sleep(30 * time.Second)
+		if core_utils.CheckStatusCluster(domainAPI, vpcID, accessToken, clusterIDPortal) {
+			klog.V(1).Infof("Status of cluster is SUCCEEDED")
+			break
+		}
+		klog.V(1).Infof("Status of cluster is SCALING")
+		if core_utils.CheckErrorStatusCluster(domainAPI, vpcID, accessToken, clusterIDPortal) {
+			klog.Errorf("Deletion of unhealthy node %s through the portal failed", unhealthyNode.Name)
+			metrics.UpdateDurationFromStart(metrics.ScaleDownPortalWait, portalWaitStart)
+			return false
+		}
+	}
+	metrics.UpdateDurationFromStart(metrics.ScaleDownPortalWait, portalWaitStart)
+
+	// Scale the pool back up so capacity is restored to what it was before
+	// the repair.
+	klog.V(1).Infof("Scaling pool %q back up to replace the deleted node", poolName)
+	if poolName != "" {
+		core_utils.PerformScaleUpForPool(domainAPI, vpcID, accessToken, 1, idCluster, clusterIDPortal, poolName)
+	} else {
+		core_utils.PerformScaleUp(domainAPI, vpcID, accessToken, 1, idCluster, clusterIDPortal)
+	}
+	metrics.RegisterNodesCreated(poolName, 1)
+	portalWaitStart = time.Now()
+	for {
+		time.Sleep(30 * time.Second)
+		if core_utils.CheckStatusCluster(domainAPI, vpcID, accessToken, clusterIDPortal) {
+			klog.V(1).Infof("Status of cluster is SUCCEEDED")
+			break
+		}
+		klog.V(1).Infof("Status of cluster is SCALING")
+		if core_utils.CheckErrorStatusCluster(domainAPI, vpcID, accessToken, clusterIDPortal) {
+			klog.Errorf("Replacement scale up through the portal failed")
+			break
+		}
+	}
+	metrics.UpdateDurationFromStart(metrics.ScaleUpPortalWait, portalWaitStart)
+	return true
+}
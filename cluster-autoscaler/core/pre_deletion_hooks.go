@@ -0,0 +1,199 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"bytes"
+	ctx "context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"k8s.io/autoscaler/cluster-autoscaler/auditlog"
+	"k8s.io/autoscaler/cluster-autoscaler/config"
+
+	batchv1 "k8s.io/api/batch/v1"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kube_client "k8s.io/client-go/kubernetes"
+	klog "k8s.io/klog/v2"
+)
+
+// preDeletionHookPollInterval is how often the Job and annotation hooks check
+// for completion.
+const preDeletionHookPollInterval = 5 * time.Second
+
+// preDeletionHooksConfigured tells whether any pre-deletion hook is enabled.
+func preDeletionHooksConfigured(options config.AutoscalingOptions) bool {
+	return options.PreDeletionWebhookURL != "" || options.PreDeletionJobImage != "" || options.PreDeletionHookAnnotation != ""
+}
+
+// runPreDeletionHooks runs the configured pre-deletion hooks for every worker
+// about to be deleted, in deletion order. When a node's hooks fail or time out
+// and the fail policy is abort, the list is cut at that node: the portal
+// removes the highest numbered workers first, so nothing after it can be
+// deleted either. With the skip policy the failure is logged and the deletion
+// proceeds.
+func runPreDeletionHooks(options config.AutoscalingOptions, kubeclient kube_client.Interface, workerNames []string) []string {
+	if !preDeletionHooksConfigured(options) {
+		return workerNames
+	}
+	for i, workerName := range workerNames {
+		deadline := time.Now().Add(options.PreDeletionHookTimeout)
+		if err := runPreDeletionHooksForNode(options, kubeclient, workerName, deadline); err != nil {
+			if options.PreDeletionHookFailPolicy == config.PreDeletionHookAbort {
+				klog.Warningf("Pre-deletion hooks for %s failed, keeping the node: %v", workerName, err)
+				auditlog.Log("pre-deletion-hook-abort", map[string]string{
+					"node":   workerName,
+					"reason": err.Error(),
+				})
+				return workerNames[:i]
+			}
+			klog.Warningf("Pre-deletion hooks for %s failed, deleting the node anyway: %v", workerName, err)
+		}
+	}
+	return workerNames
+}
+
+// runPreDeletionHooksForNode runs the webhook, Job and annotation hooks for
+// one node, sharing a single deadline.
+func runPreDeletionHooksForNode(options config.AutoscalingOptions, kubeclient kube_client.Interface, workerName string, deadline time.Time) error {
+	if options.PreDeletionWebhookURL != "" {
+		if err := callPreDeletionWebhook(options.PreDeletionWebhookURL, workerName, deadline); err != nil {
+			return err
+		}
+	}
+	if options.PreDeletionJobImage != "" {
+		if err := runPreDeletionJob(kubeclient, options.PreDeletionJobImage, workerName, deadline); err != nil {
+			return err
+		}
+	}
+	if options.PreDeletionHookAnnotation != "" {
+		if err := waitForPreDeletionAnnotation(kubeclient, options.PreDeletionHookAnnotation, workerName, deadline); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// callPreDeletionWebhook POSTs the node name to the configured webhook and
+// expects a 2xx response.
+func callPreDeletionWebhook(url string, workerName string, deadline time.Time) error {
+	body, err := json.Marshal(map[string]string{"node": workerName})
+	if err != nil {
+		return err
+	}
+	requestContext, cancel := ctx.WithDeadline(ctx.Background(), deadline)
+	defer cancel()
+	request, err := http.NewRequestWithContext(requestContext, http.MethodPost, url, bytes.NewBuffer(body))
+	if err != nil {
+		return fmt.Errorf("failed to build pre-deletion webhook request: %v", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return fmt.Errorf("pre-deletion webhook call failed: %v", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode >= 300 {
+		return fmt.Errorf("pre-deletion webhook returned status %v", response.StatusCode)
+	}
+	klog.V(1).Infof("Pre-deletion webhook acknowledged deletion of %s", workerName)
+	return nil
+}
+
+// runPreDeletionJob runs the configured image as a Job pinned to the node and
+// waits for it to complete. The Job is cleaned up afterwards regardless of the
+// outcome.
+func runPreDeletionJob(kubeclient kube_client.Interface, image string, workerName string, deadline time.Time) error {
+	jobName := "pre-deletion-" + workerName
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      jobName,
+			Namespace: metav1.NamespaceSystem,
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: func() *int32 { limit := int32(0); return &limit }(),
+			Template: apiv1.PodTemplateSpec{
+				Spec: apiv1.PodSpec{
+					NodeName:      workerName,
+					RestartPolicy: apiv1.RestartPolicyNever,
+					Tolerations: []apiv1.Toleration{
+						{Operator: apiv1.TolerationOpExists},
+					},
+					Containers: []apiv1.Container{
+						{
+							Name:  "pre-deletion-hook",
+							Image: image,
+							Env: []apiv1.EnvVar{
+								{Name: "NODE_NAME", Value: workerName},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	if _, err := kubeclient.BatchV1().Jobs(metav1.NamespaceSystem).Create(ctx.Background(), job, metav1.CreateOptions{}); err != nil {
+		if !errors.IsAlreadyExists(err) {
+			return fmt.Errorf("failed to create pre-deletion Job for %s: %v", workerName, err)
+		}
+	}
+	defer func() {
+		propagation := metav1.DeletePropagationBackground
+		kubeclient.BatchV1().Jobs(metav1.NamespaceSystem).Delete(ctx.Background(), jobName, metav1.DeleteOptions{PropagationPolicy: &propagation})
+	}()
+	for {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("pre-deletion Job for %s did not complete before the hook timeout", workerName)
+		}
+		time.Sleep(preDeletionHookPollInterval)
+		jobReturned, err := kubeclient.BatchV1().Jobs(metav1.NamespaceSystem).Get(ctx.Background(), jobName, metav1.GetOptions{})
+		if err != nil {
+			klog.Errorf("Failed to get pre-deletion Job for %s: %v", workerName, err)
+			continue
+		}
+		if jobReturned.Status.Succeeded > 0 {
+			klog.V(1).Infof("Pre-deletion Job for %s completed", workerName)
+			return nil
+		}
+		if jobReturned.Status.Failed > 0 {
+			return fmt.Errorf("pre-deletion Job for %s failed", workerName)
+		}
+	}
+}
+
+// waitForPreDeletionAnnotation waits until the node carries the configured
+// annotation with a non-empty value, so agents on the node can signal that
+// they have deregistered cleanly.
+func waitForPreDeletionAnnotation(kubeclient kube_client.Interface, annotation string, workerName string, deadline time.Time) error {
+	for {
+		node, err := kubeclient.CoreV1().Nodes().Get(ctx.Background(), workerName, metav1.GetOptions{})
+		if err != nil {
+			klog.Errorf("Failed to get node %s while waiting for the pre-deletion annotation: %v", workerName, err)
+		} else if node.Annotations[annotation] != "" {
+			klog.V(1).Infof("Node %s carries the pre-deletion annotation %s, proceeding", workerName, annotation)
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("node %s did not receive the %s annotation before the hook timeout", workerName, annotation)
+		}
+		time.Sleep(preDeletionHookPollInterval)
+	}
+}
@@ -0,0 +1,269 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	ctx "context"
+	"encoding/json"
+	"math"
+	"strconv"
+	"time"
+
+	"k8s.io/autoscaler/cluster-autoscaler/auditlog"
+	"k8s.io/autoscaler/cluster-autoscaler/context"
+	"k8s.io/autoscaler/cluster-autoscaler/core/utils"
+	"k8s.io/autoscaler/cluster-autoscaler/metrics"
+
+	apiv1 "k8s.io/api/core/v1"
+	kube_errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kube_client "k8s.io/client-go/kubernetes"
+	klog "k8s.io/klog/v2"
+)
+
+const (
+	// demandHistoryConfigMap persists the per-hour demand history across
+	// restarts, in the same namespace as the autoscaling configmap.
+	demandHistoryConfigMap = "autoscaler-demand-history"
+	// demandHistoryKey is the configmap key holding the serialized buckets.
+	demandHistoryKey = "hourly_demand"
+	// demandBucketCount is one bucket per hour of the week, so both daily and
+	// weekly periodicity are captured.
+	demandBucketCount = 7 * 24
+	// demandEWMAWeight is the weight of the latest observation in the
+	// exponentially weighted average of each bucket. Roughly three weeks of
+	// history dominate a bucket's value.
+	demandEWMAWeight = 0.3
+)
+
+// demandBucket is the smoothed demand observed during one hour of the week.
+type demandBucket struct {
+	PendingPods float64 `json:"pendingPods"`
+	CPUMilli    float64 `json:"cpuMilli"`
+	MemoryBytes float64 `json:"memoryBytes"`
+}
+
+// Predictor records per-hour demand and optionally pre-scales the worker pool
+// shortly before recurring daily or weekly peaks, so periodic workloads do not
+// pay the portal provisioning latency every day. The history survives restarts
+// in a configmap.
+type Predictor struct {
+	context *context.AutoscalingContext
+	buckets []demandBucket
+	loaded  bool
+	// peak is the highest demand seen in the hour currently being recorded;
+	// it is folded into its bucket when the hour rolls over.
+	peak       demandBucket
+	activeHour int
+	// lastPreScaledHour avoids pre-scaling repeatedly for the same upcoming
+	// bucket within one lead window.
+	lastPreScaledHour int
+}
+
+// NewPredictor builds a Predictor for the given autoscaling context.
+func NewPredictor(context *context.AutoscalingContext) *Predictor {
+	return &Predictor{
+		context:           context,
+		buckets:           make([]demandBucket, demandBucketCount),
+		activeHour:        -1,
+		lastPreScaledHour: -1,
+	}
+}
+
+// hourOfWeek maps a timestamp to its demand bucket index.
+func hourOfWeek(timestamp time.Time) int {
+	return int(timestamp.Weekday())*24 + timestamp.Hour()
+}
+
+// RecordDemand tracks the pending pods of this iteration in the current hourly
+// bucket. When the hour rolls over, the peak demand of the finished hour is
+// folded into the history and persisted.
+func (p *Predictor) RecordDemand(currentTime time.Time, pendingPods []*apiv1.Pod, kubeclient kube_client.Interface) {
+	if !p.context.AutoscalingOptions.PredictiveScalingEnabled {
+		return
+	}
+	p.loadHistory(kubeclient)
+
+	var cpuMilli, memoryBytes int64
+	for _, pod := range pendingPods {
+		podCPU, podMemory := podRequests(pod)
+		cpuMilli += podCPU
+		memoryBytes += podMemory
+	}
+
+	hour := hourOfWeek(currentTime)
+	if p.activeHour >= 0 && hour != p.activeHour {
+		bucket := &p.buckets[p.activeHour]
+		bucket.PendingPods = demandEWMAWeight*p.peak.PendingPods + (1-demandEWMAWeight)*bucket.PendingPods
+		bucket.CPUMilli = demandEWMAWeight*p.peak.CPUMilli + (1-demandEWMAWeight)*bucket.CPUMilli
+		bucket.MemoryBytes = demandEWMAWeight*p.peak.MemoryBytes + (1-demandEWMAWeight)*bucket.MemoryBytes
+		p.peak = demandBucket{}
+		p.saveHistory(kubeclient)
+	}
+	p.activeHour = hour
+	if pods := float64(len(pendingPods)); pods > p.peak.PendingPods {
+		p.peak.PendingPods = pods
+	}
+	if cpu := float64(cpuMilli); cpu > p.peak.CPUMilli {
+		p.peak.CPUMilli = cpu
+	}
+	if memory := float64(memoryBytes); memory > p.peak.MemoryBytes {
+		p.peak.MemoryBytes = memory
+	}
+}
+
+// MaybePreScale scales the worker pool up ahead of a predicted recurring peak.
+// It returns true if a portal scale up was submitted, so the caller can start
+// the scale up cooldown.
+func (p *Predictor) MaybePreScale(
+	currentTime time.Time,
+	nodes []*apiv1.Node,
+	kubeclient kube_client.Interface,
+	accessToken string,
+	vpcID string,
+	idCluster string,
+	clusterIDPortal string,
+	env string,
+) bool {
+	options := p.context.AutoscalingOptions
+	if !options.PredictiveScalingEnabled {
+		return false
+	}
+	p.loadHistory(kubeclient)
+
+	// Only look ahead across the next hour boundary; inside the peak hour the
+	// regular scale up is already handling the real demand.
+	upcomingHour := hourOfWeek(currentTime.Add(options.PredictiveScalingLeadTime))
+	if upcomingHour == hourOfWeek(currentTime) || upcomingHour == p.lastPreScaledHour {
+		return false
+	}
+	predicted := p.buckets[upcomingHour]
+	if predicted.PendingPods < 1 {
+		return false
+	}
+
+	// Translate the predicted resource demand into worker nodes using the
+	// largest current worker as the template.
+	var templateCPU, templateMemory int64
+	currentWorkers := 0
+	for _, node := range nodes {
+		if !utils.IsWorkerNode(node) || isNodeBeingDeleted(node, currentTime) {
+			continue
+		}
+		currentWorkers++
+		if cpu := node.Status.Allocatable.Cpu().MilliValue(); cpu > templateCPU {
+			templateCPU = cpu
+		}
+		if memory := node.Status.Allocatable.Memory().Value(); memory > templateMemory {
+			templateMemory = memory
+		}
+	}
+	if templateCPU == 0 || templateMemory == 0 {
+		return false
+	}
+	neededNodes := int(math.Ceil(math.Max(predicted.CPUMilli/float64(templateCPU), predicted.MemoryBytes/float64(templateMemory))))
+	if neededNodes < 1 {
+		neededNodes = 1
+	}
+	maxSizeNodeGroup, maxSizeErr := utils.GetMaxSizeNodeGroup(kubeclient)
+	if maxSizeErr != nil {
+		klog.Warningf("Predictive scaling: failed to read node group max size: %v", maxSizeErr)
+		return false
+	}
+	if currentWorkers+neededNodes > maxSizeNodeGroup {
+		neededNodes = maxSizeNodeGroup - currentWorkers
+	}
+	if neededNodes <= 0 {
+		return false
+	}
+
+	klog.V(1).Infof("Predictive scaling: expecting %.1f pending pods in the upcoming hour, pre-scaling %v nodes", predicted.PendingPods, neededNodes)
+	auditlog.Log("predictive-scale-up", map[string]string{
+		"nodes":                strconv.Itoa(neededNodes),
+		"predictedPendingPods": strconv.FormatFloat(predicted.PendingPods, 'f', 1, 64),
+	})
+	if utils.InReadOnlyMode() {
+		p.lastPreScaledHour = upcomingHour
+		return false
+	}
+	domainAPI := utils.GetDomainApiConformEnv(env)
+	if !utils.CheckStatusCluster(domainAPI, vpcID, accessToken, clusterIDPortal) {
+		klog.V(1).Infof("Predictive scaling: another action is being performed, waiting for scaling ...")
+		return false
+	}
+	utils.PerformScaleUp(domainAPI, vpcID, accessToken, neededNodes, idCluster, clusterIDPortal)
+	metrics.RegisterNodesCreated("", neededNodes)
+	p.lastPreScaledHour = upcomingHour
+	portalWaitStart := time.Now()
+	for {
+		time.Sleep(30 * time.Second)
+		if utils.CheckStatusCluster(domainAPI, vpcID, accessToken, clusterIDPortal) {
+			klog.V(1).Infof("Status of cluster is SUCCEEDED")
+			break
+		}
+		klog.V(1).Infof("Status of cluster is SCALING")
+		if utils.CheckErrorStatusCluster(domainAPI, vpcID, accessToken, clusterIDPortal) {
+			klog.Errorf("Predictive scale up through the portal failed")
+			break
+		}
+	}
+	metrics.UpdateDurationFromStart(metrics.ScaleUpPortalWait, portalWaitStart)
+	return true
+}
+
+// loadHistory restores the demand history from its configmap once per process.
+func (p *Predictor) loadHistory(kubeclient kube_client.Interface) {
+	if p.loaded {
+		return
+	}
+	p.loaded = true
+	configMap, err := kubeclient.CoreV1().ConfigMaps("kube-system").Get(ctx.TODO(), demandHistoryConfigMap, metav1.GetOptions{})
+	if err != nil {
+		if !kube_errors.IsNotFound(err) {
+			klog.Warningf("Predictive scaling: failed to read demand history: %v", err)
+		}
+		return
+	}
+	var buckets []demandBucket
+	if err := json.Unmarshal([]byte(configMap.Data[demandHistoryKey]), &buckets); err != nil {
+		klog.Warningf("Predictive scaling: failed to parse demand history, starting fresh: %v", err)
+		return
+	}
+	if len(buckets) == demandBucketCount {
+		p.buckets = buckets
+	}
+}
+
+// saveHistory persists the demand history, creating its configmap on first use.
+func (p *Predictor) saveHistory(kubeclient kube_client.Interface) {
+	serialized, err := json.Marshal(p.buckets)
+	if err != nil {
+		klog.Warningf("Predictive scaling: failed to serialize demand history: %v", err)
+		return
+	}
+	configMap := &apiv1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: demandHistoryConfigMap, Namespace: "kube-system"},
+		Data:       map[string]string{demandHistoryKey: string(serialized)},
+	}
+	_, err = kubeclient.CoreV1().ConfigMaps("kube-system").Update(ctx.TODO(), configMap, metav1.UpdateOptions{})
+	if kube_errors.IsNotFound(err) {
+		_, err = kubeclient.CoreV1().ConfigMaps("kube-system").Create(ctx.TODO(), configMap, metav1.CreateOptions{})
+	}
+	if err != nil {
+		klog.Warningf("Predictive scaling: failed to persist demand history: %v", err)
+	}
+}
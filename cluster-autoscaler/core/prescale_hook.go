@@ -0,0 +1,159 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"k8s.io/autoscaler/cluster-autoscaler/auditlog"
+	core_utils "k8s.io/autoscaler/cluster-autoscaler/core/utils"
+	"k8s.io/autoscaler/cluster-autoscaler/metrics"
+
+	apiv1 "k8s.io/api/core/v1"
+	kube_client "k8s.io/client-go/kubernetes"
+	klog "k8s.io/klog/v2"
+)
+
+// processPreScaleSignals provisions nodes for replica increases announced
+// through the control API before the pods exist, so bursts driven by HPA or
+// KEDA do not have to wait for unschedulable pods. It returns true if a portal
+// scale up was submitted.
+func (a *StaticAutoscaler) processPreScaleSignals(
+	currentTime time.Time,
+	nodes []*apiv1.Node,
+	kubeclient kube_client.Interface,
+	accessToken string,
+	vpcID string,
+	idCluster string,
+	clusterIDPortal string,
+	env string,
+) bool {
+	signals := core_utils.TakePreScaleSignals(currentTime)
+	if len(signals) == 0 {
+		return false
+	}
+
+	// Translate the announced replicas into worker nodes per pool, using the
+	// largest worker of each pool as the template.
+	nodesByPool := make(map[string]int)
+	for _, signal := range signals {
+		templateCPU, templateMemory := largestWorkerInPool(nodes, signal.Pool, currentTime)
+		needed := 1
+		if signal.CPUMilli > 0 || signal.MemoryBytes > 0 {
+			if templateCPU == 0 || templateMemory == 0 {
+				klog.Warningf("Pre-scale signal for pool %q has no worker to use as a template, requesting one node", signal.Pool)
+			} else {
+				cpuNeed := float64(signal.CPUMilli*int64(signal.Replicas)) / float64(templateCPU)
+				memoryNeed := float64(signal.MemoryBytes*int64(signal.Replicas)) / float64(templateMemory)
+				needed = int(math.Ceil(math.Max(cpuNeed, memoryNeed)))
+				if needed < 1 {
+					needed = 1
+				}
+			}
+		}
+		nodesByPool[signal.Pool] += needed
+	}
+
+	maxSizeNodeGroup, maxSizeErr := core_utils.GetMaxSizeNodeGroup(kubeclient)
+	if maxSizeErr != nil {
+		klog.Warningf("Pre-scale: failed to read node group max size: %v", maxSizeErr)
+		return false
+	}
+	currentWorkers := 0
+	for _, node := range nodes {
+		if core_utils.IsWorkerNode(node) && !isNodeBeingDeleted(node, currentTime) {
+			currentWorkers++
+		}
+	}
+	available := maxSizeNodeGroup - currentWorkers
+	if available <= 0 {
+		klog.V(1).Infof("Pre-scale: node group max size reached, dropping the signals")
+		return false
+	}
+
+	domainAPI := core_utils.GetDomainApiConformEnv(env)
+	if !core_utils.CheckStatusCluster(domainAPI, vpcID, accessToken, clusterIDPortal) {
+		klog.V(1).Infof("Pre-scale: another action is being performed, waiting for scaling ...")
+		return false
+	}
+
+	submitted := false
+	for poolName, needed := range nodesByPool {
+		if needed > available {
+			needed = available
+		}
+		if needed == 0 {
+			continue
+		}
+		available -= needed
+		klog.V(1).Infof("Pre-scaling %v nodes in pool %q for an announced replica burst", needed, poolName)
+		auditlog.Log("prescale-decision", map[string]string{
+			"workerPool": poolName,
+			"nodes":      strconv.Itoa(needed),
+		})
+		if poolName != "" {
+			core_utils.PerformScaleUpForPool(domainAPI, vpcID, accessToken, needed, idCluster, clusterIDPortal, poolName)
+		} else {
+			core_utils.PerformScaleUp(domainAPI, vpcID, accessToken, needed, idCluster, clusterIDPortal)
+		}
+		metrics.RegisterNodesCreated(poolName, needed)
+		submitted = true
+	}
+	if !submitted || core_utils.InReadOnlyMode() {
+		return false
+	}
+
+	portalWaitStart := time.Now()
+	for {
+		time.Sleep(30 * time.Second)
+		if core_utils.CheckStatusCluster(domainAPI, vpcID, accessToken, clusterIDPortal) {
+			klog.V(1).Infof("Status of cluster is SUCCEEDED")
+			break
+		}
+		klog.V(1).Infof("Status of cluster is SCALING")
+		if core_utils.CheckErrorStatusCluster(domainAPI, vpcID, accessToken, clusterIDPortal) {
+			klog.Errorf("Pre-scale through the portal failed")
+			break
+		}
+	}
+	metrics.UpdateDurationFromStart(metrics.ScaleUpPortalWait, portalWaitStart)
+	return true
+}
+
+// largestWorkerInPool returns the largest allocatable cpu (milli) and memory
+// (bytes) among the workers of the given pool. An empty pool name matches
+// every worker.
+func largestWorkerInPool(nodes []*apiv1.Node, poolName string, currentTime time.Time) (int64, int64) {
+	var cpuMilli, memoryBytes int64
+	for _, node := range nodes {
+		if !core_utils.IsWorkerNode(node) || isNodeBeingDeleted(node, currentTime) {
+			continue
+		}
+		if poolName != "" && node.Labels[core_utils.WorkerPoolLabel] != poolName {
+			continue
+		}
+		if cpu := node.Status.Allocatable.Cpu().MilliValue(); cpu > cpuMilli {
+			cpuMilli = cpu
+		}
+		if memory := node.Status.Allocatable.Memory().Value(); memory > memoryBytes {
+			memoryBytes = memory
+		}
+	}
+	return cpuMilli, memoryBytes
+}
@@ -0,0 +1,207 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/autoscaler/cluster-autoscaler/auditlog"
+	"k8s.io/autoscaler/cluster-autoscaler/context"
+	core_utils "k8s.io/autoscaler/cluster-autoscaler/core/utils"
+	"k8s.io/autoscaler/cluster-autoscaler/metrics"
+
+	apiv1 "k8s.io/api/core/v1"
+	kube_client "k8s.io/client-go/kubernetes"
+	klog "k8s.io/klog/v2"
+)
+
+// Refresher orchestrates a rolling node refresh requested through the control
+// API: every worker present when the refresh started is replaced, a batch at a
+// time, by first scaling up a replacement through the portal and then draining
+// and deleting the old node. Upgrades therefore reuse the autoscaler's drain,
+// PDB and cooldown logic instead of needing a separate tool.
+type Refresher struct {
+	context *context.AutoscalingContext
+	// remaining holds the names of the old nodes still to be replaced. The
+	// refresh is active while it is non-empty.
+	remaining []string
+	batchSize int
+	pool      string
+}
+
+// NewRefresher builds a Refresher for the given autoscaling context.
+func NewRefresher(context *context.AutoscalingContext) *Refresher {
+	return &Refresher{context: context}
+}
+
+// Run makes refresh progress: it starts a requested refresh, replaces the next
+// batch of old nodes, and handles cancellation. It returns true if the portal
+// was used, so the caller can start the scaling cooldowns.
+func (r *Refresher) Run(
+	currentTime time.Time,
+	allNodes []*apiv1.Node,
+	kubeclient kube_client.Interface,
+	accessToken string,
+	vpcID string,
+	idCluster string,
+	clusterIDPortal string,
+	env string,
+) bool {
+	if core_utils.TakeRefreshCancel() && len(r.remaining) > 0 {
+		klog.V(0).Infof("Rolling refresh cancelled with %v nodes left to replace", len(r.remaining))
+		auditlog.Log("refresh-cancel", map[string]string{
+			"remaining": strconv.Itoa(len(r.remaining)),
+		})
+		r.remaining = nil
+	}
+	if request, found := core_utils.TakeRefreshRequest(); found {
+		if len(r.remaining) > 0 {
+			klog.Warningf("Ignoring refresh request: a refresh is already running with %v nodes left", len(r.remaining))
+		} else {
+			r.start(request, allNodes, currentTime)
+		}
+	}
+	if len(r.remaining) == 0 {
+		return false
+	}
+
+	// Nodes can disappear while the refresh is running, e.g. through an
+	// ordinary scale down. Drop them instead of replacing them.
+	liveNodes := make(map[string]bool, len(allNodes))
+	for _, node := range allNodes {
+		liveNodes[node.Name] = true
+	}
+	remaining := r.remaining[:0]
+	for _, name := range r.remaining {
+		if liveNodes[name] {
+			remaining = append(remaining, name)
+		}
+	}
+	r.remaining = remaining
+	if len(r.remaining) == 0 {
+		klog.V(0).Info("Rolling refresh complete")
+		auditlog.Log("refresh-complete", map[string]string{})
+		return false
+	}
+
+	batch := r.remaining
+	if len(batch) > r.batchSize {
+		batch = batch[:r.batchSize]
+	}
+
+	if core_utils.InReadOnlyMode() {
+		klog.V(1).Infof("Read-only mode: would refresh nodes: %s", strings.Join(batch, ", "))
+		auditlog.Log("refresh-recommendation", map[string]string{
+			"nodes": strings.Join(batch, ","),
+		})
+		return false
+	}
+
+	domainAPI := core_utils.GetDomainApiConformEnv(env)
+	if !core_utils.CheckStatusCluster(domainAPI, vpcID, accessToken, clusterIDPortal) {
+		klog.V(1).Infof("Rolling refresh: another action is being performed, waiting for scaling ...")
+		return false
+	}
+
+	klog.V(1).Infof("Rolling refresh: replacing nodes %s (%v left in total)", strings.Join(batch, ", "), len(r.remaining))
+	auditlog.Log("refresh-batch", map[string]string{
+		"nodes":     strings.Join(batch, ","),
+		"remaining": strconv.Itoa(len(r.remaining)),
+	})
+
+	// Bring up the replacements first so capacity never drops below the
+	// pre-refresh level.
+	if r.pool != "" {
+		core_utils.PerformScaleUpForPool(domainAPI, vpcID, accessToken, len(batch), idCluster, clusterIDPortal, r.pool)
+	} else {
+		core_utils.PerformScaleUp(domainAPI, vpcID, accessToken, len(batch), idCluster, clusterIDPortal)
+	}
+	metrics.RegisterNodesCreated(r.pool, len(batch))
+	if !r.waitForPortal(domainAPI, vpcID, accessToken, clusterIDPortal, metrics.ScaleUpPortalWait) {
+		klog.Errorf("Rolling refresh: replacement scale up failed, keeping the old nodes")
+		return true
+	}
+
+	options := r.context.AutoscalingOptions
+	nodesToDelete := runPreDeletionHooks(options, kubeclient, batch)
+	drainWorkerNodes(kubeclient, r.context.Recorder, nodesToDelete, options.MaxDrainParallelism, options.ForceDrainTimeout)
+	for _, name := range nodesToDelete {
+		core_utils.PerformDeleteWorkerNode(domainAPI, vpcID, accessToken, idCluster, clusterIDPortal, name)
+		if !r.waitForPortal(domainAPI, vpcID, accessToken, clusterIDPortal, metrics.ScaleDownPortalWait) {
+			klog.Errorf("Rolling refresh: deletion of %s failed, it will be retried next iteration", name)
+			return true
+		}
+		metrics.RegisterNodesDeleted(workerPoolOfNode(kubeclient, name), 1)
+		r.remaining = removeName(r.remaining, name)
+	}
+	return true
+}
+
+// start captures the set of nodes to replace from the current cluster state.
+func (r *Refresher) start(request core_utils.RefreshRequest, allNodes []*apiv1.Node, currentTime time.Time) {
+	r.pool = request.Pool
+	r.batchSize = request.BatchSize
+	if r.batchSize < 1 {
+		r.batchSize = 1
+	}
+	r.remaining = nil
+	for _, node := range allNodes {
+		if !core_utils.IsWorkerNode(node) || isNodeBeingDeleted(node, currentTime) {
+			continue
+		}
+		if request.Pool != "" && node.Labels[core_utils.WorkerPoolLabel] != request.Pool {
+			continue
+		}
+		r.remaining = append(r.remaining, node.Name)
+	}
+	klog.V(0).Infof("Rolling refresh started: %v nodes to replace in pool %q, %v per iteration", len(r.remaining), r.pool, r.batchSize)
+	auditlog.Log("refresh-start", map[string]string{
+		"workerPool": r.pool,
+		"nodes":      strconv.Itoa(len(r.remaining)),
+		"batchSize":  strconv.Itoa(r.batchSize),
+	})
+}
+
+// waitForPortal blocks until the running portal action finishes and reports
+// whether it succeeded.
+func (r *Refresher) waitForPortal(domainAPI string, vpcID string, accessToken string, clusterIDPortal string, metric metrics.FunctionLabel) bool {
+	portalWaitStart := time.Now()
+	defer metrics.UpdateDurationFromStart(metric, portalWaitStart)
+	for {
+		time.Sleep(30 * time.Second)
+		if core_utils.CheckStatusCluster(domainAPI, vpcID, accessToken, clusterIDPortal) {
+			klog.V(1).Infof("Status of cluster is SUCCEEDED")
+			return true
+		}
+		klog.V(1).Infof("Status of cluster is SCALING")
+		if core_utils.CheckErrorStatusCluster(domainAPI, vpcID, accessToken, clusterIDPortal) {
+			return false
+		}
+	}
+}
+
+// removeName returns names with the first occurrence of name removed.
+func removeName(names []string, name string) []string {
+	for i, candidate := range names {
+		if candidate == name {
+			return append(names[:i], names[i+1:]...)
+		}
+	}
+	return names
+}
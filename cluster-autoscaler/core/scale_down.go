@@ -21,7 +21,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"k8s.io/apimachinery/pkg/types"
-	"log"
 	"math"
 	"strconv"
 	"strings"
@@ -30,6 +29,7 @@ import (
 
 	"k8s.io/autoscaler/cluster-autoscaler/core/utils"
 
+	"k8s.io/autoscaler/cluster-autoscaler/auditlog"
 	"k8s.io/autoscaler/cluster-autoscaler/clusterstate"
 	"k8s.io/autoscaler/cluster-autoscaler/context"
 	"k8s.io/autoscaler/cluster-autoscaler/metrics"
@@ -38,6 +38,7 @@ import (
 	"k8s.io/autoscaler/cluster-autoscaler/utils/daemonset"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/deletetaint"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/errors"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/events"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
 	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
 
@@ -56,6 +57,10 @@ import (
 const (
 	// ScaleDownDisabledKey is the name of annotation marking node as not eligible for scale down.
 	ScaleDownDisabledKey = "cluster-autoscaler.kubernetes.io/scale-down-disabled"
+	// PreferRemoveKey is the name of annotation marking node for prioritized removal. Annotated
+	// nodes skip the utilization and unneeded time checks and become scale down candidates as
+	// soon as their pods can be rescheduled elsewhere.
+	PreferRemoveKey = "cluster-autoscaler.kubernetes.io/prefer-remove"
 	// DelayDeletionAnnotationPrefix is the prefix of annotation marking node as it needs to wait
 	// for other K8s components before deleting node.
 	DelayDeletionAnnotationPrefix = "delay-deletion.cluster-autoscaler.kubernetes.io/"
@@ -70,6 +75,9 @@ const (
 	MaxPodEvictionTime = 2 * time.Minute
 	// EvictionRetryTime is the time after CA retries failed pod eviction.
 	EvictionRetryTime = 10 * time.Second
+	// EvictionRetryBackoffCap is the maximum time between retries of an eviction
+	// rejected because of a temporarily violated PodDisruptionBudget.
+	EvictionRetryBackoffCap = 1 * time.Minute
 	// PodEvictionHeadroom is the extra time we wait to catch situations when the pod is ignoring SIGTERM and
 	// is killed with SIGKILL after MaxGracefulTerminationTime
 	PodEvictionHeadroom = 30 * time.Second
@@ -82,7 +90,6 @@ const (
 // NodeDeletionTracker keeps track of node deletions.
 type NodeDeletionTracker struct {
 	sync.Mutex
-	nonEmptyNodeDeleteInProgress bool
 	// A map of node delete results by node name. It's being constantly emptied into ScaleDownStatus
 	// objects in order to notify the ScaleDownStatusProcessor that the node drain has ended or that
 	// an error occurred during the deletion process.
@@ -90,6 +97,8 @@ type NodeDeletionTracker struct {
 	// A map which keeps track of deletions in progress for nodepools.
 	// Key is a node group id and value is a number of node deletions in progress.
 	deletionsInProgress map[string]int
+	// A map of deletion start times by node name, for nodes whose deletion is in flight.
+	deletionStartTimes map[string]time.Time
 }
 
 // Get current time. Proxy for unit tests.
@@ -100,6 +109,7 @@ func NewNodeDeletionTracker() *NodeDeletionTracker {
 	return &NodeDeletionTracker{
 		nodeDeleteResults:   make(map[string]status.NodeDeleteResult),
 		deletionsInProgress: make(map[string]int),
+		deletionStartTimes:  make(map[string]time.Time),
 	}
 }
 
@@ -107,32 +117,36 @@ func NewNodeDeletionTracker() *NodeDeletionTracker {
 func (n *NodeDeletionTracker) IsNonEmptyNodeDeleteInProgress() bool {
 	n.Lock()
 	defer n.Unlock()
-	return n.nonEmptyNodeDeleteInProgress
+	return len(n.deletionStartTimes) > 0
 }
 
-// SetNonEmptyNodeDeleteInProgress sets non empty node deletion in progress status.
-func (n *NodeDeletionTracker) SetNonEmptyNodeDeleteInProgress(status bool) {
-	n.Lock()
-	defer n.Unlock()
-	n.nonEmptyNodeDeleteInProgress = status
-}
-
-// StartDeletion increments node deletion in progress counter for the given nodegroup.
-func (n *NodeDeletionTracker) StartDeletion(nodeGroupId string) {
+// StartDeletion increments node deletion in progress counter for the given nodegroup
+// and records when the deletion of the given node started.
+func (n *NodeDeletionTracker) StartDeletion(nodeGroupId, nodeName string) {
 	n.Lock()
 	defer n.Unlock()
 	n.deletionsInProgress[nodeGroupId]++
+	n.deletionStartTimes[nodeName] = now()
 }
 
-// EndDeletion decrements node deletion in progress counter for the given nodegroup.
-func (n *NodeDeletionTracker) EndDeletion(nodeGroupId string) {
+// EndDeletion decrements node deletion in progress counter for the given nodegroup
+// and returns how long the deletion of the given node took.
+func (n *NodeDeletionTracker) EndDeletion(nodeGroupId, nodeName string) time.Duration {
 	n.Lock()
 	defer n.Unlock()
 
+	var duration time.Duration
+	if startTime, found := n.deletionStartTimes[nodeName]; found {
+		duration = now().Sub(startTime)
+		delete(n.deletionStartTimes, nodeName)
+	} else {
+		klog.Errorf("This should never happen, no deletion start time recorded for node %s", nodeName)
+	}
+
 	value, found := n.deletionsInProgress[nodeGroupId]
 	if !found {
 		klog.Errorf("This should never happen, counter for %s in DelayedNodeDeletionStatus wasn't found", nodeGroupId)
-		return
+		return duration
 	}
 	if value <= 0 {
 		klog.Errorf("This should never happen, counter for %s in DelayedNodeDeletionStatus isn't greater than 0, counter value is %d", nodeGroupId, value)
@@ -141,6 +155,18 @@ func (n *NodeDeletionTracker) EndDeletion(nodeGroupId string) {
 	if n.deletionsInProgress[nodeGroupId] <= 0 {
 		delete(n.deletionsInProgress, nodeGroupId)
 	}
+	return duration
+}
+
+// DeletionsInProgress returns how long each in flight node deletion has been running.
+func (n *NodeDeletionTracker) DeletionsInProgress() map[string]time.Duration {
+	n.Lock()
+	defer n.Unlock()
+	result := make(map[string]time.Duration, len(n.deletionStartTimes))
+	for nodeName, startTime := range n.deletionStartTimes {
+		result[nodeName] = now().Sub(startTime)
+	}
+	return result
 }
 
 // GetDeletionsInProgress returns the number of deletions in progress for the given node group.
@@ -359,12 +385,15 @@ type ScaleDown struct {
 	clusterStateRegistry   *clusterstate.ClusterStateRegistry
 	unneededNodes          map[string]time.Time
 	unneededNodesList      []*apiv1.Node
+	unneededNodePods       map[string][]*apiv1.Pod
 	unremovableNodes       map[string]time.Time
 	podLocationHints       map[string]string
 	nodeUtilizationMap     map[string]simulator.UtilizationInfo
 	usageTracker           *simulator.UsageTracker
 	nodeDeletionTracker    *NodeDeletionTracker
 	unremovableNodeReasons map[string]*simulator.UnremovableNode
+	nominatedNodeNames     map[string]bool
+	utilizationWeights     map[string]utils.UtilizationWeights
 }
 
 // NewScaleDown builds new ScaleDown object.
@@ -374,6 +403,7 @@ func NewScaleDown(context *context.AutoscalingContext, processors *processors.Au
 		processors:             processors,
 		clusterStateRegistry:   clusterStateRegistry,
 		unneededNodes:          make(map[string]time.Time),
+		unneededNodePods:       make(map[string][]*apiv1.Pod),
 		unremovableNodes:       make(map[string]time.Time),
 		podLocationHints:       make(map[string]string),
 		nodeUtilizationMap:     make(map[string]simulator.UtilizationInfo),
@@ -381,6 +411,8 @@ func NewScaleDown(context *context.AutoscalingContext, processors *processors.Au
 		unneededNodesList:      make([]*apiv1.Node, 0),
 		nodeDeletionTracker:    NewNodeDeletionTracker(),
 		unremovableNodeReasons: make(map[string]*simulator.UnremovableNode),
+		nominatedNodeNames:     make(map[string]bool),
+		utilizationWeights:     make(map[string]utils.UtilizationWeights),
 	}
 }
 
@@ -392,10 +424,24 @@ func (sd *ScaleDown) CleanUp(timestamp time.Time) {
 	sd.clearUnremovableNodeReasons()
 }
 
+// SetPodsAwaitingPreemption records the nodes nominated by pods waiting for
+// lower priority pods preemption. Those nodes are about to receive the
+// preempting pods, so UpdateUnneededNodes never marks them as unneeded.
+func (sd *ScaleDown) SetPodsAwaitingPreemption(pods []*apiv1.Pod) {
+	nominated := make(map[string]bool, len(pods))
+	for _, pod := range pods {
+		if pod.Status.NominatedNodeName != "" {
+			nominated[pod.Status.NominatedNodeName] = true
+		}
+	}
+	sd.nominatedNodeNames = nominated
+}
+
 // CleanUpUnneededNodes clears the list of unneeded nodes.
 func (sd *ScaleDown) CleanUpUnneededNodes() {
 	sd.unneededNodesList = make([]*apiv1.Node, 0)
 	sd.unneededNodes = make(map[string]time.Time)
+	sd.unneededNodePods = make(map[string][]*apiv1.Pod)
 }
 
 func (sd *ScaleDown) checkNodeUtilization(timestamp time.Time, node *apiv1.Node, nodeInfo *schedulerframework.NodeInfo) (simulator.UnremovableReason, *simulator.UtilizationInfo) {
@@ -418,10 +464,25 @@ func (sd *ScaleDown) checkNodeUtilization(timestamp time.Time, node *apiv1.Node,
 		return simulator.ScaleDownDisabledAnnotation, nil
 	}
 
+	// Skip nodes carrying a taint configured as never-scale-down, e.g. nodes
+	// dedicated to licensed software.
+	if taintKey, found := sd.scaleDownExcludedTaint(node); found {
+		klog.V(1).Infof("Skipping %s from delete consideration - the node carries the excluded taint %s", node.Name, taintKey)
+		return simulator.ScaleDownExcludedTaint, nil
+	}
+
 	utilInfo, err := simulator.CalculateUtilization(node, nodeInfo, sd.context.IgnoreDaemonSetsUtilization, sd.context.IgnoreMirrorPodsUtilization, timestamp)
 	if err != nil {
 		klog.Warningf("Failed to calculate utilization for %s: %v", node.Name, err)
 	}
+	sd.applyUtilizationWeights(node, &utilInfo)
+
+	// Nodes marked for prioritized removal skip the utilization threshold - they only
+	// need to be drainable.
+	if hasPreferRemoveAnnotation(node) {
+		klog.V(1).Infof("Node %s is marked for prioritized removal", node.Name)
+		return simulator.NoReason, &utilInfo
+	}
 
 	// nodeGroup, err := sd.context.CloudProvider.NodeGroupForNode(node)
 	// if err != nil {
@@ -449,6 +510,54 @@ func (sd *ScaleDown) checkNodeUtilization(timestamp time.Time, node *apiv1.Node,
 	return simulator.NoReason, &utilInfo
 }
 
+// refreshUtilizationWeights reloads the per pool utilization weights from the
+// autoscaling configmap, once per loop rather than once per candidate. Only
+// pools with explicitly configured weights end up in the map.
+func (sd *ScaleDown) refreshUtilizationWeights(kubeclient kube_client.Interface, scaleDownCandidates []*apiv1.Node) {
+	weightsPerPool := make(map[string]utils.UtilizationWeights)
+	for _, node := range scaleDownCandidates {
+		pool := node.Labels[utils.WorkerPoolLabel]
+		if _, checked := weightsPerPool[pool]; checked {
+			continue
+		}
+		if weights, configured := utils.GetUtilizationWeights(kubeclient, pool); configured {
+			weightsPerPool[pool] = weights
+		}
+	}
+	sd.utilizationWeights = weightsPerPool
+}
+
+// applyUtilizationWeights recomputes which resource drives the scale-down
+// decision for pools with configured utilization weights: the maximum of
+// weight * utilization over the resources with a positive weight. The per
+// resource utilizations are left untouched for reporting.
+func (sd *ScaleDown) applyUtilizationWeights(node *apiv1.Node, utilInfo *simulator.UtilizationInfo) {
+	weights, configured := sd.utilizationWeights[node.Labels[utils.WorkerPoolLabel]]
+	if !configured {
+		return
+	}
+	picked := false
+	for _, weighted := range []struct {
+		resourceName apiv1.ResourceName
+		weight       float64
+		utilization  float64
+	}{
+		{apiv1.ResourceCPU, weights.CPU, utilInfo.CpuUtil},
+		{apiv1.ResourceMemory, weights.Memory, utilInfo.MemUtil},
+		{simulator.ResourceNvidiaGPU, weights.GPU, utilInfo.GpuUtil},
+	} {
+		if weighted.weight <= 0 {
+			continue
+		}
+		value := weighted.weight * weighted.utilization
+		if !picked || value > utilInfo.Utilization {
+			utilInfo.ResourceName = weighted.resourceName
+			utilInfo.Utilization = value
+			picked = true
+		}
+	}
+}
+
 // UpdateUnneededNodes calculates which nodes are not needed, i.e. all pods can be scheduled somewhere else,
 // and updates unneededNodes map accordingly. It also computes information where pods can be rescheduled and
 // node utilization level. The computations are made only for the nodes managed by CA.
@@ -480,6 +589,7 @@ func (sd *ScaleDown) UpdateUnneededNodes(
 	}
 
 	sd.updateUnremovableNodes(timestamp)
+	sd.refreshUtilizationWeights(kubeclient, scaleDownCandidates)
 
 	skipped := 0
 	utilizationMap := make(map[string]simulator.UtilizationInfo)
@@ -498,7 +608,7 @@ func (sd *ScaleDown) UpdateUnneededNodes(
 	// Phase1 - look at the nodes utilization. Calculate the utilization
 	// only for the managed nodes.
 	for _, node := range scaleDownCandidates {
-		if strings.Contains(node.Name, "master") {
+		if !utils.IsWorkerNode(node) {
 			continue
 		}
 		nodeInfo, err := sd.context.ClusterSnapshot.NodeInfos().Get(node.Name)
@@ -529,6 +639,19 @@ func (sd *ScaleDown) UpdateUnneededNodes(
 			continue
 		}
 
+		if window := sd.context.AutoscalingOptions.ScaleDownRecentPodActivityWindow; window > 0 &&
+			hasRecentPodActivity(kubeclient, node.Name, window, timestamp) {
+			klog.V(2).Infof("Node %s is not suitable for removal - pods terminated on it within the last %v", node.Name, window)
+			sd.addUnremovableNodeReason(node, simulator.RecentPodActivity)
+			continue
+		}
+
+		if sd.nominatedNodeNames[node.Name] {
+			klog.V(2).Infof("Node %s is not suitable for removal - it is the nominated target of a pod awaiting preemption", node.Name)
+			sd.addUnremovableNodeReason(node, simulator.AwaitingPreemption)
+			continue
+		}
+
 		//if strings.Contains(node.Name, "master") {
 		//	continue
 		//}
@@ -574,6 +697,11 @@ func (sd *ScaleDown) UpdateUnneededNodes(
 		destinations = append(destinations, destinationNode.Name)
 	}
 
+	simulationDeadline := time.Time{}
+	if budget := sd.context.AutoscalingOptions.ScaleDownSimulationTimeout; budget > 0 {
+		simulationDeadline = time.Now().Add(budget)
+	}
+
 	//Look for nodes to remove in the current candidates
 	nodesToRemove, unremovable, newHints, simulatorErr := simulator.FindNodesToRemove(
 		currentCandidates,
@@ -584,7 +712,8 @@ func (sd *ScaleDown) UpdateUnneededNodes(
 		sd.podLocationHints,
 		sd.usageTracker,
 		timestamp,
-		pdbs)
+		pdbs,
+		simulationDeadline)
 	if simulatorErr != nil {
 		return sd.markSimulationError(simulatorErr, timestamp)
 	}
@@ -622,7 +751,8 @@ func (sd *ScaleDown) UpdateUnneededNodes(
 				sd.podLocationHints,
 				sd.usageTracker,
 				timestamp,
-				pdbs)
+				pdbs,
+				simulationDeadline)
 		if simulatorErr != nil {
 			return sd.markSimulationError(simulatorErr, timestamp)
 		}
@@ -643,9 +773,11 @@ func (sd *ScaleDown) UpdateUnneededNodes(
 	// Update the timestamp map.
 	result := make(map[string]time.Time)
 	unneededNodesList := make([]*apiv1.Node, 0, len(nodesToRemove))
+	unneededNodePods := make(map[string][]*apiv1.Pod, len(nodesToRemove))
 	for _, node := range nodesToRemove {
 		name := node.Node.Name
 		unneededNodesList = append(unneededNodesList, node.Node)
+		unneededNodePods[name] = node.PodsToReschedule
 		if val, found := sd.unneededNodes[name]; !found {
 			result[name] = timestamp
 		} else {
@@ -675,6 +807,7 @@ func (sd *ScaleDown) UpdateUnneededNodes(
 	// Update state and metrics
 	sd.unneededNodesList = unneededNodesList
 	sd.unneededNodes = result
+	sd.unneededNodePods = unneededNodePods
 	sd.podLocationHints = newHints
 	sd.nodeUtilizationMap = utilizationMap
 	// sd.clusterStateRegistry.UpdateScaleDownCandidates(sd.unneededNodesList, timestamp)
@@ -748,6 +881,7 @@ func (sd *ScaleDown) markSimulationError(simulatorErr errors.AutoscalerError,
 	klog.Errorf("Error while simulating node drains: %v", simulatorErr)
 	sd.unneededNodesList = make([]*apiv1.Node, 0)
 	sd.unneededNodes = make(map[string]time.Time)
+	sd.unneededNodePods = make(map[string][]*apiv1.Pod)
 	sd.nodeUtilizationMap = make(map[string]simulator.UtilizationInfo)
 	// sd.clusterStateRegistry.UpdateScaleDownCandidates(sd.unneededNodesList, timestamp)
 	return simulatorErr.AddPrefix("error while simulating node drains: ")
@@ -785,9 +919,27 @@ func (sd *ScaleDown) chooseCandidates(nodes []string) (candidates []string, nonC
 //	return result
 //}
 
+// unneededNodesStatus maps the current unneeded nodes to status.ScaleDownNode
+// entries, so the candidates can be reported even when no node is actually
+// deleted, e.g. when scale down is in cooldown.
+func (sd *ScaleDown) unneededNodesStatus() []*status.ScaleDownNode {
+	var result []*status.ScaleDownNode
+	for _, node := range sd.unneededNodesList {
+		result = append(result, &status.ScaleDownNode{
+			Node:        node,
+			UtilInfo:    sd.nodeUtilizationMap[node.Name],
+			EvictedPods: sd.unneededNodePods[node.Name],
+		})
+	}
+	return result
+}
+
 // SoftTaintUnneededNodes manage soft taints of unneeded nodes.
 func (sd *ScaleDown) SoftTaintUnneededNodes(allNodes []*apiv1.Node) (errors []error) {
 	defer metrics.UpdateDurationFromStart(metrics.ScaleDownSoftTaintUnneeded, time.Now())
+	if utils.InReadOnlyMode() {
+		return nil
+	}
 	apiCallBudget := sd.context.AutoscalingOptions.MaxBulkSoftTaintCount
 	timeBudget := sd.context.AutoscalingOptions.MaxBulkSoftTaintTime
 	skippedNodes := 0
@@ -860,10 +1012,17 @@ func (sd *ScaleDown) TryToScaleDown(
 	// fmt.Println("nodes without master are: ")
 
 	for _, node := range nodesWithoutMaster {
+		// Workers already being deleted must not count toward the current size or
+		// be offered as candidates again, otherwise an extra portal scale down can
+		// be triggered while the previous one is still in flight.
+		if isNodeBeingDeleted(node, currentTime) {
+			continue
+		}
 		nodesWithoutMasterNames = append(nodesWithoutMasterNames, node.Name)
 	}
 
 	candidateNames := make([]string, 0)
+	preferredCandidates := make(map[string]bool)
 	//readinessMap := make(map[string]bool)
 	//candidateNodeGroups := make(map[string]cloudprovider.NodeGroup)
 	// gpuLabel := sd.context.CloudProvider.GPULabel()
@@ -880,7 +1039,12 @@ func (sd *ScaleDown) TryToScaleDown(
 	//nodeGroupSize := utils.GetNodeGroupSizeMap(sd.context.CloudProvider)
 	// resourcesWithLimits := resourceLimiter.GetResources()
 
-	if len(nodesWithoutMasterNames) <= utils.GetMinSizeNodeGroup(kubeclient) {
+	minSizeNodeGroup, minSizeErr := utils.GetMinSizeNodeGroup(kubeclient)
+	if minSizeErr != nil {
+		scaleDownStatus.Result = status.ScaleDownError
+		return scaleDownStatus, errors.ToAutoscalerError(errors.TransientError, minSizeErr)
+	}
+	if len(nodesWithoutMasterNames) <= minSizeNodeGroup {
 		klog.V(4).Infof("Node group min size reached")
 		scaleDownStatus.Result = status.ScaleDownNoUnneeded
 		return scaleDownStatus, nil
@@ -903,6 +1067,15 @@ func (sd *ScaleDown) TryToScaleDown(
 			continue
 		}
 
+		// A node that has been NotReady longer than the unready time is cheaper to
+		// remove than draining a healthy one, so it skips the unneeded wait and is
+		// preferred like a node marked for prioritized removal.
+		longNotReady := false
+		if ready, lastTransition, err := kubernetes.GetReadinessState(node); err == nil && !ready &&
+			lastTransition.Add(sd.context.AutoscalingOptions.NodeGroupDefaults.ScaleDownUnreadyTime).Before(currentTime) {
+			longNotReady = true
+		}
+
 		//ready, _, _ := kube_util.GetReadinessState(node)
 		//readinessMap[node.Name] = ready
 
@@ -948,11 +1121,17 @@ func (sd *ScaleDown) TryToScaleDown(
 		//	klog.Errorf("Error trying to get ScaleDownUnneededTime for node %s (in group: %s)", node.Name, nodeGroup.Id())
 		//	continue
 		//}
-		if !unneededSince.Add(unneededTime).Before(currentTime) {
+		// Nodes marked for prioritized removal do not have to stay unneeded for the
+		// full period - an admin wants them gone as soon as they are drainable.
+		if !hasPreferRemoveAnnotation(node) && !longNotReady && !unneededSince.Add(unneededTime).Before(currentTime) {
 			sd.addUnremovableNodeReason(node, simulator.NotUnneededLongEnough)
 			continue
 		}
 
+		if hasPreferRemoveAnnotation(node) || longNotReady {
+			preferredCandidates[node.Name] = true
+		}
+
 		//size := utils.GetNodeGroupSize()
 		//minSize := utils.GetMinSizeNodeGroup(kubeclient)
 		//if (len(nodesWithoutMasterNames) - len(candidateNames)) <= minSize {
@@ -1024,6 +1203,10 @@ func (sd *ScaleDown) TryToScaleDown(
 	//}
 
 	findNodesToRemoveStart := time.Now()
+	simulationDeadline := time.Time{}
+	if budget := sd.context.AutoscalingOptions.ScaleDownSimulationTimeout; budget > 0 {
+		simulationDeadline = findNodesToRemoveStart.Add(budget)
+	}
 
 	// We look for only 1 node so new hints may be incomplete.
 	_, unremovable, _, err := simulator.FindNodesToRemove(
@@ -1035,7 +1218,8 @@ func (sd *ScaleDown) TryToScaleDown(
 		sd.podLocationHints,
 		sd.usageTracker,
 		time.Now(),
-		pdbs)
+		pdbs,
+		simulationDeadline)
 	findNodesToRemoveDuration = time.Now().Sub(findNodesToRemoveStart)
 
 	for _, unremovableNode := range unremovable {
@@ -1085,24 +1269,154 @@ func (sd *ScaleDown) TryToScaleDown(
 	//fmt.Println("vpcID is: ", vpcID)
 	//fmt.Println("access token is: ", accessToken)
 
-	var workerNameToRemove string
+	// The portal always removes the highest numbered workers first, so candidates
+	// are picked from the top of the worker numbering down. Several unneeded nodes
+	// can be removed in one iteration, bounded by MaxDrainParallelism.
+	nodesToRemoveCount := len(candidateNames)
+	if maxDrainParallelism := sd.context.AutoscalingOptions.MaxDrainParallelism; nodesToRemoveCount > maxDrainParallelism && maxDrainParallelism >= 1 {
+		nodesToRemoveCount = maxDrainParallelism
+	}
+	if available := len(nodesWithoutMasterNames) - minSizeNodeGroup; nodesToRemoveCount > available {
+		nodesToRemoveCount = available
+	}
+
+	// Index the workers by number once instead of rescanning the node list per
+	// candidate, which is quadratic on large clusters.
+	workerNameByNumber := make(map[int]string, len(nodesWithoutMasterNames))
 	for _, nodeName := range nodesWithoutMasterNames {
-		if strings.HasSuffix(nodeName, "worker"+strconv.Itoa(len(nodesWithoutMasterNames))) {
-			workerNameToRemove = nodeName
+		if idx := strings.LastIndex(nodeName, "worker"); idx >= 0 {
+			if number, err := strconv.Atoi(nodeName[idx+len("worker"):]); err == nil {
+				workerNameByNumber[number] = nodeName
+			}
 		}
 	}
-	klog.V(1).Infof("Scaling down node %s", workerNameToRemove)
-	if !checkWorkerNodeCanBeScaleDown(kubeclient, workerNameToRemove) {
+	workerNamesToRemove := make([]string, 0, nodesToRemoveCount)
+	for i := 0; i < nodesToRemoveCount; i++ {
+		if nodeName, found := workerNameByNumber[len(nodesWithoutMasterNames)-i]; found {
+			workerNamesToRemove = append(workerNamesToRemove, nodeName)
+		}
+	}
+
+	// Workers are removed in reverse numbering order, so stop at the first
+	// worker that cannot be scaled down.
+	for i, workerName := range workerNamesToRemove {
+		if !checkWorkerNodeCanBeScaleDown(kubeclient, workerName, sd.context.AutoscalingOptions.ScaleDownAllowOwnerlessPods) {
+			workerNamesToRemove = workerNamesToRemove[:i]
+			break
+		}
+	}
+
+	workerNamesToRemove = sd.trimForHeadroom(workerNamesToRemove, kubeclient, currentTime)
+
+	if utils.InReadOnlyMode() {
+		if len(workerNamesToRemove) > 0 {
+			klog.V(1).Infof("Read-only mode: would scale down %v nodes: %s", len(workerNamesToRemove), strings.Join(workerNamesToRemove, ", "))
+			auditlog.Log("scale-down-recommendation", map[string]string{
+				"nodes": strings.Join(workerNamesToRemove, ","),
+				"count": strconv.Itoa(len(workerNamesToRemove)),
+			})
+		}
+		scaleDownStatus.Result = status.ScaleDownNotTried
+		return scaleDownStatus, nil
+	}
+
+	// The portal removes workers by number, so a node marked for prioritized removal
+	// cannot be deleted until it is among the highest numbered candidates. Cordon and
+	// soft taint the ones that have to wait so they stop receiving new pods.
+	if len(preferredCandidates) > 0 {
+		removeSet := make(map[string]bool, len(workerNamesToRemove))
+		for _, workerName := range workerNamesToRemove {
+			removeSet[workerName] = true
+		}
+		for preferredName := range preferredCandidates {
+			if removeSet[preferredName] {
+				continue
+			}
+			klog.V(1).Infof("Node %s is marked for prioritized removal but the portal removes workers by number - cordoning it until its turn comes", preferredName)
+			cordonWorkerNode(kubeclient, preferredName)
+			nodeInfo, err := sd.context.ClusterSnapshot.NodeInfos().Get(preferredName)
+			if err != nil {
+				continue
+			}
+			if err := deletetaint.MarkDeletionCandidate(nodeInfo.Node(), sd.context.ClientSet); err != nil {
+				klog.Warningf("Failed to soft taint node %s: %v", preferredName, err)
+			}
+		}
+	}
+
+	if len(workerNamesToRemove) == 0 {
 		klog.V(1).Infof("Cannot perform scale down action")
 		scaleDownStatus.Result = status.ScaleDownNoUnneeded
 		return scaleDownStatus, nil
 	}
+	klog.V(1).Infof("Scaling down %v nodes: %s", len(workerNamesToRemove), strings.Join(workerNamesToRemove, ", "))
+	auditlog.Log("scale-down-decision", map[string]string{
+		"nodes": strings.Join(workerNamesToRemove, ","),
+		"count": strconv.Itoa(len(workerNamesToRemove)),
+	})
+
+	if sd.context.AutoscalingOptions.ScaleDownCordonOnly {
+		// Leave the portal deletion to a human or an external system; just cordon
+		// and soft taint the candidates so they stop receiving new pods.
+		for _, workerName := range workerNamesToRemove {
+			cordonWorkerNode(kubeclient, workerName)
+			nodeInfo, err := sd.context.ClusterSnapshot.NodeInfos().Get(workerName)
+			if err != nil {
+				continue
+			}
+			if err := deletetaint.MarkDeletionCandidate(nodeInfo.Node(), sd.context.ClientSet); err != nil {
+				klog.Warningf("Failed to soft taint node %s: %v", workerName, err)
+			}
+		}
+		klog.V(1).Infof("Cordon-only mode: marked %v nodes for removal, leaving the deletion to an external system", len(workerNamesToRemove))
+		scaleDownStatus.Result = status.ScaleDownNoNodeDeleted
+		return scaleDownStatus, nil
+	}
+
+	if warmPoolSize := sd.context.AutoscalingOptions.WarmPoolSize; warmPoolSize > 0 {
+		// Refill the warm pool from the scale down candidates before deleting
+		// anything. The portal always removes the highest numbered workers, which
+		// are now the warm ones, so the remaining candidates are left for later
+		// loops rather than deleted underneath the warm pool.
+		warmSlots := warmPoolSize - countWarmNodes(kubeclient)
+		moved := 0
+		for _, workerName := range workerNamesToRemove {
+			if moved >= warmSlots {
+				break
+			}
+			if err := markNodeWarm(kubeclient, workerName); err != nil {
+				klog.Warningf("Failed to return node %s to the warm pool: %v", workerName, err)
+				continue
+			}
+			moved++
+		}
+		if moved > 0 {
+			klog.V(1).Infof("Returned %v nodes to the warm pool instead of deleting them", moved)
+			scaleDownStatus.Result = status.ScaleDownNoNodeDeleted
+			return scaleDownStatus, nil
+		}
+	}
 
 	domainAPI := utils.GetDomainApiConformEnv(env)
 
 	if utils.CheckStatusCluster(domainAPI, vpcID, accessToken, clusterIDPortal) {
-		//cordonWorkerNodeAndDeletePod(kubeclient, workerNameToRemove)
-		utils.PerformScaleDown(domainAPI, vpcID, accessToken, 1, idCluster, clusterIDPortal)
+		workerNamesToRemove = runPreDeletionHooks(sd.context.AutoscalingOptions, kubeclient, workerNamesToRemove)
+		if len(workerNamesToRemove) == 0 {
+			scaleDownStatus.Result = status.ScaleDownNoNodeDeleted
+			return scaleDownStatus, nil
+		}
+		workerPrices := utils.GetWorkerHourlyPrices(kubeclient)
+		costDelta := 0.0
+		for _, workerName := range workerNamesToRemove {
+			sd.nodeDeletionTracker.StartDeletion(clusterIDPortal, workerName)
+			metrics.RegisterNodesDeleted(workerPoolOfNode(kubeclient, workerName), 1)
+			costDelta -= utils.WorkerHourlyPrice(workerPrices, workerPoolOfNode(kubeclient, workerName))
+		}
+		metrics.ObserveScaleCostDelta("scale_down", costDelta)
+		metrics.UpdateNodeDeletionsInProgress(clusterIDPortal, sd.nodeDeletionTracker.GetDeletionsInProgress(clusterIDPortal))
+		drainWorkerNodes(kubeclient, sd.context.Recorder, workerNamesToRemove, sd.context.AutoscalingOptions.MaxDrainParallelism, sd.context.AutoscalingOptions.ForceDrainTimeout)
+		utils.PerformScaleDown(domainAPI, vpcID, accessToken, len(workerNamesToRemove), idCluster, clusterIDPortal)
+		portalWaitStart := time.Now()
 		for {
 			time.Sleep(30 * time.Second)
 			isSucceededStatus := utils.CheckStatusCluster(domainAPI, vpcID, accessToken, clusterIDPortal)
@@ -1115,7 +1429,7 @@ func (sd *ScaleDown) TryToScaleDown(
 			}
 			isErrorStatus := utils.CheckErrorStatusCluster(domainAPI, vpcID, accessToken, clusterIDPortal)
 			if isErrorStatus {
-				utils.PerformScaleDown(domainAPI, vpcID, accessToken, 1, idCluster, clusterIDPortal)
+				utils.PerformScaleDown(domainAPI, vpcID, accessToken, len(workerNamesToRemove), idCluster, clusterIDPortal)
 				for {
 					time.Sleep(30 * time.Second)
 					if utils.CheckStatusCluster(domainAPI, vpcID, accessToken, clusterIDPortal) {
@@ -1125,6 +1439,7 @@ func (sd *ScaleDown) TryToScaleDown(
 				break
 			}
 		}
+		metrics.UpdateDurationFromStart(metrics.ScaleDownPortalWait, portalWaitStart)
 	} else {
 		klog.V(1).Infof("Another action is being performed")
 		klog.V(1).Infof("Waiting for scaling ...")
@@ -1132,6 +1447,18 @@ func (sd *ScaleDown) TryToScaleDown(
 		return scaleDownStatus, nil
 	}
 
+	for _, workerName := range workerNamesToRemove {
+		duration := sd.nodeDeletionTracker.EndDeletion(clusterIDPortal, workerName)
+		metrics.ObserveNodeDeletionDuration(duration)
+		if unneededSince, found := sd.unneededNodes[workerName]; found {
+			// End to end latency from the node first being marked unneeded to its
+			// deletion being confirmed by the portal.
+			metrics.ObserveScaleOperationDuration(clusterIDPortal, "scale_down", time.Since(unneededSince))
+		}
+		sd.nodeDeletionTracker.AddNodeDeleteResult(workerName, status.NodeDeleteResult{ResultType: status.NodeDeleteOk})
+	}
+	metrics.UpdateNodeDeletionsInProgress(clusterIDPortal, sd.nodeDeletionTracker.GetDeletionsInProgress(clusterIDPortal))
+
 	//go func() {
 	//	//// Finishing the delete process once this goroutine is over.
 	//	//var result status.NodeDeleteResult
@@ -1396,7 +1723,7 @@ func evictDaemonSetPods(clusterSnapshot simulator.ClusterSnapshot, nodeToDelete
 
 func evictPod(podToEvict *apiv1.Pod, isDaemonSetPod bool, client kube_client.Interface, recorder kube_record.EventRecorder,
 	maxGracefulTerminationSec int, retryUntil time.Time, waitBetweenRetries time.Duration) status.PodEvictionResult {
-	recorder.Eventf(podToEvict, apiv1.EventTypeNormal, "ScaleDown", "deleting pod for node scale down")
+	events.Recordf(recorder, podToEvict, apiv1.EventTypeNormal, events.ScaleDownEviction, "evicting pod for node scale down: pod=%s/%s", podToEvict.Namespace, podToEvict.Name)
 
 	maxTermination := int64(apiv1.DefaultTerminationGracePeriodSeconds)
 	if podToEvict.Spec.TerminationGracePeriodSeconds != nil {
@@ -1408,7 +1735,9 @@ func evictPod(podToEvict *apiv1.Pod, isDaemonSetPod bool, client kube_client.Int
 	}
 
 	var lastError error
-	for first := true; first || time.Now().Before(retryUntil); time.Sleep(waitBetweenRetries) {
+	waitTime := waitBetweenRetries
+	pdbBlockReported := false
+	for first := true; first || time.Now().Before(retryUntil); time.Sleep(waitTime) {
 		first = false
 		eviction := &policyv1.Eviction{
 			ObjectMeta: metav1.ObjectMeta{
@@ -1421,12 +1750,31 @@ func evictPod(podToEvict *apiv1.Pod, isDaemonSetPod bool, client kube_client.Int
 		}
 		lastError = client.CoreV1().Pods(podToEvict.Namespace).Evict(ctx.TODO(), eviction)
 		if lastError == nil || kube_errors.IsNotFound(lastError) {
+			if !isDaemonSetPod {
+				metrics.RegisterEvictedPod(podToEvict.Namespace, podOwningController(podToEvict))
+			}
 			return status.PodEvictionResult{Pod: podToEvict, TimedOut: false, Err: nil}
 		}
+		// 429 means the eviction is temporarily blocked by a PodDisruptionBudget.
+		// Back off instead of hammering the API server at a fixed interval until
+		// the per-pod timeout expires.
+		if kube_errors.IsTooManyRequests(lastError) {
+			if !pdbBlockReported && !isDaemonSetPod {
+				events.Recordf(recorder, podToEvict, apiv1.EventTypeWarning, events.ScaleDownBlockedPDB, "eviction blocked by PodDisruptionBudget: pod=%s/%s", podToEvict.Namespace, podToEvict.Name)
+				pdbBlockReported = true
+			}
+			waitTime = 2 * waitTime
+			if waitTime > EvictionRetryBackoffCap {
+				waitTime = EvictionRetryBackoffCap
+			}
+		} else {
+			waitTime = waitBetweenRetries
+		}
 	}
+	metrics.RegisterFailedEvictions(1)
 	if !isDaemonSetPod {
 		klog.Errorf("Failed to evict pod %s, error: %v", podToEvict.Name, lastError)
-		recorder.Eventf(podToEvict, apiv1.EventTypeWarning, "ScaleDownFailed", "failed to delete pod for ScaleDown")
+		events.Recordf(recorder, podToEvict, apiv1.EventTypeWarning, events.ScaleDownEvictionFailed, "failed to evict pod within allowed timeout: pod=%s/%s error=%v", podToEvict.Namespace, podToEvict.Name, lastError)
 	}
 	return status.PodEvictionResult{Pod: podToEvict, TimedOut: true, Err: fmt.Errorf("failed to evict pod %s/%s within allowed timeout (last error: %v)", podToEvict.Namespace, podToEvict.Name, lastError)}
 }
@@ -1584,6 +1932,23 @@ func hasNoScaleDownAnnotation(node *apiv1.Node) bool {
 	return node.Annotations[ScaleDownDisabledKey] == "true"
 }
 
+// scaleDownExcludedTaint returns the first taint on the node whose key is
+// listed in ScaleDownExcludeTaintKeys, if any.
+func (sd *ScaleDown) scaleDownExcludedTaint(node *apiv1.Node) (string, bool) {
+	for _, taint := range node.Spec.Taints {
+		for _, excludedKey := range sd.context.ScaleDownExcludeTaintKeys {
+			if taint.Key == excludedKey {
+				return taint.Key, true
+			}
+		}
+	}
+	return "", false
+}
+
+func hasPreferRemoveAnnotation(node *apiv1.Node) bool {
+	return node.Annotations[PreferRemoveKey] == "true"
+}
+
 const (
 	apiServerLabelKey   = "component"
 	apiServerLabelValue = "kube-apiserver"
@@ -1614,7 +1979,7 @@ type patchStringValue struct {
 	Value bool   `json:"value"`
 }
 
-func cordonWorkerNodeAndDeletePod(kubeclient kube_client.Interface, workerName string) {
+func cordonWorkerNode(kubeclient kube_client.Interface, workerName string) {
 	payload := []patchStringValue{{
 		Op:    "replace",
 		Path:  "/spec/unschedulable",
@@ -1623,13 +1988,21 @@ func cordonWorkerNodeAndDeletePod(kubeclient kube_client.Interface, workerName s
 	payloadBytes, _ := json.Marshal(payload)
 	klog.V(1).Infof("Cordon node %s", workerName)
 	kubeclient.CoreV1().Nodes().Patch(ctx.Background(), workerName, types.JSONPatchType, payloadBytes, metav1.PatchOptions{})
-	pods, err := kubeclient.CoreV1().Pods("").List(ctx.Background(), metav1.ListOptions{})
+}
+
+func cordonWorkerNodeAndDeletePod(kubeclient kube_client.Interface, workerName string) {
+	cordonWorkerNode(kubeclient, workerName)
+	pods, err := kubeclient.CoreV1().Pods("").List(ctx.Background(), metav1.ListOptions{
+		FieldSelector:   "spec.nodeName=" + workerName,
+		ResourceVersion: "0",
+	})
 	if err != nil {
-		log.Fatal(err)
+		klog.Errorf("Failed to list pods on node %s: %v", workerName, err)
+		return
 	}
 	var gracePeriodSeconds int64 = 30
 	for _, pod := range pods.Items {
-		if pod.Spec.NodeName == workerName && pod.OwnerReferences[0].Kind != "DaemonSet" {
+		if len(pod.OwnerReferences) == 0 || pod.OwnerReferences[0].Kind != "DaemonSet" {
 			// for _, volume := range pod.Spec.Volumes {
 			// 	if volume.EmptyDir != nil {
 			// 		klog.V(1).Infof("Evict pod %s", pod.Name)
@@ -1638,35 +2011,221 @@ func cordonWorkerNodeAndDeletePod(kubeclient kube_client.Interface, workerName s
 			// 	}
 			// }
 			kubeclient.CoreV1().Pods(pod.Namespace).Delete(ctx.Background(), pod.Name, metav1.DeleteOptions{GracePeriodSeconds: &gracePeriodSeconds})
+			metrics.RegisterEvictedPod(pod.Namespace, podOwningController(&pod))
 		}
 	}
 }
 
-func checkWorkerNodeCanBeScaleDown(kubeclient kube_client.Interface, workerNodeName string) bool {
+// podOwningController returns the owning controller of the pod in kind/name form,
+// or "none" for ownerless pods, for use as a metric label.
+func podOwningController(pod *apiv1.Pod) string {
+	if len(pod.OwnerReferences) == 0 {
+		return "none"
+	}
+	owner := pod.OwnerReferences[0]
+	return owner.Kind + "/" + owner.Name
+}
+
+// drainWorkerNodes cordons the given workers and deletes their pods with bounded
+// parallelism, so a multi-node scale down doesn't drain strictly one node at a time.
+func drainWorkerNodes(kubeclient kube_client.Interface, recorder kube_record.EventRecorder, workerNames []string, maxParallel int, forceDrainTimeout time.Duration) {
+	if maxParallel < 1 {
+		maxParallel = 1
+	}
+	sem := make(chan struct{}, maxParallel)
+	var wg sync.WaitGroup
+	for _, workerName := range workerNames {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(name string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			cordonWorkerNodeAndDeletePod(kubeclient, name)
+			if forceDrainTimeout > 0 {
+				forceDrainWorkerNode(kubeclient, recorder, name, forceDrainTimeout)
+			}
+		}(workerName)
+	}
+	wg.Wait()
+}
+
+// forceDrainWorkerNode waits until the drained worker has no pods left. If some pods
+// are still running when the deadline expires (stuck finalizers, misbehaving PDBs),
+// they are deleted with no grace period and a ForcedDrain event is emitted, instead
+// of leaving a cordoned half-drained node forever.
+func forceDrainWorkerNode(kubeclient kube_client.Interface, recorder kube_record.EventRecorder, workerName string, deadline time.Duration) {
+	for start := time.Now(); time.Now().Sub(start) < deadline; time.Sleep(10 * time.Second) {
+		if len(listPodsToDrain(kubeclient, workerName)) == 0 {
+			return
+		}
+	}
+	remaining := listPodsToDrain(kubeclient, workerName)
+	if len(remaining) == 0 {
+		return
+	}
+	klog.V(1).Infof("Drain of node %s did not complete within %v, force deleting %v remaining pods", workerName, deadline, len(remaining))
+	if node, err := kubeclient.CoreV1().Nodes().Get(ctx.Background(), workerName, metav1.GetOptions{}); err == nil {
+		recorder.Eventf(node, apiv1.EventTypeWarning, events.ForcedDrain, "force deleting pods remaining after drain deadline: node=%s remainingPods=%v deadline=%v", node.Name, len(remaining), deadline)
+	}
+	var noGracePeriod int64 = 0
+	for _, pod := range remaining {
+		err := kubeclient.CoreV1().Pods(pod.Namespace).Delete(ctx.Background(), pod.Name, metav1.DeleteOptions{GracePeriodSeconds: &noGracePeriod})
+		if err != nil && !kube_errors.IsNotFound(err) {
+			klog.Errorf("Failed to force delete pod %s/%s: %v", pod.Namespace, pod.Name, err)
+		}
+	}
+}
+
+// countWarmNodes returns the current number of warm standby nodes.
+func countWarmNodes(kubeclient kube_client.Interface) int {
+	nodeList, err := kubeclient.CoreV1().Nodes().List(ctx.Background(), metav1.ListOptions{
+		LabelSelector:   utils.WarmPoolLabel + "=true",
+		ResourceVersion: "0",
+	})
+	if err != nil {
+		klog.Errorf("Failed to list warm pool nodes: %v", err)
+		return 0
+	}
+	return len(nodeList.Items)
+}
+
+// markNodeWarm cordons the node and labels it as part of the warm pool.
+func markNodeWarm(kubeclient kube_client.Interface, workerName string) error {
+	node, err := kubeclient.CoreV1().Nodes().Get(ctx.Background(), workerName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	freshNode := node.DeepCopy()
+	freshNode.Spec.Unschedulable = true
+	if freshNode.Labels == nil {
+		freshNode.Labels = map[string]string{}
+	}
+	freshNode.Labels[utils.WarmPoolLabel] = "true"
+	_, err = kubeclient.CoreV1().Nodes().Update(ctx.Background(), freshNode, metav1.UpdateOptions{})
+	return err
+}
+
+// workerPoolOfNode returns the worker pool label of the node, or "default" when
+// the node has no pool label or cannot be fetched.
+func workerPoolOfNode(kubeclient kube_client.Interface, workerName string) string {
+	node, err := kubeclient.CoreV1().Nodes().Get(ctx.Background(), workerName, metav1.GetOptions{})
+	if err != nil {
+		klog.Errorf("Failed to get node %s: %v", workerName, err)
+		return "default"
+	}
+	if pool := node.Labels[utils.WorkerPoolLabel]; pool != "" {
+		return pool
+	}
+	return "default"
+}
+
+// hasRecentPodActivity checks whether any pod on the given node terminated within
+// the window before timestamp. Such a node is likely to receive similar pods again
+// soon, e.g. from a CronJob, so deleting it would just force a scale up shortly after.
+func hasRecentPodActivity(kubeclient kube_client.Interface, nodeName string, window time.Duration, timestamp time.Time) bool {
+	pods, err := kubeclient.CoreV1().Pods("").List(ctx.Background(), metav1.ListOptions{
+		FieldSelector:   "spec.nodeName=" + nodeName,
+		ResourceVersion: "0",
+	})
+	if err != nil {
+		klog.Errorf("Failed to list pods on node %s: %v", nodeName, err)
+		return false
+	}
+	activityCutoff := timestamp.Add(-window)
+	for _, pod := range pods.Items {
+		for _, containerStatus := range pod.Status.ContainerStatuses {
+			if terminated := containerStatus.State.Terminated; terminated != nil && terminated.FinishedAt.Time.After(activityCutoff) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// listPodsToDrain returns the non-DaemonSet pods still running on the given node.
+func listPodsToDrain(kubeclient kube_client.Interface, workerName string) []apiv1.Pod {
+	pods, err := kubeclient.CoreV1().Pods("").List(ctx.Background(), metav1.ListOptions{
+		FieldSelector:   "spec.nodeName=" + workerName,
+		ResourceVersion: "0",
+	})
+	if err != nil {
+		klog.Errorf("Failed to list pods on node %s: %v", workerName, err)
+		return nil
+	}
+	result := make([]apiv1.Pod, 0)
+	for _, pod := range pods.Items {
+		if len(pod.OwnerReferences) == 0 || pod.OwnerReferences[0].Kind != "DaemonSet" {
+			result = append(result, pod)
+		}
+	}
+	return result
+}
+
+func checkWorkerNodeCanBeScaleDown(kubeclient kube_client.Interface, workerNodeName string, allowOwnerlessPods bool) bool {
 	var canBeRemove bool = true
-	pods, err := kubeclient.CoreV1().Pods("").List(ctx.Background(), metav1.ListOptions{})
+	// Only the pods on this node matter, so let the API server filter instead
+	// of listing every pod in the cluster per candidate.
+	pods, err := kubeclient.CoreV1().Pods("").List(ctx.Background(), metav1.ListOptions{
+		FieldSelector:   "spec.nodeName=" + workerNodeName,
+		ResourceVersion: "0",
+	})
 	if err != nil {
-		log.Fatal(err)
+		klog.Errorf("Failed to list pods on node %s: %v", workerNodeName, err)
+		return false
 	}
 	for _, pod := range pods.Items {
-		if pod.Spec.NodeName == workerNodeName && pod.OwnerReferences[0].Kind != "DaemonSet" {
-			replicaset, _ := kubeclient.AppsV1().ReplicaSets(pod.Namespace).Get(ctx.Background(),
-				pod.OwnerReferences[0].Name, metav1.GetOptions{})
-			//if err != nil {
-			//	log.Fatal(err)
-			//}
-			if replicaset.Status.Replicas == 1 {
+		if len(pod.OwnerReferences) == 0 {
+			// Nothing recreates a naked pod elsewhere after eviction.
+			if !allowOwnerlessPods {
 				klog.V(1).Infof("If you want to scale down, you should evict pod %s in namespace %s "+
-					"because your replicaset %s has only one replica", pod.Name, pod.Namespace,
-					replicaset.Name)
+					"because it has no controller and will not be recreated", pod.Name, pod.Namespace)
+				metrics.RegisterScaleDownRejection("ownerless_pod")
 				canBeRemove = false
 			}
-			for _, volume := range pod.Spec.Volumes {
-				if volume.EmptyDir != nil {
-					klog.V(1).Infof("If you want to scale down, you should evict pod %s"+
-						" in namespace %s because pod has local storage", pod.Name, pod.Namespace)
+		} else {
+			switch owner := pod.OwnerReferences[0]; owner.Kind {
+			case "DaemonSet":
+				// DaemonSet pods go away with the node.
+				continue
+			case "ReplicaSet":
+				replicaset, err := kubeclient.AppsV1().ReplicaSets(pod.Namespace).Get(ctx.Background(),
+					owner.Name, metav1.GetOptions{})
+				if err != nil {
+					klog.Warningf("Failed to get owner of pod %s/%s: %v", pod.Namespace, pod.Name, err)
+					continue
+				}
+				if replicaset.Status.Replicas == 1 {
+					klog.V(1).Infof("If you want to scale down, you should evict pod %s in namespace %s "+
+						"because your replicaset %s has only one replica", pod.Name, pod.Namespace,
+						replicaset.Name)
+					metrics.RegisterScaleDownRejection("single_replica_replicaset")
 					canBeRemove = false
 				}
+			case "StatefulSet":
+				statefulset, err := kubeclient.AppsV1().StatefulSets(pod.Namespace).Get(ctx.Background(),
+					owner.Name, metav1.GetOptions{})
+				if err != nil {
+					klog.Warningf("Failed to get owner of pod %s/%s: %v", pod.Namespace, pod.Name, err)
+					continue
+				}
+				if statefulset.Status.Replicas == 1 {
+					klog.V(1).Infof("If you want to scale down, you should evict pod %s in namespace %s "+
+						"because your statefulset %s has only one replica", pod.Name, pod.Namespace,
+						statefulset.Name)
+					metrics.RegisterScaleDownRejection("single_replica_statefulset")
+					canBeRemove = false
+				}
+			default:
+				// Jobs and custom controllers reschedule their pods themselves;
+				// only the generic checks below apply.
+			}
+		}
+		for _, volume := range pod.Spec.Volumes {
+			if volume.EmptyDir != nil {
+				klog.V(1).Infof("If you want to scale down, you should evict pod %s"+
+					" in namespace %s because pod has local storage", pod.Name, pod.Namespace)
+				metrics.RegisterScaleDownRejection("local_storage")
+				canBeRemove = false
 			}
 		}
 	}
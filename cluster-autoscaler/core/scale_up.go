@@ -20,21 +20,29 @@ import (
 	ctx "context"
 	"fmt"
 	"math"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
 	apiv1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/auditlog"
 	"k8s.io/autoscaler/cluster-autoscaler/clusterstate"
 	"k8s.io/autoscaler/cluster-autoscaler/context"
 	"k8s.io/autoscaler/cluster-autoscaler/core/utils"
+	"k8s.io/autoscaler/cluster-autoscaler/expander"
+	"k8s.io/autoscaler/cluster-autoscaler/expander/perpool"
+	"k8s.io/autoscaler/cluster-autoscaler/expander/random"
+	"k8s.io/autoscaler/cluster-autoscaler/metrics"
 	ca_processors "k8s.io/autoscaler/cluster-autoscaler/processors"
 	"k8s.io/autoscaler/cluster-autoscaler/processors/status"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/errors"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/klogx"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/taints"
 	kube_client "k8s.io/client-go/kubernetes"
+	corev1helpers "k8s.io/component-helpers/scheduling/corev1"
 	klog "k8s.io/klog/v2"
 	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
 )
@@ -312,7 +320,7 @@ func maxResourceLimitReached(resources []string) *skippedReasons {
 //}
 
 // Calculate new node need to be scaled up
-func CalculateNewNodeScaledUp(kubeclient kube_client.Interface, unschedulablePods []*apiv1.Pod, nodes []*apiv1.Node) int {
+func CalculateNewNodeScaledUp(kubeclient kube_client.Interface, unschedulablePods []*apiv1.Pod, nodes []*apiv1.Node, daemonSets []*appsv1.DaemonSet, poolName string) int {
 	podEquivalenceGroups := buildPodEquivalenceGroups(unschedulablePods)
 	skippedNodeGroups := map[string]status.Reasons{}
 	podsRemainUnschedulable := getRemainingPods(podEquivalenceGroups, skippedNodeGroups)
@@ -320,7 +328,7 @@ func CalculateNewNodeScaledUp(kubeclient kube_client.Interface, unschedulablePod
 	var totalMemoryRequest int64 = 0
 	var numberNodeScaledUpFloat float64 = 0.0
 	for _, pod := range podsRemainUnschedulable {
-		events, _ := kubeclient.CoreV1().Events(pod.Pod.Namespace).List(ctx.TODO(), metav1.ListOptions{FieldSelector: "involvedObject.name=" + pod.Pod.Name, TypeMeta: metav1.TypeMeta{Kind: "Pod"}})
+		events, _ := kubeclient.CoreV1().Events(pod.Pod.Namespace).List(ctx.TODO(), metav1.ListOptions{FieldSelector: "involvedObject.name=" + pod.Pod.Name, TypeMeta: metav1.TypeMeta{Kind: "Pod"}, ResourceVersion: "0"})
 		//fmt.Println("first event of ", pod.Pod.Name, " is: ", events.Items[0].Message)
 
 		if strings.Contains(events.Items[0].Message, "Insufficient") == false {
@@ -338,17 +346,38 @@ func CalculateNewNodeScaledUp(kubeclient kube_client.Interface, unschedulablePod
 	var cpus int64
 	var memory int64
 	for _, node := range nodes {
-		if strings.Contains(node.Name, "worker") {
-			cpus, memory = utils.GetNodeCoresAndMemory(node)
-			break
+		if !utils.IsWorkerNode(node) {
+			continue
+		}
+		if poolName != "" && node.Labels[utils.WorkerPoolLabel] != poolName {
+			continue
 		}
-		continue
+		cpus, memory = utils.GetNodeCoresAndMemory(node)
+		break
 	}
 	//fmt.Println("worker CPU: ", cpus)
 	//fmt.Println("worker Memory: ", memory)
-	numberNodeScaledUpFloat = float64(totalCPUrequest) / (float64(cpus) * 1000)
-	if numberNodeScaledUpFloat < (float64(totalMemoryRequest) / (float64(memory) * 1000)) {
-		numberNodeScaledUpFloat = (float64(totalMemoryRequest) / (float64(memory) * 1000))
+	if cpus == 0 || memory == 0 {
+		klog.Warningf("No worker node found in pool %q to use as a template, skipping the estimation", poolName)
+		return 0
+	}
+
+	// A new node does not offer its full flavor capacity to pending pods, the
+	// DaemonSet pods scheduled on it consume their share first.
+	daemonSetCPURequest, daemonSetMemoryRequest := calculateDaemonSetOverhead(daemonSets)
+	// The kubelet reserves part of the flavor capacity for itself and system
+	// daemons, so the real allocatable of a new node is smaller than its capacity.
+	reservedCPU, reservedMemory := utils.GetKubeletReservedResources(kubeclient, poolName)
+	cpuCapacity := float64(cpus)*1000 - float64(daemonSetCPURequest) - float64(reservedCPU)
+	memoryCapacity := float64(memory)*1000 - float64(daemonSetMemoryRequest) - float64(reservedMemory)
+	if cpuCapacity <= 0 || memoryCapacity <= 0 {
+		klog.Warningf("DaemonSet and kubelet reserved overhead exceeds worker flavor capacity, ignoring it in the estimation")
+		cpuCapacity = float64(cpus) * 1000
+		memoryCapacity = float64(memory) * 1000
+	}
+	numberNodeScaledUpFloat = float64(totalCPUrequest) / cpuCapacity
+	if numberNodeScaledUpFloat < (float64(totalMemoryRequest) / memoryCapacity) {
+		numberNodeScaledUpFloat = (float64(totalMemoryRequest) / memoryCapacity)
 	}
 	//fmt.Println("numberNodeScaledUpFloat is: ", numberNodeScaledUpFloat)
 	numberNodeScaledUpInt := int(math.Ceil(numberNodeScaledUpFloat))
@@ -371,6 +400,13 @@ func ScaleUp(context *context.AutoscalingContext, processors *ca_processors.Auto
 
 	//now := time.Now()
 
+	// Order pending pods by PriorityClass, so when the scale up cannot cover all
+	// of them within the configured limits, the highest priority pods are
+	// satisfied first and the rest is reported through PodsRemainUnschedulable.
+	sort.SliceStable(unschedulablePods, func(i, j int) bool {
+		return podPriority(unschedulablePods[i]) > podPriority(unschedulablePods[j])
+	})
+
 	loggingQuota := klogx.PodsLoggingQuota()
 
 	for _, pod := range unschedulablePods {
@@ -491,13 +527,13 @@ func ScaleUp(context *context.AutoscalingContext, processors *ca_processors.Auto
 
 	var numberWorkerNode int = 0
 	for _, node := range nodes {
-		if strings.Contains(node.Name, "worker") {
+		if utils.IsWorkerNode(node) {
 			numberWorkerNode += 1
 		}
 	}
 	//fmt.Println()
 	//fmt.Println("Number of worker node: ", numberWorkerNode)
-	numberNodeScaleUp := CalculateNewNodeScaledUp(kubeclient, unschedulablePods, nodes)
+	numberNodeScaleUp := CalculateNewNodeScaledUp(kubeclient, unschedulablePods, nodes, daemonSets, "")
 	if numberNodeScaleUp == 0 {
 		return &status.ScaleUpStatus{
 			Result:                  status.ScaleUpNotNeeded,
@@ -505,7 +541,17 @@ func ScaleUp(context *context.AutoscalingContext, processors *ca_processors.Auto
 			//ConsideredNodeGroups:    nodeGroups,
 		}, nil
 	}
-	if (numberWorkerNode + numberNodeScaleUp) > utils.GetMaxSizeNodeGroup(kubeclient) {
+	if maxIncrement := context.MaxScaleUpIncrement; maxIncrement > 0 && numberNodeScaleUp > maxIncrement {
+		// Larger needs are satisfied over successive loops, so a misconfigured
+		// workload cannot request an arbitrary number of nodes at once.
+		klog.V(1).Infof("Capping scale up from %v to %v nodes, the remaining nodes will be added in next iterations", numberNodeScaleUp, maxIncrement)
+		numberNodeScaleUp = maxIncrement
+	}
+	maxSizeNodeGroup, maxSizeErr := utils.GetMaxSizeNodeGroup(kubeclient)
+	if maxSizeErr != nil {
+		return scaleUpError(&status.ScaleUpStatus{}, errors.ToAutoscalerError(errors.TransientError, maxSizeErr))
+	}
+	if (numberWorkerNode + numberNodeScaleUp) > maxSizeNodeGroup {
 		klog.V(4).Infof("Skipping node group - max size reached")
 		klog.V(4).Infof("Number of nodes need to be scaled up is: %v", numberNodeScaleUp)
 		//fmt.Println("Number of nodes need to be scaled up is: ", numberNodeScaleUp)
@@ -513,7 +559,7 @@ func ScaleUp(context *context.AutoscalingContext, processors *ca_processors.Auto
 		klog.V(4).Infof("Max node group size reached")
 		klog.V(4).Infof("You need to increase max group size")
 		//fmt.Println("You need to increase max group size")
-		numberNodeScaleUp = utils.GetMaxSizeNodeGroup(kubeclient) - numberWorkerNode
+		numberNodeScaleUp = maxSizeNodeGroup - numberWorkerNode
 		//fmt.Println("scaling up ", numberNodeScaleUp, " node")
 		//fmt.Println("waiting for job running in AWX successfully")
 		if numberNodeScaleUp == 0 {
@@ -524,12 +570,101 @@ func ScaleUp(context *context.AutoscalingContext, processors *ca_processors.Auto
 			}, nil
 		}
 	}
+	if context.WarmPoolSize > 0 {
+		// Warm standby nodes are already provisioned, so uncordoning them gives
+		// capacity without waiting for the portal.
+		uncordoned := activateWarmNodes(kubeclient, numberNodeScaleUp)
+		numberNodeScaleUp -= uncordoned
+		if numberNodeScaleUp == 0 {
+			klog.V(1).Infof("Scale up fully satisfied by %v warm pool nodes", uncordoned)
+			return &status.ScaleUpStatus{
+				Result:                  status.ScaleUpSuccessful,
+				PodsRemainUnschedulable: getRemainingPods(podEquivalenceGroups, skippedNodeGroups),
+			}, nil
+		}
+	}
 	klog.V(4).Infof("Scaling up %v node", numberNodeScaleUp)
+	auditlog.Log("scale-up-decision", map[string]string{
+		"nodes":       strconv.Itoa(numberNodeScaleUp),
+		"pendingPods": strconv.Itoa(len(unschedulablePods)),
+	})
 	//fmt.Println("scaling up ", numberNodeScaleUp, " node")
 	//fmt.Println("waiting for job running in AWX successfully")
+	if utils.InReadOnlyMode() {
+		klog.V(1).Infof("Read-only mode: would scale up %v node", numberNodeScaleUp)
+		return &status.ScaleUpStatus{
+			Result:                  status.ScaleUpNotTried,
+			PodsRemainUnschedulable: getRemainingPods(podEquivalenceGroups, skippedNodeGroups),
+		}, nil
+	}
 	domainAPI := utils.GetDomainApiConformEnv(env)
 	if utils.CheckStatusCluster(domainAPI, vpcID, accessToken, clusterIDPortal) {
-		utils.PerformScaleUp(domainAPI, vpcID, accessToken, numberNodeScaleUp, idCluster, clusterIDPortal)
+		workerPrices := utils.GetWorkerHourlyPrices(kubeclient)
+		podsByPool := groupPodsByWorkerPool(unschedulablePods)
+		// Route the scale up per pool whenever any pending pod requires a specific
+		// pool, so a pod requiring e.g. pool=gpu cannot trigger a useless scale up
+		// of the generic worker pool.
+		usePoolRouting := len(podsByPool) > 1
+		if !usePoolRouting {
+			for poolName := range podsByPool {
+				usePoolRouting = poolName != ""
+			}
+		}
+		if usePoolRouting {
+			// Pending pods split across worker pools, e.g. GPU and general. Build one
+			// expansion option per pool and let the configured expander strategy pick
+			// the order in which the shared node budget is spent, so under a capped
+			// budget the preferred pools are served first.
+			options := make([]expander.Option, 0, len(podsByPool))
+			for poolName, poolPods := range podsByPool {
+				poolPods = filterPodsToleratingPoolTaints(poolPods, nodes, poolName)
+				if len(poolPods) == 0 {
+					continue
+				}
+				poolNodeCount := CalculateNewNodeScaledUp(kubeclient, poolPods, nodes, daemonSets, poolName)
+				if poolNodeCount == 0 {
+					continue
+				}
+				options = append(options, expander.Option{
+					NodeCount: poolNodeCount,
+					Debug:     fmt.Sprintf("pool=%s nodes=%d pods=%d", poolName, poolNodeCount, len(poolPods)),
+					Pods:      poolPods,
+				})
+			}
+			remainingNodes := numberNodeScaleUp
+			for len(options) > 0 && remainingNodes > 0 {
+				bestOption := bestPoolOption(context, options)
+				if bestOption == nil {
+					klog.Warningf("Expander returned no option for %v remaining pools, skipping them", len(options))
+					break
+				}
+				poolName := perpool.PoolOf(*bestOption)
+				poolNodeCount := bestOption.NodeCount
+				if poolNodeCount > remainingNodes {
+					poolNodeCount = remainingNodes
+				}
+				remainingNodes -= poolNodeCount
+				klog.V(1).Infof("Scaling up %v nodes in worker pool %q (%s)", poolNodeCount, poolName, bestOption.Debug)
+				utils.PerformScaleUpForPool(domainAPI, vpcID, accessToken, poolNodeCount, idCluster, clusterIDPortal, poolName)
+				metrics.RegisterNodesCreated(poolLabelOrDefault(poolName), poolNodeCount)
+				metrics.ObserveScaleCostDelta("scale_up", utils.WorkerHourlyPrice(workerPrices, poolName)*float64(poolNodeCount))
+				options = removePoolOption(options, poolName)
+			}
+		} else if spotPool := context.SpotWorkerPool; spotPool != "" {
+			// Prefer the cheaper spot pool for pods with no pool requirement. If the
+			// spot capacity cannot be delivered within the fallback timeout, e.g.
+			// because instances were reclaimed, the missing nodes are requested from
+			// the on-demand pool below.
+			klog.V(1).Infof("Scaling up %v nodes in spot pool %q", numberNodeScaleUp, spotPool)
+			utils.PerformScaleUpForPool(domainAPI, vpcID, accessToken, numberNodeScaleUp, idCluster, clusterIDPortal, spotPool)
+			metrics.RegisterNodesCreated(spotPool, numberNodeScaleUp)
+			metrics.ObserveScaleCostDelta("scale_up", utils.WorkerHourlyPrice(workerPrices, spotPool)*float64(numberNodeScaleUp))
+		} else {
+			utils.PerformScaleUp(domainAPI, vpcID, accessToken, numberNodeScaleUp, idCluster, clusterIDPortal)
+			metrics.RegisterNodesCreated(poolLabelOrDefault(""), numberNodeScaleUp)
+			metrics.ObserveScaleCostDelta("scale_up", utils.WorkerHourlyPrice(workerPrices, "")*float64(numberNodeScaleUp))
+		}
+		portalWaitStart := time.Now()
 		for {
 			time.Sleep(30 * time.Second)
 			isSucceededStatus := utils.CheckStatusCluster(domainAPI, vpcID, accessToken, clusterIDPortal)
@@ -552,6 +687,7 @@ func ScaleUp(context *context.AutoscalingContext, processors *ca_processors.Auto
 				break
 			}
 		}
+		metrics.UpdateDurationFromStart(metrics.ScaleUpPortalWait, portalWaitStart)
 	} else {
 		klog.V(1).Infof("Another action is being performed")
 		klog.V(1).Infof("Waiting for scaling ...")
@@ -744,6 +880,59 @@ func ScaleUp(context *context.AutoscalingContext, processors *ca_processors.Auto
 	//		PodsAwaitEvaluation: getPodsAwaitingEvaluation(podEquivalenceGroups, ""),
 	//	}, nil
 	//}
+	if spotPool := context.SpotWorkerPool; spotPool != "" {
+		spotShortfall := detectScaleUpShortfall(kubeclient, numberWorkerNode+numberNodeScaleUp, context.SpotFallbackTimeout)
+		if spotShortfall > 0 && utils.CheckStatusCluster(domainAPI, vpcID, accessToken, clusterIDPortal) {
+			klog.Warningf("Spot pool %q delivered %v of %v requested nodes within %v, falling back to on-demand pool %q",
+				spotPool, numberNodeScaleUp-spotShortfall, numberNodeScaleUp, context.SpotFallbackTimeout, context.SpotFallbackPool)
+			utils.PerformScaleUpForPool(domainAPI, vpcID, accessToken, spotShortfall, idCluster, clusterIDPortal, context.SpotFallbackPool)
+			metrics.RegisterNodesCreated(poolLabelOrDefault(context.SpotFallbackPool), spotShortfall)
+			for {
+				time.Sleep(30 * time.Second)
+				if utils.CheckStatusCluster(domainAPI, vpcID, accessToken, clusterIDPortal) {
+					break
+				}
+			}
+		}
+	}
+
+	// The portal can deliver fewer nodes than requested, e.g. when some instances
+	// fail to provision or never join the cluster. Detect the shortfall instead of
+	// leaving half-capacity and pending pods waiting on each other indefinitely.
+	shortfall := detectScaleUpShortfall(kubeclient, numberWorkerNode+numberNodeScaleUp, context.MaxNodeProvisionTime)
+	if shortfall > 0 {
+		klog.Warningf("Scale up delivered %v of %v requested nodes, dropping %v instances that never joined", numberNodeScaleUp-shortfall, numberNodeScaleUp, shortfall)
+		// The portal removes the highest numbered workers first, which are the ones
+		// just created, so a scale down by the shortfall cleans up the failed instances.
+		if utils.CheckStatusCluster(domainAPI, vpcID, accessToken, clusterIDPortal) {
+			utils.PerformScaleDown(domainAPI, vpcID, accessToken, shortfall, idCluster, clusterIDPortal)
+			for {
+				time.Sleep(30 * time.Second)
+				if utils.CheckStatusCluster(domainAPI, vpcID, accessToken, clusterIDPortal) {
+					break
+				}
+			}
+		}
+		// The pods that did not fit on the joined nodes are still unschedulable, so
+		// the next loop re-runs the estimation for them.
+		return &status.ScaleUpStatus{
+			Result:                  status.ScaleUpNoOptionsAvailable,
+			PodsRemainUnschedulable: getRemainingPods(podEquivalenceGroups, skippedNodeGroups),
+		}, nil
+	}
+
+	// Report the end to end scale up latency as experienced by the workload,
+	// from the oldest pending pod to all requested nodes joined.
+	var oldestPendingPod time.Time
+	for _, pod := range unschedulablePods {
+		if oldestPendingPod.IsZero() || pod.CreationTimestamp.Time.Before(oldestPendingPod) {
+			oldestPendingPod = pod.CreationTimestamp.Time
+		}
+	}
+	if !oldestPendingPod.IsZero() {
+		metrics.ObserveScaleOperationDuration(clusterIDPortal, "scale_up", time.Since(oldestPendingPod))
+	}
+
 	//fmt.Println("End of scale up process")
 	klog.V(1).Infof("End of scale up process")
 	return &status.ScaleUpStatus{
@@ -753,6 +942,187 @@ func ScaleUp(context *context.AutoscalingContext, processors *ca_processors.Auto
 	}, nil
 }
 
+// calculateDaemonSetOverhead sums the cpu (milli) and memory (milli) requests of
+// the pods every DaemonSet will run on a new node.
+func calculateDaemonSetOverhead(daemonSets []*appsv1.DaemonSet) (cpuRequest int64, memoryRequest int64) {
+	for _, daemonSet := range daemonSets {
+		for _, container := range daemonSet.Spec.Template.Spec.Containers {
+			cpuRequest += container.Resources.Requests.Cpu().MilliValue()
+			memoryRequest += container.Resources.Requests.Memory().MilliValue()
+		}
+	}
+	return cpuRequest, memoryRequest
+}
+
+// activateWarmNodes uncordons up to maxCount warm standby nodes and removes them
+// from the warm pool. It returns how many nodes were activated.
+func activateWarmNodes(kubeclient kube_client.Interface, maxCount int) int {
+	nodeList, err := kubeclient.CoreV1().Nodes().List(ctx.TODO(), metav1.ListOptions{
+		LabelSelector:   utils.WarmPoolLabel + "=true",
+		ResourceVersion: "0",
+	})
+	if err != nil {
+		klog.Errorf("Failed to list warm pool nodes: %v", err)
+		return 0
+	}
+	activated := 0
+	for _, node := range nodeList.Items {
+		if activated >= maxCount {
+			break
+		}
+		freshNode := node.DeepCopy()
+		freshNode.Spec.Unschedulable = false
+		delete(freshNode.Labels, utils.WarmPoolLabel)
+		if _, err := kubeclient.CoreV1().Nodes().Update(ctx.TODO(), freshNode, metav1.UpdateOptions{}); err != nil {
+			klog.Errorf("Failed to activate warm pool node %s: %v", node.Name, err)
+			continue
+		}
+		klog.V(1).Infof("Activated warm pool node %s", node.Name)
+		activated++
+	}
+	return activated
+}
+
+// filterPodsToleratingPoolTaints drops pods that do not tolerate the taints of
+// the given pool, so a tainted pool (e.g. dedicated=batch:NoSchedule) is not
+// scaled up for pods that could never schedule on its nodes.
+func filterPodsToleratingPoolTaints(pods []*apiv1.Pod, nodes []*apiv1.Node, poolName string) []*apiv1.Pod {
+	var poolTaints []apiv1.Taint
+	for _, node := range nodes {
+		if !utils.IsWorkerNode(node) {
+			continue
+		}
+		if poolName != "" && node.Labels[utils.WorkerPoolLabel] != poolName {
+			continue
+		}
+		poolTaints = node.Spec.Taints
+		break
+	}
+	if len(poolTaints) == 0 {
+		return pods
+	}
+	result := make([]*apiv1.Pod, 0, len(pods))
+	for _, pod := range pods {
+		_, untolerated := corev1helpers.FindMatchingUntoleratedTaint(poolTaints, pod.Spec.Tolerations, func(taint *apiv1.Taint) bool {
+			return taint.Effect == apiv1.TaintEffectNoSchedule || taint.Effect == apiv1.TaintEffectNoExecute
+		})
+		if untolerated {
+			klog.V(2).Infof("Pod %s/%s does not tolerate the taints of pool %q, not scaling the pool for it", pod.Namespace, pod.Name, poolName)
+			continue
+		}
+		result = append(result, pod)
+	}
+	return result
+}
+
+// podPriority returns the priority of a pod, treating pods with no priority
+// set as priority 0.
+func podPriority(pod *apiv1.Pod) int32 {
+	if pod.Spec.Priority != nil {
+		return *pod.Spec.Priority
+	}
+	return 0
+}
+
+// groupPodsByWorkerPool splits pods by the worker pool they request through a
+// node selector or required node affinity on WorkerPoolLabel. Pods with no pool
+// requirement end up in the default pool, represented by an empty name.
+func groupPodsByWorkerPool(pods []*apiv1.Pod) map[string][]*apiv1.Pod {
+	podsByPool := make(map[string][]*apiv1.Pod)
+	for _, pod := range pods {
+		poolName := podWorkerPool(pod)
+		podsByPool[poolName] = append(podsByPool[poolName], pod)
+	}
+	return podsByPool
+}
+
+// bestPoolOption lets the configured expander strategy choose between the
+// per-pool expansion options, falling back to a random pick when no strategy
+// is configured, which matches the previous map iteration order behavior.
+func bestPoolOption(context *context.AutoscalingContext, options []expander.Option) *expander.Option {
+	if context.ExpanderStrategy == nil {
+		return random.NewStrategy().BestOption(options)
+	}
+	return context.ExpanderStrategy.BestOption(options)
+}
+
+// removePoolOption drops the option belonging to the given pool once its
+// scale up has been submitted.
+func removePoolOption(options []expander.Option, poolName string) []expander.Option {
+	remaining := options[:0]
+	for _, option := range options {
+		if perpool.PoolOf(option) != poolName {
+			remaining = append(remaining, option)
+		}
+	}
+	return remaining
+}
+
+// poolLabelOrDefault returns the worker pool name to use as a metric label,
+// mapping the empty (default) pool to "default".
+func poolLabelOrDefault(poolName string) string {
+	if poolName == "" {
+		return "default"
+	}
+	return poolName
+}
+
+// podWorkerPool returns the worker pool a pod requires through its node
+// selector or required node affinity, or an empty string when the pod can run
+// in any pool.
+func podWorkerPool(pod *apiv1.Pod) string {
+	if poolName, found := pod.Spec.NodeSelector[utils.WorkerPoolLabel]; found {
+		return poolName
+	}
+	if pod.Spec.Affinity == nil || pod.Spec.Affinity.NodeAffinity == nil ||
+		pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return ""
+	}
+	for _, term := range pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms {
+		for _, expression := range term.MatchExpressions {
+			if expression.Key == utils.WorkerPoolLabel && expression.Operator == apiv1.NodeSelectorOpIn && len(expression.Values) > 0 {
+				return expression.Values[0]
+			}
+		}
+	}
+	return ""
+}
+
+// detectScaleUpShortfall waits for the cluster to reach the expected number of
+// Ready worker nodes and returns how many of them are still missing when the
+// timeout expires.
+func detectScaleUpShortfall(kubeclient kube_client.Interface, expectedWorkerCount int, timeout time.Duration) int {
+	deadline := time.Now().Add(timeout)
+	for {
+		readyWorkerCount := 0
+		nodeList, err := kubeclient.CoreV1().Nodes().List(ctx.TODO(), metav1.ListOptions{ResourceVersion: "0"})
+		if err != nil {
+			klog.Errorf("Failed to list nodes while waiting for scaled up nodes to join: %v", err)
+		} else {
+			for i := range nodeList.Items {
+				node := &nodeList.Items[i]
+				if !utils.IsWorkerNode(node) {
+					continue
+				}
+				for _, condition := range node.Status.Conditions {
+					if condition.Type == apiv1.NodeReady && condition.Status == apiv1.ConditionTrue {
+						readyWorkerCount++
+						break
+					}
+				}
+			}
+			if readyWorkerCount >= expectedWorkerCount {
+				return 0
+			}
+		}
+		if time.Now().After(deadline) {
+			return expectedWorkerCount - readyWorkerCount
+		}
+		klog.V(1).Infof("Waiting for scaled up nodes to join, %v of %v workers Ready", readyWorkerCount, expectedWorkerCount)
+		time.Sleep(30 * time.Second)
+	}
+}
+
 func getRemainingPods(egs []*podEquivalenceGroup, skipped map[string]status.Reasons) []status.NoScaleUpInfo {
 	remaining := []status.NoScaleUpInfo{}
 	for _, eg := range egs {
@@ -0,0 +1,85 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"sort"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/debuggingsnapshot"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator"
+	kube_client "k8s.io/client-go/kubernetes"
+	klog "k8s.io/klog/v2"
+)
+
+// buildSchedulabilityReports runs the scheduler predicates of every pending
+// pod against every node in the cluster snapshot and records why each node
+// was rejected. This is only worth the cost when a debugging snapshot capture
+// is in progress, so callers must gate on IsDataCollectionAllowed.
+func buildSchedulabilityReports(predicateChecker simulator.PredicateChecker, clusterSnapshot simulator.ClusterSnapshot, pods []*apiv1.Pod) []*debuggingsnapshot.PodSchedulabilityReport {
+	nodeInfos, err := clusterSnapshot.NodeInfos().List()
+	if err != nil {
+		klog.Errorf("Unable to fetch ClusterNode List for the schedulability report, %v", err)
+		return nil
+	}
+	var reports []*debuggingsnapshot.PodSchedulabilityReport
+	for _, pod := range pods {
+		report := &debuggingsnapshot.PodSchedulabilityReport{
+			Pod:  pod.Namespace + "/" + pod.Name,
+			Pool: podWorkerPool(pod),
+		}
+		for _, nodeInfo := range nodeInfos {
+			predicateError := predicateChecker.CheckPredicates(clusterSnapshot, pod, nodeInfo.Node().Name)
+			if predicateError == nil {
+				continue
+			}
+			reasons := predicateError.Reasons()
+			if len(reasons) == 0 {
+				reasons = []string{predicateError.Message()}
+			}
+			report.NodeFailures = append(report.NodeFailures, debuggingsnapshot.NodePredicateFailure{
+				Node:    nodeInfo.Node().Name,
+				Reasons: reasons,
+			})
+		}
+		reports = append(reports, report)
+	}
+	return reports
+}
+
+// buildPoolEstimations estimates, per worker pool, how many nodes the pending
+// pods routed to that pool would need, mirroring the estimation the scale up
+// path performs.
+func buildPoolEstimations(kubeclient kube_client.Interface, pods []*apiv1.Pod, nodes []*apiv1.Node, daemonSets []*appsv1.DaemonSet) []debuggingsnapshot.PoolEstimation {
+	var estimations []debuggingsnapshot.PoolEstimation
+	for poolName, poolPods := range groupPodsByWorkerPool(pods) {
+		poolPods = filterPodsToleratingPoolTaints(poolPods, nodes, poolName)
+		if len(poolPods) == 0 {
+			continue
+		}
+		estimations = append(estimations, debuggingsnapshot.PoolEstimation{
+			Pool:               poolLabelOrDefault(poolName),
+			PendingPods:        len(poolPods),
+			EstimatedNodeCount: CalculateNewNodeScaledUp(kubeclient, poolPods, nodes, daemonSets, poolName),
+		})
+	}
+	sort.Slice(estimations, func(i, j int) bool {
+		return estimations[i].Pool < estimations[j].Pool
+	})
+	return estimations
+}
@@ -19,15 +19,17 @@ package core
 import (
 	ctx "context"
 	"fmt"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"log"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	kube_client "k8s.io/client-go/kubernetes"
 
 	apiv1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/autoscaler/cluster-autoscaler/clusterstate"
 	"k8s.io/autoscaler/cluster-autoscaler/clusterstate/utils"
@@ -37,12 +39,18 @@ import (
 	"k8s.io/autoscaler/cluster-autoscaler/debuggingsnapshot"
 	"k8s.io/autoscaler/cluster-autoscaler/estimator"
 	"k8s.io/autoscaler/cluster-autoscaler/expander"
+	"k8s.io/autoscaler/cluster-autoscaler/expander/audit"
 	"k8s.io/autoscaler/cluster-autoscaler/metrics"
 	ca_processors "k8s.io/autoscaler/cluster-autoscaler/processors"
 	"k8s.io/autoscaler/cluster-autoscaler/processors/status"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator"
+	"k8s.io/autoscaler/cluster-autoscaler/statusreport"
+	ca_utils "k8s.io/autoscaler/cluster-autoscaler/utils"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/deletetaint"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/drain"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/errors"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/events"
+	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/taints"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/tpu"
 
@@ -70,11 +78,26 @@ type StaticAutoscaler struct {
 	lastScaleUpTime         time.Time
 	lastScaleDownDeleteTime time.Time
 	lastScaleDownFailTime   time.Time
-	scaleDown               *ScaleDown
-	processors              *ca_processors.AutoscalingProcessors
-	processorCallbacks      *staticAutoscalerProcessorCallbacks
-	initialized             bool
-	ignoredTaints           taints.TaintKeySet
+	// limitDriftSince is when the worker count was first observed outside the
+	// configured node group limits, or zero while it is within them.
+	limitDriftSince    time.Time
+	lastSoftTaintTime  time.Time
+	scaleDown          *ScaleDown
+	consolidator       *Consolidator
+	autoProvisioner    *AutoProvisioner
+	flavorAnalyzer     *FlavorAnalyzer
+	predictor          *Predictor
+	nodeRepairer       *NodeRepairer
+	refresher          *Refresher
+	costReporter       *CostReporter
+	processors         *ca_processors.AutoscalingProcessors
+	processorCallbacks *staticAutoscalerProcessorCallbacks
+	initialized        bool
+	ignoredTaints      taints.TaintKeySet
+	// runOnceLock serializes RunOnce executions. A loop that overruns the scan
+	// interval, e.g. while waiting on the portal, must not run concurrently
+	// with the next tick because RunOnce mutates shared state.
+	runOnceLock sync.Mutex
 }
 
 type staticAutoscalerProcessorCallbacks struct {
@@ -148,8 +171,48 @@ func NewStaticAutoscaler(
 		ignoredTaints[taintKey] = true
 	}
 
+	if opts.DeletionCandidateTaintKey != "" {
+		klog.V(4).Infof("Using %s as the deletion candidate taint key", opts.DeletionCandidateTaintKey)
+		deletetaint.SetDeletionCandidateTaint(opts.DeletionCandidateTaintKey)
+	}
+
+	if opts.PublishScaleDownCandidateLabel {
+		klog.V(4).Infof("Publishing %s on deletion candidate nodes", deletetaint.ScaleDownCandidateLabel)
+		deletetaint.SetPublishScaleDownCandidateLabel(true)
+	}
+
+	if opts.NodeResourcesFromAllocatable {
+		klog.V(4).Infof("Accounting node resources from allocatable instead of capacity")
+		core_utils.SetNodeResourcesFromAllocatable(true)
+	}
+
+	if len(opts.EquivalenceIgnoredTolerationKeys) > 0 || len(opts.EquivalenceIgnoredContainerNames) > 0 || len(opts.EquivalenceIgnoredLabelKeys) > 0 {
+		klog.V(4).Infof("Ignoring tolerations %v, containers %v and labels %v in pod equivalence checks",
+			opts.EquivalenceIgnoredTolerationKeys, opts.EquivalenceIgnoredContainerNames, opts.EquivalenceIgnoredLabelKeys)
+		ca_utils.SetEquivalenceIgnoredFields(opts.EquivalenceIgnoredTolerationKeys, opts.EquivalenceIgnoredContainerNames, opts.EquivalenceIgnoredLabelKeys)
+	}
+
+	if opts.ToBeDeletedTaintKey != "" || opts.ToBeDeletedTaintEffect != "" {
+		effect := apiv1.TaintEffect("")
+		switch opts.ToBeDeletedTaintEffect {
+		case "":
+		case string(apiv1.TaintEffectNoSchedule):
+			effect = apiv1.TaintEffectNoSchedule
+		case string(apiv1.TaintEffectNoExecute):
+			effect = apiv1.TaintEffectNoExecute
+		default:
+			klog.Warningf("Unknown to-be-deleted taint effect %q, keeping NoSchedule", opts.ToBeDeletedTaintEffect)
+		}
+		if opts.ToBeDeletedTaintKey != "" {
+			klog.V(4).Infof("Using %s as the to-be-deleted taint key", opts.ToBeDeletedTaintKey)
+		}
+		deletetaint.SetToBeDeletedTaint(opts.ToBeDeletedTaintKey, effect)
+	}
+
 	clusterStateRegistry := clusterstate.NewClusterStateRegistry(clusterStateConfig, autoscalingContext.LogRecorder)
 
+	core_utils.SetPortalEventRecorder(autoscalingContext.LogRecorder)
+
 	scaleDown := NewScaleDown(autoscalingContext, processors, clusterStateRegistry)
 	processorCallbacks.scaleDown = scaleDown
 
@@ -161,7 +224,15 @@ func NewStaticAutoscaler(
 		lastScaleUpTime:         initialScaleTime,
 		lastScaleDownDeleteTime: initialScaleTime,
 		lastScaleDownFailTime:   initialScaleTime,
+		lastSoftTaintTime:       initialScaleTime,
 		scaleDown:               scaleDown,
+		consolidator:            NewConsolidator(autoscalingContext),
+		autoProvisioner:         NewAutoProvisioner(autoscalingContext),
+		flavorAnalyzer:          NewFlavorAnalyzer(autoscalingContext),
+		predictor:               NewPredictor(autoscalingContext),
+		nodeRepairer:            NewNodeRepairer(autoscalingContext),
+		refresher:               NewRefresher(autoscalingContext),
+		costReporter:            NewCostReporter(autoscalingContext),
 		processors:              processors,
 		processorCallbacks:      processorCallbacks,
 		clusterStateRegistry:    clusterStateRegistry,
@@ -186,13 +257,18 @@ func (a *StaticAutoscaler) cleanUpIfRequired() {
 	if readyNodes, err := a.ReadyNodeLister().List(); err != nil {
 		klog.Errorf("Failed to list ready nodes, not cleaning up taints: %v", err)
 	} else {
-		deletetaint.CleanAllToBeDeleted(readyNodes,
+		cleaned := deletetaint.CleanAllToBeDeleted(readyNodes,
 			a.AutoscalingContext.ClientSet, a.Recorder, a.CordonNodeBeforeTerminate)
 		if a.AutoscalingContext.AutoscalingOptions.MaxBulkSoftTaintCount == 0 {
 			// Clean old taints if soft taints handling is disabled
-			deletetaint.CleanAllDeletionCandidates(readyNodes,
+			cleaned += deletetaint.CleanAllDeletionCandidates(readyNodes,
 				a.AutoscalingContext.ClientSet, a.Recorder)
 		}
+		if cleaned > 0 {
+			klog.V(1).Infof("Removed stale autoscaler taints from %v nodes on startup", cleaned)
+			a.AutoscalingContext.LogRecorder.Eventf(apiv1.EventTypeNormal, "ClusterAutoscalerCleanup",
+				"removed stale autoscaler taints from %v nodes on startup", cleaned)
+		}
 	}
 	a.initialized = true
 }
@@ -222,6 +298,19 @@ func (a *StaticAutoscaler) initializeClusterSnapshot(nodes []*apiv1.Node, schedu
 // RunOnce iterates over node groups and scales them up/down if necessary
 func (a *StaticAutoscaler) RunOnce(currentTime time.Time, kubeclient kube_client.Interface, vpcID string,
 	accessToken string, idCluster string, clusterIDPortal string, env string) errors.AutoscalerError {
+	if !a.runOnceLock.TryLock() {
+		if a.AutoscalingOptions.LoopOverrunPolicy == config.LoopOverrunQueue {
+			klog.V(1).Infof("Previous autoscaling iteration is still running, queueing this one")
+			metrics.RegisterLoopOverrun(config.LoopOverrunQueue)
+			a.runOnceLock.Lock()
+		} else {
+			klog.V(1).Infof("Previous autoscaling iteration is still running, skipping this one")
+			metrics.RegisterLoopOverrun(config.LoopOverrunSkip)
+			return nil
+		}
+	}
+	defer a.runOnceLock.Unlock()
+
 	a.cleanUpIfRequired()
 	a.processorCallbacks.reset()
 	a.clusterStateRegistry.PeriodicCleanup()
@@ -255,21 +344,93 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time, kubeclient kube_client
 	stateUpdateStart := time.Now()
 
 	//// Get nodes and pods currently living on cluster
+	listNodesStart := time.Now()
 	allNodes, readyNodes, typedErr := a.obtainNodeLists()
+	metrics.UpdateDurationFromStart(metrics.ListNodes, listNodesStart)
 
 	domainAPI := core_utils.GetDomainApiConformEnv(env)
 
 	workerNodeNameList := make([]string, 0, len(allNodes))
 	for _, node := range allNodes {
-		if strings.Contains(node.Name, "worker") {
+		// Workers tainted ToBeDeleted are already on their way out through the
+		// portal; counting them would make the enforcement below remove one more.
+		if core_utils.IsWorkerNode(node) && !isNodeBeingDeleted(node, currentTime) {
 			workerNodeNameList = append(workerNodeNameList, node.Name)
 		}
 	}
 	numberWorkerNode := len(workerNodeNameList)
-	var workerNameToRemove string
 
-	if numberWorkerNode < core_utils.GetMinSizeNodeGroup(kubeclient) {
-		workerCountNeedToScaledUp := core_utils.GetMinSizeNodeGroup(kubeclient) - numberWorkerNode
+	// Ignored taints may change while the autoscaler runs, e.g. when a CNI
+	// startup taint is added to the configmap, so they are re-read every loop.
+	configTaints, configTaintPrefixes := core_utils.GetIgnoredTaints(kubeclient)
+	a.refreshIgnoredTaints(configTaints, configTaintPrefixes)
+	core_utils.SetNamespacePriorityCutoffs(core_utils.GetNamespacePriorityCutoffs(kubeclient))
+
+	configReadStart := time.Now()
+	minSizeNodeGroup, minSizeErr := core_utils.GetMinSizeNodeGroup(kubeclient)
+	maxSizeNodeGroup, maxSizeErr := core_utils.GetMaxSizeNodeGroup(kubeclient)
+	metrics.UpdateDurationFromStart(metrics.ReadNodeGroupConfig, configReadStart)
+	if minSizeErr != nil || maxSizeErr != nil {
+		configErr := minSizeErr
+		if configErr == nil {
+			configErr = maxSizeErr
+		}
+		// The configmap is momentarily unreadable; skip the iteration instead of
+		// dying. Persistent failures are handled by the grace period in utils.
+		klog.Warningf("Skipping autoscaling iteration: %v", configErr)
+		autoscalingContext.LogRecorder.Eventf(apiv1.EventTypeWarning, events.RequiredConfigUnavailable,
+			"skipping autoscaling iteration: %v", configErr)
+		return nil
+	}
+	if minSizeNodeGroup > maxSizeNodeGroup {
+		// Acting on an inverted range would oscillate between scale up and scale
+		// down every loop; refuse to scale until the configmap is fixed.
+		message := fmt.Sprintf("min_node_group_size=%v exceeds max_node_group_size=%v, not scaling until the configmap is fixed", minSizeNodeGroup, maxSizeNodeGroup)
+		klog.Errorf("Invalid autoscaling configuration: %s", message)
+		autoscalingContext.LogRecorder.Eventf(apiv1.EventTypeWarning, events.InvalidConfiguration, message)
+		statusreport.UpdateInvalidConfiguration(message)
+		return nil
+	}
+	statusreport.UpdateInvalidConfiguration("")
+
+	targetWorkerCount := numberWorkerNode
+	if targetWorkerCount < minSizeNodeGroup {
+		targetWorkerCount = minSizeNodeGroup
+	} else if targetWorkerCount > maxSizeNodeGroup {
+		targetWorkerCount = maxSizeNodeGroup
+	}
+	metrics.UpdateNodeGroupMin(clusterIDPortal, minSizeNodeGroup)
+	metrics.UpdateNodeGroupMax(clusterIDPortal, maxSizeNodeGroup)
+	metrics.UpdateNodeGroupTargetSize(clusterIDPortal, targetWorkerCount)
+	metrics.UpdateNodeGroupCurrentSize(clusterIDPortal, numberWorkerNode)
+
+	// Estimate the hourly cost of the current worker nodes from the configured
+	// flavor pricing, so spend can be attributed to autoscaling decisions.
+	workerPrices := core_utils.GetWorkerHourlyPrices(kubeclient)
+	hourlyCost := 0.0
+	for _, node := range allNodes {
+		if core_utils.IsWorkerNode(node) {
+			hourlyCost += core_utils.WorkerHourlyPrice(workerPrices, node.Labels[core_utils.WorkerPoolLabel])
+		}
+	}
+	metrics.UpdateClusterCostPerHour(hourlyCost)
+
+	enforceLimitsStart := time.Now()
+	outsideLimits := numberWorkerNode < minSizeNodeGroup || numberWorkerNode > maxSizeNodeGroup
+	if !outsideLimits {
+		a.limitDriftSince = time.Time{}
+	} else if a.limitDriftSince.IsZero() {
+		a.limitDriftSince = currentTime
+	}
+	if core_utils.InReadOnlyMode() {
+		if outsideLimits {
+			klog.V(1).Infof("Read-only mode: worker count %v outside [%v, %v], not enforcing node group limits through the portal", numberWorkerNode, minSizeNodeGroup, maxSizeNodeGroup)
+		}
+	} else if outsideLimits && currentTime.Sub(a.limitDriftSince) < a.AutoscalingOptions.NodeGroupLimitDriftGracePeriod {
+		klog.V(1).Infof("Worker count %v outside [%v, %v] since %v, waiting %v for the drift to persist before enforcing the limits",
+			numberWorkerNode, minSizeNodeGroup, maxSizeNodeGroup, a.limitDriftSince, a.AutoscalingOptions.NodeGroupLimitDriftGracePeriod)
+	} else if numberWorkerNode < minSizeNodeGroup {
+		workerCountNeedToScaledUp := minSizeNodeGroup - numberWorkerNode
 		klog.V(1).Infof("Current worker nodes are less than min node group")
 		klog.V(1).Infof("Scaling up %v node", workerCountNeedToScaledUp)
 		//fmt.Println("current worker nodes are less than min node group")
@@ -297,23 +458,63 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time, kubeclient kube_client
 				break
 			}
 		}
-	} else if numberWorkerNode > core_utils.GetMaxSizeNodeGroup(kubeclient) {
-		for _, nodeName := range workerNodeNameList {
-			if strings.HasSuffix(nodeName, "worker"+strconv.Itoa(len(workerNodeNameList))) {
-				workerNameToRemove = nodeName
-			}
+		// An enforcement scale up is still a scale up: start the same cooldown
+		// that a regular one would, so scale down does not fight it.
+		a.lastScaleUpTime = currentTime
+	} else if numberWorkerNode > maxSizeNodeGroup {
+		if a.lastScaleUpTime.Add(a.ScaleDownDelayAfterAdd).After(currentTime) ||
+			a.lastScaleDownFailTime.Add(a.ScaleDownDelayAfterFailure).After(currentTime) ||
+			a.lastScaleDownDeleteTime.Add(a.ScaleDownDelayAfterDelete).After(currentTime) {
+			klog.V(1).Infof("Worker count %v is above max node group size %v, but scale down is in cooldown - deferring the enforcement", numberWorkerNode, maxSizeNodeGroup)
+			return nil
 		}
-		workerCountNeedToScaledDown := numberWorkerNode - core_utils.GetMaxSizeNodeGroup(kubeclient)
+		workerCountNeedToScaledDown := numberWorkerNode - maxSizeNodeGroup
 		klog.V(1).Infof("Current worker nodes are greater than max node group")
 		klog.V(1).Infof("Scaling down %v node", workerCountNeedToScaledDown)
-		//fmt.Println("current worker nodes are greater than max node group")
-		//fmt.Println("scaling down ", workerCountNeedToScaledDown, " node")
-		klog.V(1).Infof("Scaling down node %s", workerNameToRemove)
-		if !checkWorkerNodeCanBeRemove(kubeclient, workerNameToRemove) {
-			klog.V(1).Infof("Cannot perform scale down action")
+		// The portal removes the highest numbered workers, so those are the nodes
+		// that have to be checked and drained before the portal call. Worker
+		// numbering is not contiguous after a node was replaced or is already
+		// being deleted, so collect the numbers actually present and take the
+		// highest ones instead of assuming they end at the worker count.
+		workerNameByNumber := make(map[int]string, len(workerNodeNameList))
+		workerNumbers := make([]int, 0, len(workerNodeNameList))
+		for _, nodeName := range workerNodeNameList {
+			numberIndex := strings.LastIndex(nodeName, "worker")
+			if numberIndex < 0 {
+				continue
+			}
+			number, err := strconv.Atoi(nodeName[numberIndex+len("worker"):])
+			if err != nil {
+				continue
+			}
+			workerNameByNumber[number] = nodeName
+			workerNumbers = append(workerNumbers, number)
+		}
+		sort.Sort(sort.Reverse(sort.IntSlice(workerNumbers)))
+		workerNamesToRemove := make([]string, 0, workerCountNeedToScaledDown)
+		for _, number := range workerNumbers {
+			if len(workerNamesToRemove) == workerCountNeedToScaledDown {
+				break
+			}
+			workerNamesToRemove = append(workerNamesToRemove, workerNameByNumber[number])
+		}
+		if len(workerNamesToRemove) == 0 {
+			klog.Warningf("No worker names matched the expected numbering, skipping max node group size enforcement")
 			return nil
 		}
-		core_utils.PerformScaleDown(domainAPI, vpcID, accessToken, workerCountNeedToScaledDown, idCluster, clusterIDPortal)
+		klog.V(1).Infof("Scaling down nodes: %s", strings.Join(workerNamesToRemove, ", "))
+		for _, workerName := range workerNamesToRemove {
+			if !checkWorkerNodeCanBeRemove(kubeclient, workerName, a.AutoscalingOptions.ScaleDownAllowOwnerlessPods) {
+				klog.V(1).Infof("Cannot perform scale down action")
+				return nil
+			}
+		}
+		// Enforcing the maximum size is still a scale down: the same drain logic
+		// as TryToScaleDown applies so pods are not killed with their node.
+		// Only the workers that were actually verified and drained are handed
+		// to the portal, so it never removes more nodes than were prepared.
+		drainWorkerNodes(kubeclient, autoscalingContext.Recorder, workerNamesToRemove, a.AutoscalingOptions.MaxDrainParallelism, a.AutoscalingOptions.ForceDrainTimeout)
+		core_utils.PerformScaleDown(domainAPI, vpcID, accessToken, len(workerNamesToRemove), idCluster, clusterIDPortal)
 		for {
 			time.Sleep(30 * time.Second)
 			isSucceededStatus := core_utils.CheckStatusCluster(domainAPI, vpcID, accessToken, clusterIDPortal)
@@ -326,7 +527,7 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time, kubeclient kube_client
 			}
 			isErrorStatus := core_utils.CheckErrorStatusCluster(domainAPI, vpcID, accessToken, clusterIDPortal)
 			if isErrorStatus == true {
-				core_utils.PerformScaleDown(domainAPI, vpcID, accessToken, workerCountNeedToScaledDown, idCluster, clusterIDPortal)
+				core_utils.PerformScaleDown(domainAPI, vpcID, accessToken, len(workerNamesToRemove), idCluster, clusterIDPortal)
 				for {
 					time.Sleep(30 * time.Second)
 					if core_utils.CheckStatusCluster(domainAPI, vpcID, accessToken, clusterIDPortal) == true {
@@ -336,32 +537,58 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time, kubeclient kube_client
 				break
 			}
 		}
+		a.lastScaleDownDeleteTime = currentTime
 	}
-
+	metrics.UpdateDurationFromStart(metrics.EnforceNodeGroupLimits, enforceLimitsStart)
+
+	// Nodes, pods and PDBs are independent, so fetch them concurrently instead
+	// of serializing the lister round trips at the start of every loop.
+	var (
+		listGroup             sync.WaitGroup
+		originalScheduledPods []*apiv1.Pod
+		unschedulablePods     []*apiv1.Pod
+		pdbs                  []*policyv1.PodDisruptionBudget
+		scheduledPodsErr      error
+		unschedulablePodsErr  error
+		pdbsErr               error
+	)
+	listGroup.Add(3)
+	go func() {
+		defer listGroup.Done()
+		originalScheduledPods, scheduledPodsErr = scheduledPodLister.List()
+	}()
+	go func() {
+		defer listGroup.Done()
+		unschedulablePods, unschedulablePodsErr = unschedulablePodLister.List()
+	}()
+	go func() {
+		defer listGroup.Done()
+		pdbs, pdbsErr = pdbLister.List()
+	}()
 	allNodes, readyNodes, typedErr = a.obtainNodeLists()
-
-	//fmt.Println("allNodes are")
-	//for _, node := range allNodes {
-	//	fmt.Println(node.Name)
-	//}
-	//fmt.Println("readyNodes are")
-	//for _, node := range readyNodes {
-	//	fmt.Println(node.Name)
-	//}
+	listGroup.Wait()
 	if typedErr != nil {
 		klog.Errorf("Failed to get node list: %v", typedErr)
+		metrics.RegisterErrorForPhase(typedErr, metrics.ListNodes)
 		return typedErr
 	}
-	originalScheduledPods, err := scheduledPodLister.List()
-	//fmt.Println()
-	//fmt.Println("originalScheduledPods are")
-	//for _, pod := range originalScheduledPods {
-	//	fmt.Println(pod.Name)
-	//}
-	fmt.Println()
-	if err != nil {
-		klog.Errorf("Failed to list scheduled pods: %v", err)
-		return errors.ToAutoscalerError(errors.ApiCallError, err)
+	if scheduledPodsErr != nil {
+		klog.Errorf("Failed to list scheduled pods: %v", scheduledPodsErr)
+		typedErr := errors.ToAutoscalerError(errors.ApiCallError, scheduledPodsErr)
+		metrics.RegisterErrorForPhase(typedErr, metrics.ListNodes)
+		return typedErr
+	}
+	if unschedulablePodsErr != nil {
+		klog.Errorf("Failed to list unscheduled pods: %v", unschedulablePodsErr)
+		typedErr := errors.ToAutoscalerError(errors.ApiCallError, unschedulablePodsErr)
+		metrics.RegisterErrorForPhase(typedErr, metrics.ListNodes)
+		return typedErr
+	}
+	if pdbsErr != nil {
+		klog.Errorf("Failed to list pod disruption budgets: %v", pdbsErr)
+		typedErr := errors.ToAutoscalerError(errors.ApiCallError, pdbsErr)
+		metrics.RegisterErrorForPhase(typedErr, metrics.ListNodes)
+		return typedErr
 	}
 
 	if abortLoop, err := a.processors.ActionableClusterProcessor.ShouldAbort(
@@ -384,7 +611,9 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time, kubeclient kube_client
 	//}
 	if err != nil {
 		klog.Errorf("Failed to get daemonset list: %v", err)
-		return errors.ToAutoscalerError(errors.ApiCallError, err)
+		typedErr := errors.ToAutoscalerError(errors.ApiCallError, err)
+		metrics.RegisterErrorForPhase(typedErr, metrics.ListNodes)
+		return typedErr
 	}
 
 	// Call CloudProvider.Refresh before any other calls to cloud provider.
@@ -409,9 +638,19 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time, kubeclient kube_client
 	//	fmt.Println(pod.Name)
 	//}
 	// Initialize cluster state to ClusterSnapshot
+	snapshotInitStart := time.Now()
 	if typedErr := a.initializeClusterSnapshot(allNodes, nonExpendableScheduledPods); typedErr != nil {
+		metrics.RegisterErrorForPhase(typedErr, metrics.SnapshotInit)
 		return typedErr.AddPrefix("Initialize ClusterSnapshot")
 	}
+	metrics.UpdateDurationFromStart(metrics.SnapshotInit, snapshotInitStart)
+
+	// Sample per pool utilization for the flavor right-sizing analyzer now
+	// that the snapshot reflects the current cluster state.
+	a.flavorAnalyzer.Observe(currentTime, kubeclient)
+
+	// Integrate running node costs and publish the periodic cost report.
+	a.costReporter.Observe(currentTime, allNodes, kubeclient)
 
 	//nodeInfosForGroups, autoscalerError := a.processors.TemplateNodeInfoProvider.Process(autoscalingContext, readyNodes, daemonsets, a.ignoredTaints, currentTime)
 	//if autoscalerError != nil {
@@ -429,14 +668,34 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time, kubeclient kube_client
 
 	if typedErr := a.updateClusterState(allNodes, currentTime); typedErr != nil {
 		klog.Errorf("Failed to update cluster state: %v", typedErr)
+		metrics.RegisterErrorForPhase(typedErr, metrics.UpdateState)
 		return typedErr
 	}
 	metrics.UpdateDurationFromStart(metrics.UpdateState, stateUpdateStart)
 
+	// Replace unhealthy workers before making any scaling decision: a node
+	// stuck NotReady distorts both utilization and pending pod handling. A
+	// repair reshapes the cluster, so the rest of the iteration is skipped.
+	if a.nodeRepairer.RepairUnhealthyNodes(currentTime, allNodes, kubeclient, accessToken, vpcID, idCluster, clusterIDPortal, env) {
+		a.lastScaleUpTime = currentTime
+		a.lastScaleDownDeleteTime = currentTime
+		return nil
+	}
+
+	// Make progress on a rolling refresh, if one is running. Like a repair,
+	// a refresh batch reshapes the cluster, so the iteration ends here.
+	if a.refresher.Run(currentTime, allNodes, kubeclient, accessToken, vpcID, idCluster, clusterIDPortal, env) {
+		a.lastScaleUpTime = currentTime
+		a.lastScaleDownDeleteTime = currentTime
+		return nil
+	}
+
 	scaleUpStatus := &status.ScaleUpStatus{Result: status.ScaleUpNotTried}
 	scaleUpStatusProcessorAlreadyCalled := false
 	scaleDownStatus := &status.ScaleDownStatus{Result: status.ScaleDownNotTried}
 	scaleDownStatusProcessorAlreadyCalled := false
+	var pendingPodsForStatus []*apiv1.Pod
+	var scaleDownInCooldownForStatus bool
 
 	defer func() {
 		// Update status information when the loop is done (regardless of reason)
@@ -460,6 +719,8 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time, kubeclient kube_client
 		if err != nil {
 			klog.Errorf("AutoscalingStatusProcessor error: %v.", err)
 		}
+
+		a.publishStatusReport(currentTime, allNodes, pendingPodsForStatus, scaleDownInCooldownForStatus)
 	}()
 
 	//// Check if there are any nodes that failed to register in Kubernetes
@@ -482,7 +743,7 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time, kubeclient kube_client
 	if !a.clusterStateRegistry.IsClusterHealthy() {
 		klog.Warning("Cluster is not ready for autoscaling")
 		scaleDown.CleanUpUnneededNodes()
-		autoscalingContext.LogRecorder.Eventf(apiv1.EventTypeWarning, "ClusterUnhealthy", "Cluster is unhealthy")
+		autoscalingContext.LogRecorder.Eventf(apiv1.EventTypeWarning, events.ClusterUnhealthy, "Cluster is unhealthy")
 		return nil
 	}
 
@@ -506,15 +767,6 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time, kubeclient kube_client
 
 	metrics.UpdateLastTime(metrics.Autoscaling, time.Now())
 
-	unschedulablePods, err := unschedulablePodLister.List()
-	//fmt.Println("unschedulablePods are: ")
-	//for _, pod := range unschedulablePods {
-	//	fmt.Println(pod.Name)
-	//}
-	if err != nil {
-		klog.Errorf("Failed to list unscheduled pods: %v", err)
-		return errors.ToAutoscalerError(errors.ApiCallError, err)
-	}
 	metrics.UpdateUnschedulablePodsCount(len(unschedulablePods))
 
 	unschedulablePods = tpu.ClearTPURequests(unschedulablePods)
@@ -533,6 +785,10 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time, kubeclient kube_client
 			return errors.ToAutoscalerError(errors.InternalError, err)
 		}
 	}
+	// The nominated nodes are needed regardless of what the simulated pod
+	// placement says, so scale down must not delete them out from under the
+	// preempting pods.
+	scaleDown.SetPodsAwaitingPreemption(unschedulableWaitingForLowerPriorityPreemption)
 
 	//// add upcoming nodes to ClusterSnapshot
 	//upcomingNodes := getUpcomingNodeInfos(a.clusterStateRegistry, nodeInfosForGroups)
@@ -559,6 +815,18 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time, kubeclient kube_client
 	} else {
 		a.AutoscalingContext.DebuggingSnapshotter.SetClusterNodes(l)
 	}
+	a.AutoscalingContext.DebuggingSnapshotter.SetExpanderDecisions(audit.LastDecisions())
+	a.AutoscalingContext.DebuggingSnapshotter.SetPodDisruptionBudgets(pdbs)
+	if a.AutoscalingContext.DebuggingSnapshotter.IsDataCollectionAllowed() {
+		// The events are only needed for the snapshot, so the api call is
+		// skipped on the loops where no capture is in progress.
+		schedulingEvents, eventsErr := kubeclient.CoreV1().Events(apiv1.NamespaceAll).List(ctx.TODO(), metav1.ListOptions{FieldSelector: "reason=FailedScheduling", ResourceVersion: "0"})
+		if eventsErr != nil {
+			klog.Errorf("Unable to fetch FailedScheduling events for Debugging Snapshot, %v", eventsErr)
+		} else {
+			a.AutoscalingContext.DebuggingSnapshotter.SetFailedSchedulingEvents(schedulingEvents.Items)
+		}
+	}
 
 	unschedulablePodsToHelp, _ := a.processors.PodListProcessor.Process(a.AutoscalingContext, unschedulablePods)
 	//fmt.Println()
@@ -569,6 +837,17 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time, kubeclient kube_client
 
 	// finally, filter out pods that are too "young" to safely be considered for a scale-up (delay is configurable)
 	unschedulablePodsToHelp = a.filterOutYoungPods(unschedulablePodsToHelp, currentTime)
+	pendingPodsForStatus = unschedulablePodsToHelp
+	a.AutoscalingContext.DebuggingSnapshotter.SetPendingPodConditions(unschedulablePodsToHelp)
+	if len(unschedulablePodsToHelp) > 0 && a.AutoscalingContext.DebuggingSnapshotter.IsDataCollectionAllowed() {
+		// Simulating every pending pod against every node is too expensive to
+		// do on the loops where no capture is in progress.
+		a.AutoscalingContext.DebuggingSnapshotter.SetSchedulabilityReports(buildSchedulabilityReports(a.PredicateChecker, a.ClusterSnapshot, unschedulablePodsToHelp))
+		a.AutoscalingContext.DebuggingSnapshotter.SetPoolEstimations(buildPoolEstimations(kubeclient, unschedulablePodsToHelp, allNodes, daemonsets))
+	}
+	for _, pod := range unschedulablePodsToHelp {
+		metrics.ObservePendingPodAge(currentTime.Sub(pod.CreationTimestamp.Time))
+	}
 	//fmt.Println()
 	//fmt.Println("filter out unschedulablePodsToHelp are: ")
 	//for _, pod := range unschedulablePodsToHelp {
@@ -579,6 +858,16 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time, kubeclient kube_client
 	//fmt.Println("Min node total is: ", core_utils.GetMinSizeNodeGroup(kubeclient))
 	//fmt.Println("Access Token FPTCloud is: ", core_utils.GetAccessToken(kubeclient))
 	//fmt.Println("VPC ID of customer is: ", core_utils.GetVPCId(kubeclient))
+	// Feed the demand history regardless of what happens with the pods below,
+	// so quiet hours are recorded as zero demand too.
+	a.predictor.RecordDemand(currentTime, unschedulablePodsToHelp, kubeclient)
+
+	// Bursts announced through the control API are provisioned before looking
+	// at unschedulable pods, so their capacity is ready when the pods arrive.
+	if a.processPreScaleSignals(currentTime, readyNodes, kubeclient, accessToken, vpcID, idCluster, clusterIDPortal, env) {
+		a.lastScaleUpTime = currentTime
+	}
+
 	if len(unschedulablePodsToHelp) == 0 {
 		scaleUpStatus.Result = status.ScaleUpNotNeeded
 		klog.V(1).Info("No unschedulable pods")
@@ -586,6 +875,16 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time, kubeclient kube_client
 
 		//fmt.Println("No need Scale up")
 
+		// A quiet cluster is the right moment to pre-scale for a predicted
+		// recurring peak.
+		if a.predictor.MaybePreScale(currentTime, readyNodes, kubeclient, accessToken, vpcID, idCluster, clusterIDPortal, env) {
+			a.lastScaleUpTime = currentTime
+		}
+
+		// Likewise restore the configured free capacity watermark, if any.
+		if a.enforceHeadroom(currentTime, kubeclient, accessToken, vpcID, idCluster, clusterIDPortal, env) {
+			a.lastScaleUpTime = currentTime
+		}
 	} else if allPodsAreNew(unschedulablePodsToHelp, currentTime) {
 		// The assumption here is that these pods have been created very recently and probably there
 		// is more pods to come. In theory we could check the newest pod time but then if pod were created
@@ -604,6 +903,14 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time, kubeclient kube_client
 		klog.V(1).Info("Start to scale up")
 		metrics.UpdateLastTime(metrics.ScaleUp, scaleUpStart)
 
+		// Pods that fit no existing pool cannot be helped by scaling one; give
+		// the auto-provisioner a chance to create a suitable worker group first.
+		var provisioned bool
+		unschedulablePodsToHelp, provisioned = a.autoProvisioner.ProvisionForMisfitPods(currentTime, unschedulablePodsToHelp, readyNodes, kubeclient, accessToken, vpcID, idCluster, clusterIDPortal, env)
+		if provisioned {
+			a.lastScaleUpTime = currentTime
+		}
+
 		scaleUpStatus, typedErr = ScaleUp(autoscalingContext, a.processors, a.clusterStateRegistry, unschedulablePodsToHelp, readyNodes, daemonsets, a.ignoredTaints, kubeclient, accessToken, vpcID, idCluster, clusterIDPortal, env)
 
 		metrics.UpdateDurationFromStart(metrics.ScaleUp, scaleUpStart)
@@ -615,6 +922,8 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time, kubeclient kube_client
 
 		if typedErr != nil {
 			klog.Errorf("Failed to scale up: %v", typedErr)
+			metrics.RegisterErrorForPhase(typedErr, metrics.ScaleUp)
+			a.AutoscalingContext.DebuggingSnapshotter.RequestFailureSnapshot("scale up failed: " + typedErr.Error())
 			return typedErr
 		}
 		if scaleUpStatus.Result == status.ScaleUpSuccessful {
@@ -628,21 +937,11 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time, kubeclient kube_client
 	//fmt.Println()
 	//fmt.Println("ScaleDownEnabled is: ", a.ScaleDownEnabled)
 
-	if a.ScaleDownEnabled {
-		pdbs, err := pdbLister.List()
-
-		// fmt.Println()
-		// fmt.Println("PDBs are: ")
-
-		// for _, pdb := range pdbs {
-		// 	fmt.Println(pdb.Name)
-		// }
-		if err != nil {
-			scaleDownStatus.Result = status.ScaleDownError
-			klog.Errorf("Failed to list pod disruption budgets: %v", err)
-			return errors.ToAutoscalerError(errors.ApiCallError, err)
-		}
+	// Auto-provisioned pools are deleted here rather than through the regular
+	// scale down, which only shrinks pools worker by worker.
+	a.autoProvisioner.CleanupEmptyPools(currentTime, kubeclient, accessToken, vpcID, idCluster, clusterIDPortal, env)
 
+	if a.ScaleDownEnabled {
 		unneededStart := time.Now()
 
 		klog.V(4).Infof("Calculating unneeded nodes")
@@ -700,11 +999,18 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time, kubeclient kube_client
 			}
 		}
 
+		// A node that has been NotReady for longer than the unready time cannot
+		// receive rescheduled pods, so the simulation must not count it as a
+		// destination for the pods of the nodes being removed.
+		podDestinations = filterOutLongNotReadyNodes(podDestinations, currentTime, a.NodeGroupDefaults.ScaleDownUnreadyTime)
+
 		// We use scheduledPods (not originalScheduledPods) here, so artificial scheduled pods introduced by processors
 		// (e.g unscheduled pods with nominated node name) can block scaledown of given node.
 		if typedErr := scaleDown.UpdateUnneededNodes(podDestinations, scaleDownCandidates, currentTime, pdbs, kubeclient); typedErr != nil {
 			scaleDownStatus.Result = status.ScaleDownError
 			klog.Errorf("Failed to scale down: %v", typedErr)
+			metrics.RegisterErrorForPhase(typedErr, metrics.FindUnneeded)
+			a.AutoscalingContext.DebuggingSnapshotter.RequestFailureSnapshot("scale down failed: " + typedErr.Error())
 			return typedErr
 		}
 
@@ -720,6 +1026,7 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time, kubeclient kube_client
 			a.lastScaleUpTime.Add(a.ScaleDownDelayAfterAdd).After(currentTime) ||
 			a.lastScaleDownFailTime.Add(a.ScaleDownDelayAfterFailure).After(currentTime) ||
 			a.lastScaleDownDeleteTime.Add(a.ScaleDownDelayAfterDelete).After(currentTime)
+		scaleDownInCooldownForStatus = scaleDownInCooldown
 
 		//fmt.Println()
 		//fmt.Println("scaleDownInCooldown is: ", scaleDownInCooldown)
@@ -740,8 +1047,22 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time, kubeclient kube_client
 
 		if scaleDownInCooldown {
 			scaleDownStatus.Result = status.ScaleDownInCooldown
+			// Scale down was only simulated, but operators still want to preview
+			// what would happen once the cooldown ends.
+			scaleDownStatus.ScaledDownNodes = scaleDown.unneededNodesStatus()
+			for _, sdNode := range scaleDownStatus.ScaledDownNodes {
+				podNames := make([]string, 0, len(sdNode.EvictedPods))
+				for _, pod := range sdNode.EvictedPods {
+					podNames = append(podNames, pod.Namespace+"/"+pod.Name)
+				}
+				klog.V(1).Infof("Scale down dry run: node %s (utilization %v) would be removed, pods to reschedule: %v",
+					sdNode.Node.Name, sdNode.UtilInfo.Utilization, podNames)
+			}
 		} else if scaleDown.nodeDeletionTracker.IsNonEmptyNodeDeleteInProgress() {
 			scaleDownStatus.Result = status.ScaleDownInProgress
+			for nodeName, elapsed := range scaleDown.nodeDeletionTracker.DeletionsInProgress() {
+				klog.V(1).Infof("Deletion of node %s has been in progress for %v", nodeName, elapsed)
+			}
 		} else {
 			klog.V(4).Infof("Starting scale down")
 
@@ -773,8 +1094,21 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time, kubeclient kube_client
 
 			if (scaleDownStatus.Result == status.ScaleDownNoNodeDeleted ||
 				scaleDownStatus.Result == status.ScaleDownNoUnneeded) &&
-				a.AutoscalingContext.AutoscalingOptions.MaxBulkSoftTaintCount != 0 {
+				a.AutoscalingOptions.ProactiveConsolidationEnabled {
+				// The regular scale down found nothing to remove; see whether a
+				// whole set of underutilized workers can be repacked instead.
+				if a.consolidator.TryToConsolidate(currentTime, pdbs, kubeclient, accessToken, vpcID, idCluster, clusterIDPortal, env) {
+					a.lastScaleDownDeleteTime = currentTime
+				}
+			}
+
+			if (scaleDownStatus.Result == status.ScaleDownNoNodeDeleted ||
+				scaleDownStatus.Result == status.ScaleDownNoUnneeded) &&
+				a.AutoscalingContext.AutoscalingOptions.MaxBulkSoftTaintCount != 0 &&
+				!a.AutoscalingContext.AutoscalingOptions.NodeGroupDefaults.DisableSoftTaint &&
+				!a.lastSoftTaintTime.Add(a.AutoscalingContext.AutoscalingOptions.SoftTaintInterval).After(currentTime) {
 				scaleDown.SoftTaintUnneededNodes(allNodes)
+				a.lastSoftTaintTime = currentTime
 			}
 
 			if a.processors != nil && a.processors.ScaleDownStatusProcessor != nil {
@@ -785,7 +1119,9 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time, kubeclient kube_client
 
 			if typedErr != nil {
 				klog.Errorf("Failed to scale down: %v", typedErr)
+				metrics.RegisterErrorForPhase(typedErr, metrics.ScaleDown)
 				a.lastScaleDownFailTime = currentTime
+				a.AutoscalingContext.DebuggingSnapshotter.RequestFailureSnapshot("scale down failed: " + typedErr.Error())
 				return typedErr
 			}
 		}
@@ -915,6 +1251,27 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time, kubeclient kube_client
 //	return nodeGroups
 //}
 
+// refreshIgnoredTaints rebuilds the ignored taint set from the startup options
+// and the taints currently configured in the autoscaling configmap, so new
+// CNI or bootstrap taints take effect without a restart.
+func (a *StaticAutoscaler) refreshIgnoredTaints(configTaints []string, configTaintPrefixes []string) {
+	merged := make(taints.TaintKeySet)
+	for _, taintKey := range a.AutoscalingOptions.IgnoredTaints {
+		merged[taintKey] = true
+	}
+	for _, taintKey := range configTaints {
+		if !merged[taintKey] {
+			klog.V(4).Infof("Ignoring taint %s from the autoscaling configmap on all NodeGroups", taintKey)
+		}
+		merged[taintKey] = true
+	}
+	if len(merged) != len(a.ignoredTaints) {
+		klog.V(1).Infof("Ignored taint set changed, now ignoring %v taint keys and %v taint prefixes", len(merged), len(configTaintPrefixes))
+	}
+	a.ignoredTaints = merged
+	taints.SetExtraIgnoreTaintPrefixes(configTaintPrefixes)
+}
+
 // don't consider pods newer than newPodScaleUpDelay seconds old as unschedulable
 func (a *StaticAutoscaler) filterOutYoungPods(allUnschedulablePods []*apiv1.Pod, currentTime time.Time) []*apiv1.Pod {
 	var oldUnschedulablePods []*apiv1.Pod
@@ -979,6 +1336,154 @@ func (a *StaticAutoscaler) updateClusterState(allNodes []*apiv1.Node, currentTim
 	return nil
 }
 
+// filterOutLongNotReadyNodes drops nodes that have been NotReady for longer
+// than unreadyTime from the given list.
+func filterOutLongNotReadyNodes(nodes []*apiv1.Node, currentTime time.Time, unreadyTime time.Duration) []*apiv1.Node {
+	result := make([]*apiv1.Node, 0, len(nodes))
+	for _, node := range nodes {
+		if ready, lastTransition, err := kube_util.GetReadinessState(node); err == nil && !ready &&
+			lastTransition.Add(unreadyTime).Before(currentTime) {
+			klog.V(2).Infof("Excluding long NotReady node %s from pod destination candidates", node.Name)
+			continue
+		}
+		result = append(result, node)
+	}
+	return result
+}
+
+// publishStatusReport refreshes the machine readable autoscaler state served
+// from the /statusz endpoint at the end of every loop iteration.
+func (a *StaticAutoscaler) publishStatusReport(currentTime time.Time, nodes []*apiv1.Node, pendingPods []*apiv1.Pod, scaleDownInCooldown bool) {
+	report := statusreport.Report{
+		Time:                    currentTime,
+		WorkerPoolSizes:         map[string]int{},
+		ScaleDownInCooldown:     scaleDownInCooldown,
+		LastScaleUpTime:         a.lastScaleUpTime,
+		LastScaleDownDeleteTime: a.lastScaleDownDeleteTime,
+		LastScaleDownFailTime:   a.lastScaleDownFailTime,
+	}
+	for _, node := range nodes {
+		if !core_utils.IsWorkerNode(node) {
+			continue
+		}
+		pool := node.Labels[core_utils.WorkerPoolLabel]
+		if pool == "" {
+			pool = "default"
+		}
+		report.WorkerPoolSizes[pool]++
+	}
+	for _, pod := range pendingPods {
+		report.PendingPods = append(report.PendingPods, pod.Namespace+"/"+pod.Name)
+	}
+	for name, since := range a.scaleDown.unneededNodes {
+		report.UnneededNodes = append(report.UnneededNodes, statusreport.UnneededNode{Name: name, Since: since})
+	}
+	for name, unremovable := range a.scaleDown.unremovableNodeReasons {
+		report.UnremovableNodes = append(report.UnremovableNodes, statusreport.UnremovableNode{Name: name, Reason: unremovableReasonString(unremovable.Reason)})
+	}
+	for name, elapsed := range a.scaleDown.nodeDeletionTracker.DeletionsInProgress() {
+		report.DeletionsInProgress = append(report.DeletionsInProgress, statusreport.Deletion{Node: name, Elapsed: elapsed.String()})
+	}
+	statusreport.Update(report)
+	a.publishNodeExplanations(scaleDownInCooldown)
+}
+
+// publishNodeExplanations refreshes the per-node scale down explanations served
+// from the /explain endpoint, answering why a given node is not being removed.
+func (a *StaticAutoscaler) publishNodeExplanations(scaleDownInCooldown bool) {
+	explanations := make(map[string]statusreport.NodeExplanation)
+	for name, utilInfo := range a.scaleDown.nodeUtilizationMap {
+		explanations[name] = statusreport.NodeExplanation{
+			Node:                name,
+			CpuUtilization:      utilInfo.CpuUtil,
+			MemUtilization:      utilInfo.MemUtil,
+			ScaleDownInCooldown: scaleDownInCooldown,
+		}
+	}
+	for name, since := range a.scaleDown.unneededNodes {
+		explanation := explanations[name]
+		explanation.Node = name
+		explanation.ScaleDownInCooldown = scaleDownInCooldown
+		unneededSince := since
+		explanation.UnneededSince = &unneededSince
+		explanations[name] = explanation
+	}
+	for name, unremovable := range a.scaleDown.unremovableNodeReasons {
+		explanation := explanations[name]
+		explanation.Node = name
+		explanation.ScaleDownInCooldown = scaleDownInCooldown
+		explanation.Reason = unremovableReasonString(unremovable.Reason)
+		if unremovable.BlockingPod != nil && unremovable.BlockingPod.Pod != nil {
+			explanation.BlockingPod = unremovable.BlockingPod.Pod.Namespace + "/" + unremovable.BlockingPod.Pod.Name
+			explanation.BlockingPodReason = blockingPodReasonString(unremovable.BlockingPod.Reason)
+		}
+		explanations[name] = explanation
+	}
+	statusreport.UpdateExplanations(explanations)
+}
+
+// unremovableReasonString renders an UnremovableReason for the status report.
+func unremovableReasonString(reason simulator.UnremovableReason) string {
+	switch reason {
+	case simulator.ScaleDownDisabledAnnotation:
+		return "ScaleDownDisabledAnnotation"
+	case simulator.NotAutoscaled:
+		return "NotAutoscaled"
+	case simulator.NotUnneededLongEnough:
+		return "NotUnneededLongEnough"
+	case simulator.NotUnreadyLongEnough:
+		return "NotUnreadyLongEnough"
+	case simulator.NodeGroupMinSizeReached:
+		return "NodeGroupMinSizeReached"
+	case simulator.MinimalResourceLimitExceeded:
+		return "MinimalResourceLimitExceeded"
+	case simulator.CurrentlyBeingDeleted:
+		return "CurrentlyBeingDeleted"
+	case simulator.NotUnderutilized:
+		return "NotUnderutilized"
+	case simulator.NotUnneededOtherReason:
+		return "NotUnneededOtherReason"
+	case simulator.RecentlyUnremovable:
+		return "RecentlyUnremovable"
+	case simulator.NoPlaceToMovePods:
+		return "NoPlaceToMovePods"
+	case simulator.BlockedByPod:
+		return "BlockedByPod"
+	case simulator.UnexpectedError:
+		return "UnexpectedError"
+	case simulator.RecentPodActivity:
+		return "RecentPodActivity"
+	case simulator.ScaleDownExcludedTaint:
+		return "ScaleDownExcludedTaint"
+	default:
+		return "NoReason"
+	}
+}
+
+// blockingPodReasonString renders a BlockingPodReason for the explain endpoint.
+func blockingPodReasonString(reason drain.BlockingPodReason) string {
+	switch reason {
+	case drain.ControllerNotFound:
+		return "ControllerNotFound"
+	case drain.MinReplicasReached:
+		return "MinReplicasReached"
+	case drain.NotReplicated:
+		return "NotReplicated"
+	case drain.LocalStorageRequested:
+		return "LocalStorageRequested"
+	case drain.NotSafeToEvictAnnotation:
+		return "NotSafeToEvictAnnotation"
+	case drain.UnmovableKubeSystemPod:
+		return "UnmovableKubeSystemPod"
+	case drain.NotEnoughPdb:
+		return "NotEnoughPdb"
+	case drain.UnexpectedError:
+		return "UnexpectedError"
+	default:
+		return "NoReason"
+	}
+}
+
 func allPodsAreNew(pods []*apiv1.Pod, currentTime time.Time) bool {
 	if core_utils.GetOldestCreateTime(pods).Add(unschedulablePodTimeBuffer).After(currentTime) {
 		return true
@@ -1030,33 +1535,6 @@ func calculateCoresMemoryTotal(nodes []*apiv1.Node, timestamp time.Time) (int64,
 	return coresTotal, memoryTotal
 }
 
-func checkWorkerNodeCanBeRemove(kubeclient kube_client.Interface, workerNodeName string) bool {
-	var canBeRemove bool = true
-	pods, err := kubeclient.CoreV1().Pods("").List(ctx.Background(), metav1.ListOptions{})
-	if err != nil {
-		log.Fatal(err)
-	}
-	for _, pod := range pods.Items {
-		if pod.Spec.NodeName == workerNodeName && pod.OwnerReferences[0].Kind != "DaemonSet" {
-			replicaset, _ := kubeclient.AppsV1().ReplicaSets(pod.Namespace).Get(ctx.Background(),
-				pod.OwnerReferences[0].Name, metav1.GetOptions{})
-			//if err != nil {
-			//	log.Fatal(err)
-			//}
-			if replicaset.Status.Replicas == 1 {
-				klog.V(1).Infof("If you want to scale down, you should evict pod %s in namespace %s "+
-					"because your replicaset %s has only one replica", pod.Name, pod.Namespace,
-					replicaset.Name)
-				canBeRemove = false
-			}
-			for _, volume := range pod.Spec.Volumes {
-				if volume.EmptyDir != nil {
-					klog.V(1).Infof("If you want to scale down, you should evict pod %s"+
-						" in namespace %s because pod has local storage", pod.Name, pod.Namespace)
-					canBeRemove = false
-				}
-			}
-		}
-	}
-	return canBeRemove
+func checkWorkerNodeCanBeRemove(kubeclient kube_client.Interface, workerNodeName string, allowOwnerlessPods bool) bool {
+	return checkWorkerNodeCanBeScaleDown(kubeclient, workerNodeName, allowOwnerlessPods)
 }
@@ -0,0 +1,143 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	ctx "context"
+	"sync"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	kube_client "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	klog "k8s.io/klog/v2"
+)
+
+var (
+	configCacheMutex    sync.RWMutex
+	cachedConfigMapData map[string]string
+	cachedSecretData    map[string][]byte
+)
+
+// StartConfigCache keeps an in-memory copy of the autoscaling configmap and
+// the fke secret refreshed through shared informers, so the config getters do
+// not hit the API server on every loop. Until the caches sync, or should the
+// objects be deleted, the getters fall back to live GETs.
+func StartConfigCache(kubeclient kube_client.Interface) {
+	configMapFactory := informers.NewSharedInformerFactoryWithOptions(kubeclient, 0,
+		informers.WithNamespace("kube-system"),
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.FieldSelector = "metadata.name=autoscaling-configmap"
+		}))
+	secretFactory := informers.NewSharedInformerFactoryWithOptions(kubeclient, 0,
+		informers.WithNamespace("kube-system"),
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.FieldSelector = "metadata.name=fke-secret"
+		}))
+
+	configMapInformer := configMapFactory.Core().V1().ConfigMaps().Informer()
+	configMapInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    storeCachedConfigMap,
+		UpdateFunc: func(_, obj interface{}) { storeCachedConfigMap(obj) },
+		DeleteFunc: func(_ interface{}) { setCachedConfigMapData(nil) },
+	})
+	secretInformer := secretFactory.Core().V1().Secrets().Informer()
+	secretInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    storeCachedSecret,
+		UpdateFunc: func(_, obj interface{}) { storeCachedSecret(obj) },
+		DeleteFunc: func(_ interface{}) { setCachedSecretData(nil) },
+	})
+
+	stopCh := make(chan struct{})
+	configMapFactory.Start(stopCh)
+	secretFactory.Start(stopCh)
+	synced := cache.WaitForCacheSync(stopCh, configMapInformer.HasSynced, secretInformer.HasSynced)
+	if !synced {
+		klog.Warningf("Config caches did not sync, config reads stay uncached until they do")
+		return
+	}
+	klog.V(1).Infof("Autoscaling configmap and fke secret are now served from informer caches")
+}
+
+func storeCachedConfigMap(obj interface{}) {
+	configMap, ok := obj.(*apiv1.ConfigMap)
+	if !ok {
+		return
+	}
+	data := make(map[string]string, len(configMap.Data))
+	for k, v := range configMap.Data {
+		data[k] = v
+	}
+	setCachedConfigMapData(data)
+}
+
+func storeCachedSecret(obj interface{}) {
+	secret, ok := obj.(*apiv1.Secret)
+	if !ok {
+		return
+	}
+	data := make(map[string][]byte, len(secret.Data))
+	for k, v := range secret.Data {
+		data[k] = v
+	}
+	setCachedSecretData(data)
+}
+
+func setCachedConfigMapData(data map[string]string) {
+	configCacheMutex.Lock()
+	defer configCacheMutex.Unlock()
+	cachedConfigMapData = data
+}
+
+func setCachedSecretData(data map[string][]byte) {
+	configCacheMutex.Lock()
+	defer configCacheMutex.Unlock()
+	cachedSecretData = data
+}
+
+// autoscalingConfigMapData returns the data of the autoscaling configmap,
+// preferring the informer cache over a live GET.
+func autoscalingConfigMapData(kubeclient kube_client.Interface) (map[string]string, error) {
+	configCacheMutex.RLock()
+	data := cachedConfigMapData
+	configCacheMutex.RUnlock()
+	if data != nil {
+		return data, nil
+	}
+	configmaps, err := kubeclient.CoreV1().ConfigMaps("kube-system").Get(ctx.Background(), "autoscaling-configmap", metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return configmaps.Data, nil
+}
+
+// fkeSecretData returns the data of the fke secret, preferring the informer
+// cache over a live GET.
+func fkeSecretData(kubeclient kube_client.Interface) (map[string][]byte, error) {
+	configCacheMutex.RLock()
+	data := cachedSecretData
+	configCacheMutex.RUnlock()
+	if data != nil {
+		return data, nil
+	}
+	secret, err := kubeclient.CoreV1().Secrets("kube-system").Get(ctx.Background(), "fke-secret", metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return secret.Data, nil
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"sync"
+)
+
+// CostLedgerEntry counts the nodes one worker pool gained and lost through
+// portal actions since the ledger was last reset. The empty pool name covers
+// actions that do not carry pool information, like plain scale downs.
+type CostLedgerEntry struct {
+	NodesAdded   int
+	NodesRemoved int
+}
+
+var (
+	costLedgerMutex sync.Mutex
+	costLedger      = make(map[string]CostLedgerEntry)
+)
+
+// recordNodesAdded books workers added to a pool through the portal.
+func recordNodesAdded(poolName string, count int) {
+	costLedgerMutex.Lock()
+	defer costLedgerMutex.Unlock()
+	entry := costLedger[poolName]
+	entry.NodesAdded += count
+	costLedger[poolName] = entry
+}
+
+// recordNodesRemoved books workers removed from a pool through the portal.
+func recordNodesRemoved(poolName string, count int) {
+	costLedgerMutex.Lock()
+	defer costLedgerMutex.Unlock()
+	entry := costLedger[poolName]
+	entry.NodesRemoved += count
+	costLedger[poolName] = entry
+}
+
+// SnapshotAndResetCostLedger returns the accumulated per pool counts and
+// starts a fresh accounting window.
+func SnapshotAndResetCostLedger() map[string]CostLedgerEntry {
+	costLedgerMutex.Lock()
+	defer costLedgerMutex.Unlock()
+	snapshot := costLedger
+	costLedger = make(map[string]CostLedgerEntry)
+	return snapshot
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"bytes"
+	ctx "context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kube_client "k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+// GetBootstrapToken reads the long-lived bootstrap credential from the fke
+// secret, returning the empty string when the secret has no bootstrap_token
+// key.
+func GetBootstrapToken(kubeclient kube_client.Interface) string {
+	secret, err := kubeclient.CoreV1().Secrets("kube-system").Get(ctx.Background(), "fke-secret", metav1.GetOptions{})
+	if err != nil {
+		klog.Errorf("Failed to get information of fke secret: %v", err)
+		return ""
+	}
+	bootstrapToken := string(secret.Data["bootstrap_token"])
+	registerRedactedValue(bootstrapToken)
+	return bootstrapToken
+}
+
+// tokenExchangeResponse is the identity service reply to a token exchange.
+type tokenExchangeResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// exchangeToken trades the bootstrap credential for a short-lived portal token.
+func exchangeToken(domainAPI string, bootstrapToken string) (string, time.Duration, error) {
+	url := domainAPI + "/api/v1/identity/token-exchange"
+	postBody, _ := json.Marshal(map[string]string{
+		"grant_type": "bootstrap",
+	})
+	req, _ := http.NewRequest("POST", url, bytes.NewBuffer(postBody))
+	req.Header.Add("Authorization", "Bearer "+bootstrapToken)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := portalClient.Do(req)
+	if err != nil {
+		return "", 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", 0, fmt.Errorf("identity service returned %s", resp.Status)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", 0, err
+	}
+	var exchange tokenExchangeResponse
+	if err := json.Unmarshal(body, &exchange); err != nil {
+		return "", 0, err
+	}
+	if exchange.AccessToken == "" || exchange.ExpiresIn <= 0 {
+		return "", 0, fmt.Errorf("identity service returned no usable token")
+	}
+	return exchange.AccessToken, time.Duration(exchange.ExpiresIn) * time.Second, nil
+}
+
+// RunTokenExchange keeps exchanging the long-lived bootstrap credential for
+// short-lived portal tokens, swapping each new token in for all portal calls.
+// Tokens are refreshed after two thirds of their lifetime. It blocks, so it is
+// meant to run in its own goroutine.
+func RunTokenExchange(domainAPI string, bootstrapToken string) {
+	for {
+		token, lifetime, err := exchangeToken(domainAPI, bootstrapToken)
+		if err != nil {
+			klog.Errorf("Failed to exchange bootstrap credential for a portal token: %v", err)
+			time.Sleep(time.Minute)
+			continue
+		}
+		SetAccessToken(token)
+		klog.V(1).Infof("Obtained short-lived portal token, valid for %v", lifetime)
+		time.Sleep(lifetime * 2 / 3)
+	}
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	klog "k8s.io/klog/v2"
+)
+
+// portalProxy selects the proxy for outbound portal traffic. By default the
+// standard HTTP_PROXY/HTTPS_PROXY/NO_PROXY environment variables are honored;
+// ConfigureEgressProxy replaces it with an explicitly configured proxy.
+var portalProxy = http.ProxyFromEnvironment
+
+// portalProxyFunc indirects through portalProxy so clients rebuilt by the
+// certificate pinning helpers keep using the configured proxy.
+func portalProxyFunc(req *http.Request) (*url.URL, error) {
+	return portalProxy(req)
+}
+
+// ConfigureEgressProxy routes all portal traffic through the given inspection
+// proxy, except for hosts on the noProxy list (comma separated hostnames or
+// domain suffixes, "*" disables proxying entirely). Basic auth credentials can
+// be embedded in the proxy URL.
+func ConfigureEgressProxy(proxyURL string, noProxy string) error {
+	parsed, err := url.Parse(proxyURL)
+	if err != nil {
+		return fmt.Errorf("invalid egress proxy url: %v", err)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return fmt.Errorf("egress proxy url must use http or https, got %q", parsed.Scheme)
+	}
+	var skipped []string
+	for _, entry := range strings.Split(noProxy, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			skipped = append(skipped, strings.ToLower(entry))
+		}
+	}
+	portalProxy = func(req *http.Request) (*url.URL, error) {
+		host := strings.ToLower(req.URL.Hostname())
+		for _, entry := range skipped {
+			if entry == "*" || host == entry || strings.HasSuffix(host, "."+strings.TrimPrefix(entry, ".")) {
+				return nil, nil
+			}
+		}
+		return parsed, nil
+	}
+	klog.V(1).Infof("Routing portal traffic through egress proxy %s://%s", parsed.Scheme, parsed.Host)
+	return nil
+}
@@ -21,6 +21,28 @@ import (
 	klog "k8s.io/klog/v2"
 )
 
+// namespacePriorityCutoffs holds per namespace overrides of the expendable
+// pods priority cutoff, so e.g. low-priority batch namespaces never trigger
+// scale up while their pods still get scheduled onto existing capacity.
+var namespacePriorityCutoffs = map[string]int{}
+
+// SetNamespacePriorityCutoffs replaces the per namespace overrides of the
+// expendable pods priority cutoff.
+func SetNamespacePriorityCutoffs(cutoffs map[string]int) {
+	if cutoffs == nil {
+		cutoffs = map[string]int{}
+	}
+	namespacePriorityCutoffs = cutoffs
+}
+
+// podPriorityCutoff returns the cutoff effective for the pod's namespace.
+func podPriorityCutoff(pod *apiv1.Pod, expendablePodsPriorityCutoff int) int {
+	if cutoff, found := namespacePriorityCutoffs[pod.Namespace]; found {
+		return cutoff
+	}
+	return expendablePodsPriorityCutoff
+}
+
 // FilterOutExpendableAndSplit filters out expendable pods and splits into:
 //   - waiting for lower priority pods preemption
 //   - other pods.
@@ -34,8 +56,8 @@ func FilterOutExpendableAndSplit(unschedulableCandidates []*apiv1.Pod, nodes []*
 	}
 
 	for _, pod := range unschedulableCandidates {
-		if pod.Spec.Priority != nil && int(*pod.Spec.Priority) < expendablePodsPriorityCutoff {
-			klog.V(4).Infof("Pod %s has priority below %d (%d) and will scheduled when enough resources is free. Ignoring in scale up.", pod.Name, expendablePodsPriorityCutoff, *pod.Spec.Priority)
+		if cutoff := podPriorityCutoff(pod, expendablePodsPriorityCutoff); pod.Spec.Priority != nil && int(*pod.Spec.Priority) < cutoff {
+			klog.V(4).Infof("Pod %s has priority below %d (%d) and will scheduled when enough resources is free. Ignoring in scale up.", pod.Name, cutoff, *pod.Spec.Priority)
 		} else if nominatedNodeName := pod.Status.NominatedNodeName; nominatedNodeName != "" {
 			if nodeNames[nominatedNodeName] {
 				klog.V(4).Infof("Pod %s will be scheduled after low priority pods are preempted on %s. Ignoring in scale up.", pod.Name, nominatedNodeName)
@@ -64,5 +86,5 @@ func FilterOutExpendablePods(pods []*apiv1.Pod, expendablePodsPriorityCutoff int
 
 // IsExpendablePod tests if pod is expendable for give priority cutoff
 func IsExpendablePod(pod *apiv1.Pod, expendablePodsPriorityCutoff int) bool {
-	return pod.Spec.Priority != nil && int(*pod.Spec.Priority) < expendablePodsPriorityCutoff
+	return pod.Spec.Priority != nil && int(*pod.Spec.Priority) < podPriorityCutoff(pod, expendablePodsPriorityCutoff)
 }
@@ -105,3 +105,19 @@ func TestIsExpendablePod(t *testing.T) {
 	assert.False(t, IsExpendablePod(pod2, -11))
 
 }
+
+func TestIsExpendablePodNamespaceCutoff(t *testing.T) {
+	SetNamespacePriorityCutoffs(map[string]int{"batch": 100})
+	defer SetNamespacePriorityCutoffs(nil)
+
+	var priority10 int32 = 10
+	batchPod := BuildTestPod("b1", 1500, 200000)
+	batchPod.Namespace = "batch"
+	batchPod.Spec.Priority = &priority10
+	otherPod := BuildTestPod("o1", 1500, 200000)
+	otherPod.Spec.Priority = &priority10
+
+	// The batch namespace override applies instead of the global cutoff.
+	assert.True(t, IsExpendablePod(batchPod, 0))
+	assert.False(t, IsExpendablePod(otherPod, 0))
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"strconv"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	kube_client "k8s.io/client-go/kubernetes"
+	klog "k8s.io/klog/v2"
+)
+
+// HeadroomTarget is the free allocatable capacity to keep available in a
+// worker pool at all times, expressed as absolute resources, a percentage of
+// the pool's total allocatable, or both. The effective target per resource is
+// the larger of the two. A zero target disables headroom enforcement.
+type HeadroomTarget struct {
+	CPUMilli    int64
+	MemoryBytes int64
+	Percent     float64
+}
+
+// IsZero reports whether no headroom is configured.
+func (t HeadroomTarget) IsZero() bool {
+	return t.CPUMilli == 0 && t.MemoryBytes == 0 && t.Percent == 0
+}
+
+// GetHeadroomTarget returns the headroom target of the given worker pool, as
+// configured in the autoscaling configmap. Keys can be set globally
+// (headroom_cpu, headroom_memory, headroom_percent) or per pool (e.g.
+// headroom_cpu.gpu-pool). Missing keys mean no headroom.
+func GetHeadroomTarget(kubeclient kube_client.Interface, poolName string) HeadroomTarget {
+	data, err := autoscalingConfigMapData(kubeclient)
+	if err != nil {
+		klog.Errorf("Failed to get information of autoscaling configmap: %v", err)
+		return HeadroomTarget{}
+	}
+	target := HeadroomTarget{}
+	if value, found := poolValue(data, "headroom_cpu", poolName); found {
+		quantity, err := resource.ParseQuantity(value)
+		if err != nil {
+			klog.Errorf("Failed to parse headroom_cpu value %q in autoscaling configmap: %v", value, err)
+		} else {
+			target.CPUMilli = quantity.MilliValue()
+		}
+	}
+	if value, found := poolValue(data, "headroom_memory", poolName); found {
+		quantity, err := resource.ParseQuantity(value)
+		if err != nil {
+			klog.Errorf("Failed to parse headroom_memory value %q in autoscaling configmap: %v", value, err)
+		} else {
+			target.MemoryBytes = quantity.Value()
+		}
+	}
+	if value, found := poolValue(data, "headroom_percent", poolName); found {
+		percent, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			klog.Errorf("Failed to parse headroom_percent value %q in autoscaling configmap: %v", value, err)
+		} else {
+			target.Percent = percent / 100
+		}
+	}
+	return target
+}
+
+// poolValue reads a configmap value, preferring the per pool key over the
+// global one.
+func poolValue(data map[string]string, key string, poolName string) (string, bool) {
+	if poolName != "" {
+		if value, found := data[key+"."+poolName]; found {
+			return value, true
+		}
+	}
+	value, found := data[key]
+	return value, found
+}
@@ -18,7 +18,6 @@ package utils
 
 import (
 	"k8s.io/autoscaler/cluster-autoscaler/utils"
-	"reflect"
 
 	apiv1 "k8s.io/api/core/v1"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator"
@@ -50,7 +49,7 @@ type PodSchedulableMap map[string][]PodSchedulableInfo
 
 // Match tests if given pod matches PodSchedulableInfo
 func (psi *PodSchedulableInfo) Match(pod *apiv1.Pod) bool {
-	return reflect.DeepEqual(pod.Labels, psi.labels) && utils.PodSpecSemanticallyEqual(pod.Spec, psi.spec)
+	return utils.PodLabelsSemanticallyEqual(pod.Labels, psi.labels) && utils.PodSpecSemanticallyEqual(pod.Spec, psi.spec)
 }
 
 // Get returns scheduling info for given pod if matching one exists in PodSchedulableMap
@@ -0,0 +1,55 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"sync"
+
+	apiv1 "k8s.io/api/core/v1"
+	clusterstate_utils "k8s.io/autoscaler/cluster-autoscaler/clusterstate/utils"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/events"
+	klog "k8s.io/klog/v2"
+)
+
+var (
+	portalRecorderMutex sync.Mutex
+	portalLogRecorder   *clusterstate_utils.LogEventRecorder
+)
+
+// SetPortalEventRecorder installs the recorder used to surface portal-side
+// failures as events on the status configmap. Before it is set failures are
+// only logged.
+func SetPortalEventRecorder(recorder *clusterstate_utils.LogEventRecorder) {
+	portalRecorderMutex.Lock()
+	defer portalRecorderMutex.Unlock()
+	portalLogRecorder = recorder
+}
+
+// reportPortalFailure logs a failed portal interaction and, when a recorder is
+// installed, emits a PortalUnavailable event so users can self-diagnose
+// without access to the autoscaler logs.
+func reportPortalFailure(action string, detail string) {
+	detail = RedactSecrets(detail)
+	klog.Errorf("Portal %s failed: %s", action, detail)
+	portalRecorderMutex.Lock()
+	recorder := portalLogRecorder
+	portalRecorderMutex.Unlock()
+	if recorder == nil {
+		return
+	}
+	recorder.Eventf(apiv1.EventTypeWarning, events.PortalUnavailable, "action=%s %s", action, detail)
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// portalClient is the HTTP client used for all portal traffic. The access
+// token in transit grants full scaling control over the cluster, so the client
+// always requires TLS 1.2+ and refuses redirects away from HTTPS. PinPortalCA
+// and PinPortalCertFingerprint tighten it further.
+var portalClient = newPortalClient(&tls.Config{MinVersion: tls.VersionTLS12})
+
+func newPortalClient(tlsConfig *tls.Config) *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy:           portalProxyFunc,
+			TLSClientConfig: tlsConfig,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if req.URL.Scheme != "https" {
+				return fmt.Errorf("refusing insecure redirect to %s", req.URL)
+			}
+			return nil
+		},
+	}
+}
+
+// PinPortalCA restricts portal connections to server certificates signed by
+// the CAs in the given PEM file, instead of the system trust store.
+func PinPortalCA(caFile string) error {
+	caPEM, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		return fmt.Errorf("failed to read portal CA file: %v", err)
+	}
+	caPool := x509.NewCertPool()
+	if !caPool.AppendCertsFromPEM(caPEM) {
+		return fmt.Errorf("no certificates found in portal CA file %s", caFile)
+	}
+	portalClient = newPortalClient(&tls.Config{
+		MinVersion: tls.VersionTLS12,
+		RootCAs:    caPool,
+	})
+	return nil
+}
+
+// PinPortalCertFingerprint additionally requires the portal's leaf certificate
+// to have the given SHA-256 fingerprint (hex, optionally colon separated), so
+// even a mis-issued certificate from a trusted CA is rejected.
+func PinPortalCertFingerprint(fingerprint string) error {
+	expected := strings.ToLower(strings.ReplaceAll(fingerprint, ":", ""))
+	if _, err := hex.DecodeString(expected); err != nil || len(expected) != sha256.Size*2 {
+		return fmt.Errorf("invalid SHA-256 certificate fingerprint %q", fingerprint)
+	}
+	tlsConfig := portalClient.Transport.(*http.Transport).TLSClientConfig.Clone()
+	tlsConfig.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return fmt.Errorf("portal presented no certificate")
+		}
+		sum := sha256.Sum256(rawCerts[0])
+		if hex.EncodeToString(sum[:]) != expected {
+			return fmt.Errorf("portal certificate fingerprint mismatch")
+		}
+		return nil
+	}
+	portalClient = newPortalClient(tlsConfig)
+	return nil
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"sync"
+	"time"
+
+	klog "k8s.io/klog/v2"
+)
+
+// PreScaleSignal announces an expected replica increase before the pods exist,
+// so nodes can be provisioned ahead of the burst. External scalers such as HPA
+// controllers or KEDA submit signals through the control API.
+type PreScaleSignal struct {
+	// Pool is the worker pool the replicas will land in. Empty targets the
+	// default pool.
+	Pool string
+	// Replicas is how many additional replicas are expected.
+	Replicas int
+	// CPUMilli and MemoryBytes are the requests of a single replica. When both
+	// are zero the signal is worth one worker node.
+	CPUMilli    int64
+	MemoryBytes int64
+	// Expires is when the signal becomes stale and is dropped unconsumed.
+	Expires time.Time
+}
+
+var (
+	preScaleMutex   sync.Mutex
+	preScaleSignals []PreScaleSignal
+)
+
+// AddPreScaleSignal queues a pre-scale signal for the next autoscaling
+// iteration.
+func AddPreScaleSignal(signal PreScaleSignal) {
+	preScaleMutex.Lock()
+	defer preScaleMutex.Unlock()
+	preScaleSignals = append(preScaleSignals, signal)
+	klog.V(1).Infof("Queued pre-scale signal: pool=%q replicas=%v cpuMilli=%v memoryBytes=%v", signal.Pool, signal.Replicas, signal.CPUMilli, signal.MemoryBytes)
+}
+
+// TakePreScaleSignals returns the queued signals that have not expired and
+// empties the queue.
+func TakePreScaleSignals(now time.Time) []PreScaleSignal {
+	preScaleMutex.Lock()
+	defer preScaleMutex.Unlock()
+	signals := make([]PreScaleSignal, 0, len(preScaleSignals))
+	for _, signal := range preScaleSignals {
+		if !signal.Expires.IsZero() && signal.Expires.Before(now) {
+			klog.V(1).Infof("Dropping expired pre-scale signal for pool %q", signal.Pool)
+			continue
+		}
+		signals = append(signals, signal)
+	}
+	preScaleSignals = nil
+	return signals
+}
@@ -0,0 +1,43 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"sync"
+)
+
+var (
+	readOnlyMutex sync.Mutex
+	readOnlyMode  bool
+)
+
+// SetReadOnlyMode puts the autoscaler in read-only mode. In this mode scaling
+// decisions are still computed and exposed through metrics, status and the
+// audit log, but no portal mutation or node change is performed, so the
+// autoscaler can run with get/list/watch RBAC and no portal credentials.
+func SetReadOnlyMode(enabled bool) {
+	readOnlyMutex.Lock()
+	defer readOnlyMutex.Unlock()
+	readOnlyMode = enabled
+}
+
+// InReadOnlyMode reports whether the autoscaler runs in read-only mode.
+func InReadOnlyMode() bool {
+	readOnlyMutex.Lock()
+	defer readOnlyMutex.Unlock()
+	return readOnlyMode
+}
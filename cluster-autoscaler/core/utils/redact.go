@@ -0,0 +1,62 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+)
+
+var (
+	redactMutex    sync.Mutex
+	redactedValues []string
+)
+
+// credentialPattern matches credential-bearing JSON fields and headers in raw
+// portal payloads, so even tokens the autoscaler never read itself do not end
+// up in logs or snapshots.
+var credentialPattern = regexp.MustCompile(`(?i)("?(?:access_token|bootstrap_token|signing_secret|refresh_token|authorization|password)"?\s*[:=]\s*"?)[^",}\s]*`)
+
+// registerRedactedValue adds a credential to the set of strings RedactSecrets
+// blanks out. Short values are ignored to avoid mangling unrelated text.
+func registerRedactedValue(value string) {
+	if len(value) < 6 {
+		return
+	}
+	redactMutex.Lock()
+	defer redactMutex.Unlock()
+	for _, existing := range redactedValues {
+		if existing == value {
+			return
+		}
+	}
+	redactedValues = append(redactedValues, value)
+}
+
+// RedactSecrets removes known credentials and credential-shaped fields from a
+// string. It is applied to portal payloads and failure details before they are
+// logged, recorded as events or serialized into snapshots.
+func RedactSecrets(s string) string {
+	redactMutex.Lock()
+	values := redactedValues
+	redactMutex.Unlock()
+	for _, value := range values {
+		s = strings.ReplaceAll(s, value, "[REDACTED]")
+	}
+	return credentialPattern.ReplaceAllString(s, "${1}[REDACTED]")
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"sync"
+)
+
+// RefreshRequest asks for a rolling refresh: every worker present when the
+// refresh starts is replaced by a fresh node, a batch at a time, so image or
+// flavor upgrades reuse the autoscaler's drain and cooldown logic.
+type RefreshRequest struct {
+	// Pool restricts the refresh to one worker pool. Empty refreshes all
+	// workers.
+	Pool string
+	// BatchSize is how many nodes are replaced per iteration. Values below 1
+	// default to 1.
+	BatchSize int
+}
+
+var (
+	refreshMutex         sync.Mutex
+	pendingRefresh       *RefreshRequest
+	pendingRefreshCancel bool
+)
+
+// RequestRefresh queues a rolling refresh request. It is consumed by the main
+// loop; a request queued while a refresh is running is ignored there.
+func RequestRefresh(request RefreshRequest) {
+	refreshMutex.Lock()
+	defer refreshMutex.Unlock()
+	pendingRefresh = &request
+}
+
+// TakeRefreshRequest returns the queued refresh request, if any, and clears it.
+func TakeRefreshRequest() (RefreshRequest, bool) {
+	refreshMutex.Lock()
+	defer refreshMutex.Unlock()
+	if pendingRefresh == nil {
+		return RefreshRequest{}, false
+	}
+	request := *pendingRefresh
+	pendingRefresh = nil
+	return request, true
+}
+
+// RequestRefreshCancel asks the main loop to abort the running refresh.
+func RequestRefreshCancel() {
+	refreshMutex.Lock()
+	defer refreshMutex.Unlock()
+	pendingRefreshCancel = true
+}
+
+// TakeRefreshCancel reports whether a cancel was requested and clears it.
+func TakeRefreshCancel() bool {
+	refreshMutex.Lock()
+	defer refreshMutex.Unlock()
+	requested := pendingRefreshCancel
+	pendingRefreshCancel = false
+	return requested
+}
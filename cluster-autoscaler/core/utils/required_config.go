@@ -0,0 +1,65 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+var (
+	graceMutex                 sync.Mutex
+	requiredConfigGracePeriod  = 5 * time.Minute
+	requiredConfigFailingSince time.Time
+)
+
+// SetRequiredConfigGracePeriod sets how long reads of the autoscaling
+// configmap and fke secret may keep failing before the process gives up.
+func SetRequiredConfigGracePeriod(period time.Duration) {
+	graceMutex.Lock()
+	defer graceMutex.Unlock()
+	requiredConfigGracePeriod = period
+}
+
+// requiredConfigError records a failed read of a required kube-system object.
+// Within the grace period it returns an error for the caller to handle, so a
+// momentarily unreadable configmap or secret only skips an iteration. Once
+// reads have been failing for longer than the grace period it kills the
+// process, as the old klog.Fatalf behavior did.
+func requiredConfigError(what string, err error) error {
+	graceMutex.Lock()
+	defer graceMutex.Unlock()
+	now := time.Now()
+	if requiredConfigFailingSince.IsZero() {
+		requiredConfigFailingSince = now
+	}
+	if now.Sub(requiredConfigFailingSince) > requiredConfigGracePeriod {
+		klog.Fatalf("Failed to read %s for longer than the %v grace period: %v", what, requiredConfigGracePeriod, err)
+	}
+	klog.Errorf("Failed to read %s: %v", what, err)
+	return fmt.Errorf("failed to read %s: %v", what, err)
+}
+
+// requiredConfigOK resets the failure tracking after a successful read.
+func requiredConfigOK() {
+	graceMutex.Lock()
+	defer graceMutex.Unlock()
+	requiredConfigFailingSince = time.Time{}
+}
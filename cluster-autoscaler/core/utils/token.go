@@ -0,0 +1,85 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	ctx "context"
+	"sync"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kube_client "k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+var (
+	tokenMutex         sync.RWMutex
+	currentAccessToken string
+)
+
+// SetAccessToken atomically swaps the access token used by portal calls.
+func SetAccessToken(token string) {
+	registerRedactedValue(token)
+	tokenMutex.Lock()
+	defer tokenMutex.Unlock()
+	currentAccessToken = token
+}
+
+// CurrentAccessToken returns the most recently rotated access token, or the
+// empty string when rotation has not been set up.
+func CurrentAccessToken() string {
+	tokenMutex.RLock()
+	defer tokenMutex.RUnlock()
+	return currentAccessToken
+}
+
+// bearerFor builds the Authorization header value for a portal call, preferring
+// the hot-rotated token over the one captured at startup so token rotation by
+// the platform does not require a restart.
+func bearerFor(accessToken string) string {
+	if current := CurrentAccessToken(); current != "" {
+		return "Bearer " + current
+	}
+	return "Bearer " + accessToken
+}
+
+// WatchAccessToken watches the fke-secret and swaps the access token whenever
+// the platform rotates it. It blocks and reconnects forever, so it is meant to
+// run in its own goroutine.
+func WatchAccessToken(kubeclient kube_client.Interface) {
+	for {
+		watcher, err := kubeclient.CoreV1().Secrets("kube-system").Watch(ctx.Background(), metav1.ListOptions{FieldSelector: "metadata.name=fke-secret"})
+		if err != nil {
+			klog.Errorf("Failed to watch fke secret: %v", err)
+			time.Sleep(30 * time.Second)
+			continue
+		}
+		for event := range watcher.ResultChan() {
+			secret, ok := event.Object.(*apiv1.Secret)
+			if !ok {
+				continue
+			}
+			token := string(secret.Data["access_token"])
+			if token != "" && token != CurrentAccessToken() {
+				SetAccessToken(token)
+				klog.V(1).Info("Access token rotated from fke secret")
+			}
+		}
+		klog.V(2).Info("fke secret watch closed, reconnecting")
+	}
+}
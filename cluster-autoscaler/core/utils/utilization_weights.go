@@ -0,0 +1,69 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"strconv"
+
+	kube_client "k8s.io/client-go/kubernetes"
+	klog "k8s.io/klog/v2"
+)
+
+// UtilizationWeights selects and weights the resources that drive the
+// scale-down utilization decision of a worker pool. The decision becomes the
+// maximum of weight * utilization over the resources with a positive weight;
+// a zero weight removes the resource from the decision entirely.
+type UtilizationWeights struct {
+	CPU    float64
+	Memory float64
+	GPU    float64
+}
+
+// GetUtilizationWeights returns the scale-down utilization weights of the
+// given worker pool, as configured in the autoscaling configmap. Keys can be
+// set globally (scale_down_cpu_weight, scale_down_memory_weight,
+// scale_down_gpu_weight) or per pool (e.g. scale_down_gpu_weight.gpu-pool).
+// Missing keys default to a weight of 1. The second return value tells if any
+// of the keys is configured; pools without configuration keep the default
+// utilization decision.
+func GetUtilizationWeights(kubeclient kube_client.Interface, poolName string) (UtilizationWeights, bool) {
+	data, err := autoscalingConfigMapData(kubeclient)
+	if err != nil {
+		klog.Errorf("Failed to get information of autoscaling configmap: %v", err)
+		return UtilizationWeights{}, false
+	}
+	weights := UtilizationWeights{CPU: 1, Memory: 1, GPU: 1}
+	configured := false
+	for key, target := range map[string]*float64{
+		"scale_down_cpu_weight":    &weights.CPU,
+		"scale_down_memory_weight": &weights.Memory,
+		"scale_down_gpu_weight":    &weights.GPU,
+	} {
+		value, found := poolValue(data, key, poolName)
+		if !found {
+			continue
+		}
+		weight, err := strconv.ParseFloat(value, 64)
+		if err != nil || weight < 0 {
+			klog.Errorf("Failed to parse %s value %q in autoscaling configmap: %v", key, value, err)
+			continue
+		}
+		*target = weight
+		configured = true
+	}
+	return weights, configured
+}
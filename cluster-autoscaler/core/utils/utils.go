@@ -18,7 +18,6 @@ package utils
 
 import (
 	"bytes"
-	ctx "context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -27,13 +26,15 @@ import (
 	"net/http"
 	"reflect"
 	"strconv"
+	"strings"
 	"time"
 
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	kube_client "k8s.io/client-go/kubernetes"
 	"k8s.io/klog/v2"
 
 	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/auditlog"
 	"k8s.io/autoscaler/cluster-autoscaler/clusterstate"
 	"k8s.io/autoscaler/cluster-autoscaler/metrics"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/errors"
@@ -41,6 +42,15 @@ import (
 	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
 )
 
+const (
+	// WorkerPoolLabel is the node label holding the name of the FKE worker pool
+	// a node belongs to. Pods can request a pool through a node selector on it.
+	WorkerPoolLabel = "fptcloud.com/worker-pool"
+	// WarmPoolLabel marks a pre-provisioned standby node that is kept cordoned
+	// until a scale up needs near-instant capacity.
+	WarmPoolLabel = "fptcloud.com/warm-pool"
+)
+
 // // GetNodeInfoFromTemplate returns NodeInfo object built base on TemplateNodeInfo returned by NodeGroup.TemplateNodeInfo().
 // func GetNodeInfoFromTemplate(nodeGroup cloudprovider.NodeGroup, daemonsets []*appsv1.DaemonSet, predicateChecker simulator.PredicateChecker, ignoredTaints taints.TaintKeySet) (*schedulerframework.NodeInfo, errors.AutoscalerError) {
 // 	id := nodeGroup.Id()
@@ -161,6 +171,17 @@ func hasHardInterPodAffinity(affinity *apiv1.Affinity) bool {
 	return false
 }
 
+// nodeResourcesFromAllocatable tells if GetNodeCoresAndMemory reads node
+// allocatable instead of capacity.
+var nodeResourcesFromAllocatable = false
+
+// SetNodeResourcesFromAllocatable makes GetNodeCoresAndMemory read node
+// allocatable instead of capacity, so resource accounting excludes what is
+// reserved for the system and the kubelet.
+func SetNodeResourcesFromAllocatable(enabled bool) {
+	nodeResourcesFromAllocatable = enabled
+}
+
 // GetNodeCoresAndMemory extracts cpu and memory resources out of Node object
 func GetNodeCoresAndMemory(node *apiv1.Node) (int64, int64) {
 	cores := getNodeResource(node, apiv1.ResourceCPU)
@@ -170,6 +191,12 @@ func GetNodeCoresAndMemory(node *apiv1.Node) (int64, int64) {
 
 func getNodeResource(node *apiv1.Node, resource apiv1.ResourceName) int64 {
 	nodeCapacity, found := node.Status.Capacity[resource]
+	if nodeResourcesFromAllocatable {
+		// Fall back to capacity for nodes that have not reported allocatable yet.
+		if nodeAllocatable, allocatableFound := node.Status.Allocatable[resource]; allocatableFound {
+			nodeCapacity, found = nodeAllocatable, true
+		}
+	}
 	if !found {
 		return 0
 	}
@@ -219,110 +246,109 @@ func GetOldestCreateTime(pods []*apiv1.Pod) time.Time {
 //}
 
 // GetMinSizeNodeGroup gets min size group
-func GetMinSizeNodeGroup(kubeclient kube_client.Interface) int {
+func GetMinSizeNodeGroup(kubeclient kube_client.Interface) (int, error) {
 	var minSizeNodeGroup int
-	configmaps, err := kubeclient.CoreV1().ConfigMaps("kube-system").Get(ctx.Background(), "autoscaling-configmap", metav1.GetOptions{})
+	data, err := autoscalingConfigMapData(kubeclient)
 	if err != nil {
-		fmt.Println("cannot get information from autoscaling configmap")
-		klog.Fatalf("Failed to get information of autoscaling configmap: %v", err)
+		return 0, requiredConfigError("autoscaling configmap", err)
 	}
-	for k, v := range configmaps.Data {
+	requiredConfigOK()
+	for k, v := range data {
 		if k == "min_node_group_size" {
 			value, err := strconv.Atoi(v)
 			if err != nil {
-				klog.Fatalf("Failed to convert string to integer: %v", err)
+				return 0, fmt.Errorf("failed to parse min_node_group_size %q: %v", v, err)
 			}
 			minSizeNodeGroup = value
 		}
 	}
-	return minSizeNodeGroup
+	return minSizeNodeGroup, nil
 }
 
 // GetMaxSizeNodeGroup gets max size group
-func GetMaxSizeNodeGroup(kubeclient kube_client.Interface) int {
+func GetMaxSizeNodeGroup(kubeclient kube_client.Interface) (int, error) {
 	var maxSizeNodeGroup int
-	configmaps, err := kubeclient.CoreV1().ConfigMaps("kube-system").Get(ctx.Background(), "autoscaling-configmap", metav1.GetOptions{})
+	data, err := autoscalingConfigMapData(kubeclient)
 	if err != nil {
-		fmt.Println("cannot get information from autoscaling configmap")
-		klog.Fatalf("Failed to get information of autoscaling configmap: %v", err)
+		return 0, requiredConfigError("autoscaling configmap", err)
 	}
-	for k, v := range configmaps.Data {
+	requiredConfigOK()
+	for k, v := range data {
 		if k == "max_node_group_size" {
 			value, err := strconv.Atoi(v)
 			if err != nil {
-				klog.Fatalf("Failed to convert string to integer: %v", err)
+				return 0, fmt.Errorf("failed to parse max_node_group_size %q: %v", v, err)
 			}
 			maxSizeNodeGroup = value
 		}
 	}
-	return maxSizeNodeGroup
+	return maxSizeNodeGroup, nil
 }
 
 // GetEnv gets environment (staging/pilot/production)
-func GetEnv(kubeclient kube_client.Interface) string {
+func GetEnv(kubeclient kube_client.Interface) (string, error) {
 	var env string
-	configmaps, err := kubeclient.CoreV1().ConfigMaps("kube-system").Get(ctx.Background(), "autoscaling-configmap", metav1.GetOptions{})
+	data, err := autoscalingConfigMapData(kubeclient)
 	if err != nil {
-		fmt.Println("cannot get information from autoscaling configmap")
-		klog.Fatalf("Failed to get information of autoscaling configmap: %v", err)
+		return "", requiredConfigError("autoscaling configmap", err)
 	}
-	for k, v := range configmaps.Data {
+	requiredConfigOK()
+	for k, v := range data {
 		if k == "env" {
-			if err != nil {
-				klog.Fatalf("Failed to convert string to integer: %v", err)
-			}
 			env = v
 		}
 	}
-	return env
+	return env, nil
 }
 
 // GetAccessToken gets access token of FPTCloud
-func GetAccessToken(kubeclient kube_client.Interface) string {
+func GetAccessToken(kubeclient kube_client.Interface) (string, error) {
 	var accessToken string
-	secret, err := kubeclient.CoreV1().Secrets("kube-system").Get(ctx.Background(), "fke-secret", metav1.GetOptions{})
+	data, err := fkeSecretData(kubeclient)
 	if err != nil {
-		fmt.Println("cannot get information from fke secret")
-		klog.Fatalf("Failed to get information of fke secret: %v", err)
+		return "", requiredConfigError("fke secret", err)
 	}
-	for k, v := range secret.Data {
+	requiredConfigOK()
+	for k, v := range data {
 		if k == "access_token" {
 			accessToken = string(v)
 		}
 	}
-	return accessToken
+	registerRedactedValue(accessToken)
+	return accessToken, nil
 }
 
 // GetVPCId gets vpc_id of customer
-func GetVPCId(kubeclient kube_client.Interface) string {
+func GetVPCId(kubeclient kube_client.Interface) (string, error) {
 	var vpcID string
-	secret, err := kubeclient.CoreV1().Secrets("kube-system").Get(ctx.Background(), "fke-secret", metav1.GetOptions{})
+	data, err := fkeSecretData(kubeclient)
 	if err != nil {
-		fmt.Println("cannot get information from fke secret")
-		klog.Fatalf("Failed to get information of fke secret: %v", err)
+		return "", requiredConfigError("fke secret", err)
 	}
-	for k, v := range secret.Data {
+	requiredConfigOK()
+	for k, v := range data {
 		if k == "vpc_id" {
 			vpcID = string(v)
 		}
 	}
-	return vpcID
+	registerRedactedValue(vpcID)
+	return vpcID, nil
 }
 
 // GetClusterID gets cluster_id info of K8S cluster
-func GetClusterID(kubeclient kube_client.Interface) string {
+func GetClusterID(kubeclient kube_client.Interface) (string, error) {
 	var clusterID string
-	secret, err := kubeclient.CoreV1().Secrets("kube-system").Get(ctx.Background(), "fke-secret", metav1.GetOptions{})
+	data, err := fkeSecretData(kubeclient)
 	if err != nil {
-		fmt.Println("cannot get information from fke secret")
-		klog.Fatalf("Failed to get information of fke secret: %v", err)
+		return "", requiredConfigError("fke secret", err)
 	}
-	for k, v := range secret.Data {
+	requiredConfigOK()
+	for k, v := range data {
 		if k == "cluster_id" {
 			clusterID = string(v)
 		}
 	}
-	return clusterID
+	return clusterID, nil
 }
 
 type Cluster struct {
@@ -362,9 +388,9 @@ func GetIDCluster(domainAPI string, vpcID string, accessToken string, clusterID
 	var k8sCluster Cluster
 	url := domainAPI + "/api/v1/vmware/vpc/" + vpcID + "/kubernetes?page=1&page_size=25"
 	token := accessToken
-	client := &http.Client{}
+	client := portalClient
 	req, _ := http.NewRequest("GET", url, nil)
-	req.Header.Add("Authorization", "Bearer "+token)
+	req.Header.Add("Authorization", bearerFor(token))
 	resp, err := client.Do(req)
 	if err != nil {
 		log.Println(err)
@@ -402,9 +428,9 @@ func CheckStatusCluster(domainAPI string, vpcID string, accessToken string, clus
 	var k8sCluster Cluster
 	url := domainAPI + "/api/v1/vmware/vpc/" + vpcID + "/kubernetes?page=1&page_size=25"
 	token := accessToken
-	client := &http.Client{}
+	client := portalClient
 	req, _ := http.NewRequest("GET", url, nil)
-	req.Header.Add("Authorization", "Bearer "+token)
+	req.Header.Add("Authorization", bearerFor(token))
 	resp, err := client.Do(req)
 	if err != nil {
 		log.Println(err)
@@ -446,9 +472,9 @@ func CheckErrorStatusCluster(domainAPT string, vpcID string, accessToken string,
 	var k8sCluster Cluster
 	url := domainAPT + "/api/v1/vmware/vpc/" + vpcID + "/kubernetes?page=1&page_size=25"
 	token := accessToken
-	client := &http.Client{}
+	client := portalClient
 	req, _ := http.NewRequest("GET", url, nil)
-	req.Header.Add("Authorization", "Bearer "+token)
+	req.Header.Add("Authorization", bearerFor(token))
 	resp, err := client.Do(req)
 	if err != nil {
 		log.Println(err)
@@ -473,6 +499,7 @@ func CheckErrorStatusCluster(domainAPT string, vpcID string, accessToken string,
 		if cluster.ClusterID == clusterID {
 			if cluster.Status == "ERROR" {
 				isError = true
+				reportPortalFailure("cluster-status", fmt.Sprintf("cluster=%s status=ERROR errorMessage=%q processingMessage=%q", clusterID, cluster.ErrorMessage, cluster.ProcessingMess))
 			}
 		}
 	}
@@ -482,31 +509,202 @@ func CheckErrorStatusCluster(domainAPT string, vpcID string, accessToken string,
 	return isError
 }
 
+// GetKubeletReservedResources returns the cpu (milli) and memory (milli) the
+// kubelet reserves for itself and system daemons on a worker node of the given
+// pool, as configured in the autoscaling configmap. Keys can be set globally
+// (e.g. kube_reserved_cpu: 100m) or per pool (e.g. kube_reserved_cpu.gpu-pool).
+// Missing keys mean no reservation.
+func GetKubeletReservedResources(kubeclient kube_client.Interface, poolName string) (cpuMilli int64, memoryMilli int64) {
+	data, err := autoscalingConfigMapData(kubeclient)
+	if err != nil {
+		klog.Errorf("Failed to get information of autoscaling configmap: %v", err)
+		return 0, 0
+	}
+	for _, key := range []string{"kube_reserved_cpu", "system_reserved_cpu"} {
+		cpuMilli += reservedQuantityMilli(data, key, poolName)
+	}
+	for _, key := range []string{"kube_reserved_memory", "system_reserved_memory"} {
+		memoryMilli += reservedQuantityMilli(data, key, poolName)
+	}
+	return cpuMilli, memoryMilli
+}
+
+// reservedQuantityMilli reads a reserved resource quantity from the configmap
+// data, preferring the per pool key over the global one.
+func reservedQuantityMilli(data map[string]string, key string, poolName string) int64 {
+	var value string
+	var found bool
+	if poolName != "" {
+		value, found = data[key+"."+poolName]
+	}
+	if !found {
+		value, found = data[key]
+	}
+	if !found {
+		return 0
+	}
+	quantity, err := resource.ParseQuantity(value)
+	if err != nil {
+		klog.Errorf("Failed to parse %s value %q in autoscaling configmap: %v", key, value, err)
+		return 0
+	}
+	return quantity.MilliValue()
+}
+
+// GetIgnoredTaints returns the taint keys and taint key prefixes to ignore
+// when considering nodes and node templates, as configured in the autoscaling
+// configmap under the comma-separated ignored_taints and
+// startup_taint_prefixes keys. Missing keys mean nothing extra is ignored.
+func GetIgnoredTaints(kubeclient kube_client.Interface) (taintKeys []string, taintPrefixes []string) {
+	data, err := autoscalingConfigMapData(kubeclient)
+	if err != nil {
+		klog.Errorf("Failed to get information of autoscaling configmap: %v", err)
+		return nil, nil
+	}
+	return splitConfigList(data["ignored_taints"]), splitConfigList(data["startup_taint_prefixes"])
+}
+
+// GetNamespacePriorityCutoffs returns the per namespace overrides of the
+// expendable pods priority cutoff, as configured in the autoscaling configmap
+// under expendable_pods_priority_cutoff.<namespace> keys. Missing keys mean
+// the global cutoff applies everywhere.
+func GetNamespacePriorityCutoffs(kubeclient kube_client.Interface) map[string]int {
+	data, err := autoscalingConfigMapData(kubeclient)
+	if err != nil {
+		klog.Errorf("Failed to get information of autoscaling configmap: %v", err)
+		return nil
+	}
+	const cutoffKeyPrefix = "expendable_pods_priority_cutoff."
+	cutoffs := map[string]int{}
+	for k, v := range data {
+		if !strings.HasPrefix(k, cutoffKeyPrefix) {
+			continue
+		}
+		namespace := strings.TrimPrefix(k, cutoffKeyPrefix)
+		if namespace == "" {
+			continue
+		}
+		cutoff, err := strconv.Atoi(v)
+		if err != nil {
+			klog.Errorf("Failed to parse %s value %q in autoscaling configmap: %v", k, v, err)
+			continue
+		}
+		cutoffs[namespace] = cutoff
+	}
+	return cutoffs
+}
+
+// splitConfigList splits a comma-separated configmap value, dropping empty
+// entries and surrounding whitespace.
+func splitConfigList(value string) []string {
+	var entries []string
+	for _, entry := range strings.Split(value, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// GetWorkerHourlyPrices returns the estimated hourly price of a worker node in
+// each pool, as configured in the autoscaling configmap. The price of the
+// default pool is stored under the worker_price_per_hour key, per pool prices
+// under worker_price_per_hour.<pool>. Missing keys mean the price is unknown.
+func GetWorkerHourlyPrices(kubeclient kube_client.Interface) map[string]float64 {
+	prices := map[string]float64{}
+	data, err := autoscalingConfigMapData(kubeclient)
+	if err != nil {
+		klog.Errorf("Failed to get information of autoscaling configmap: %v", err)
+		return prices
+	}
+	const priceKey = "worker_price_per_hour"
+	for k, v := range data {
+		var pool string
+		if k == priceKey {
+			pool = ""
+		} else if strings.HasPrefix(k, priceKey+".") {
+			pool = strings.TrimPrefix(k, priceKey+".")
+		} else {
+			continue
+		}
+		price, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			klog.Errorf("Failed to parse %s value %q in autoscaling configmap: %v", k, v, err)
+			continue
+		}
+		prices[pool] = price
+	}
+	return prices
+}
+
+// WorkerHourlyPrice returns the hourly price of a worker node in the given
+// pool, falling back to the default pool price when the pool has no own entry.
+func WorkerHourlyPrice(prices map[string]float64, poolName string) float64 {
+	if price, found := prices[poolName]; found {
+		return price
+	}
+	return prices[""]
+}
+
 // PerformScaleUp performs scale up
 func PerformScaleUp(domainAPI string, vpcID string, accessToken string, workerCount int, idCluster string, clusterIDPortal string) {
+	PerformScaleUpForPool(domainAPI, vpcID, accessToken, workerCount, idCluster, clusterIDPortal, "")
+}
+
+// PerformScaleUpForPool performs scale up of the given worker pool. An empty
+// pool name targets the default worker pool.
+func PerformScaleUpForPool(domainAPI string, vpcID string, accessToken string, workerCount int, idCluster string, clusterIDPortal string, poolName string) {
+	if InReadOnlyMode() {
+		klog.V(1).Infof("Read-only mode: suppressed portal scale up of %v workers in pool %q", workerCount, poolName)
+		auditlog.Log("suppressed-scale-up", map[string]string{
+			"cluster":     clusterIDPortal,
+			"workerPool":  poolName,
+			"workerCount": strconv.Itoa(workerCount),
+		})
+		return
+	}
 	url := domainAPI + "/api/v1/vmware/vpc/" + vpcID + "/cluster/" + idCluster + "/scale-cluster"
-	postBody, _ := json.Marshal(map[string]string{
+	requestBody := map[string]string{
 		"cluster_id":   clusterIDPortal,
 		"scale_type":   "up",
 		"worker_count": strconv.Itoa(workerCount),
-	})
+	}
+	if poolName != "" {
+		requestBody["worker_pool_name"] = poolName
+	}
+	postBody, _ := json.Marshal(requestBody)
 	responseBody := bytes.NewBuffer(postBody)
-	var bearer = "Bearer " + accessToken
-	client := &http.Client{}
+	var bearer = bearerFor(accessToken)
+	client := portalClient
 	req, _ := http.NewRequest("POST", url, responseBody)
 	req.Header.Add("Authorization", bearer)
 	req.Header.Set("Content-Type", "application/json")
+	signPortalRequest(req, postBody)
 	resp, err := client.Do(req)
 	if err != nil {
-		log.Println(err)
+		reportPortalFailure("scale-up", fmt.Sprintf("cluster=%s workerPool=%s error=%v", clusterIDPortal, poolName, err))
+		return
 	}
 	defer resp.Body.Close()
-	log.Println(resp)
+	log.Println("Portal scale up response status:", resp.Status)
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		log.Println("Error while reading the response bytes:", err)
 	}
-	log.Println(string([]byte(body)))
+	log.Println(RedactSecrets(string(body)))
+	if resp.StatusCode >= 400 {
+		// The portal rejected the request, so no nodes were added - keep the
+		// rejection out of the cost ledger and node counters.
+		reportPortalFailure("scale-up", fmt.Sprintf("cluster=%s workerPool=%s status=%q response=%q", clusterIDPortal, poolName, resp.Status, strings.TrimSpace(string(body))))
+	} else {
+		recordNodesAdded(poolName, workerCount)
+	}
+	auditlog.Log("portal-scale-up", map[string]string{
+		"cluster":     clusterIDPortal,
+		"workerPool":  poolName,
+		"workerCount": strconv.Itoa(workerCount),
+		"status":      resp.Status,
+	})
 	// fmt.Println("response Status:", resp.Status)
 	// fmt.Println("response Headers:", resp.Header)
 	// fmt.Println("response Body:", string(body))
@@ -514,6 +712,14 @@ func PerformScaleUp(domainAPI string, vpcID string, accessToken string, workerCo
 
 // PerformScaleDown performs scale down
 func PerformScaleDown(domainAPI string, vpcID string, token string, workerCount int, idCluster string, clusterIDPortal string) {
+	if InReadOnlyMode() {
+		klog.V(1).Infof("Read-only mode: suppressed portal scale down of %v workers", workerCount)
+		auditlog.Log("suppressed-scale-down", map[string]string{
+			"cluster":     clusterIDPortal,
+			"workerCount": strconv.Itoa(workerCount),
+		})
+		return
+	}
 	url := domainAPI + "/api/v1/vmware/vpc/" + vpcID + "/cluster/" + idCluster + "/scale-cluster"
 	postBody, _ := json.Marshal(map[string]string{
 		"cluster_id":   clusterIDPortal,
@@ -521,27 +727,88 @@ func PerformScaleDown(domainAPI string, vpcID string, token string, workerCount
 		"worker_count": strconv.Itoa(workerCount),
 	})
 	responseBody := bytes.NewBuffer(postBody)
-	var bearer = "Bearer " + token
-	client := &http.Client{}
+	var bearer = bearerFor(token)
+	client := portalClient
 	req, _ := http.NewRequest("POST", url, responseBody)
 	req.Header.Add("Authorization", bearer)
 	req.Header.Set("Content-Type", "application/json")
+	signPortalRequest(req, postBody)
 	resp, err := client.Do(req)
 	if err != nil {
-		log.Println(err)
+		reportPortalFailure("scale-down", fmt.Sprintf("cluster=%s error=%v", clusterIDPortal, err))
+		return
 	}
 	defer resp.Body.Close()
-	//log.Println(resp)
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		log.Println("Error while reading the response bytes:", err)
 	}
-	log.Println(string([]byte(body)))
+	log.Println(RedactSecrets(string(body)))
+	if resp.StatusCode >= 400 {
+		// A rejected scale down removed nothing, so the ledger and node
+		// counters must not book it.
+		reportPortalFailure("scale-down", fmt.Sprintf("cluster=%s status=%q response=%q", clusterIDPortal, resp.Status, strings.TrimSpace(string(body))))
+	} else {
+		recordNodesRemoved("", workerCount)
+	}
+	auditlog.Log("portal-scale-down", map[string]string{
+		"cluster":     clusterIDPortal,
+		"workerCount": strconv.Itoa(workerCount),
+		"status":      resp.Status,
+	})
 	//fmt.Println("response Status:", resp.Status)
 	//fmt.Println("response Headers:", resp.Header)
 	//fmt.Println("response Body:", string(body))
 }
 
+// PerformDeleteWorkerNode asks the portal to delete one specific worker node,
+// regardless of its position in the numbering. Used for replacing unhealthy
+// nodes, where the ordinary highest-number-first scale down cannot target the
+// broken machine.
+func PerformDeleteWorkerNode(domainAPI string, vpcID string, accessToken string, idCluster string, clusterIDPortal string, workerName string) {
+	if InReadOnlyMode() {
+		klog.V(1).Infof("Read-only mode: suppressed portal deletion of worker node %s", workerName)
+		auditlog.Log("suppressed-delete-worker-node", map[string]string{
+			"cluster": clusterIDPortal,
+			"node":    workerName,
+		})
+		return
+	}
+	url := domainAPI + "/api/v1/vmware/vpc/" + vpcID + "/cluster/" + idCluster + "/worker/delete"
+	postBody, _ := json.Marshal(map[string]string{
+		"cluster_id":  clusterIDPortal,
+		"worker_name": workerName,
+	})
+	responseBody := bytes.NewBuffer(postBody)
+	var bearer = bearerFor(accessToken)
+	client := portalClient
+	req, _ := http.NewRequest("POST", url, responseBody)
+	req.Header.Add("Authorization", bearer)
+	req.Header.Set("Content-Type", "application/json")
+	signPortalRequest(req, postBody)
+	resp, err := client.Do(req)
+	if err != nil {
+		reportPortalFailure("delete-worker-node", fmt.Sprintf("cluster=%s node=%s error=%v", clusterIDPortal, workerName, err))
+		return
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		log.Println("Error while reading the response bytes:", err)
+	}
+	log.Println(RedactSecrets(string(body)))
+	if resp.StatusCode >= 400 {
+		reportPortalFailure("delete-worker-node", fmt.Sprintf("cluster=%s node=%s status=%q response=%q", clusterIDPortal, workerName, resp.Status, strings.TrimSpace(string(body))))
+	} else {
+		recordNodesRemoved("", 1)
+	}
+	auditlog.Log("portal-delete-worker-node", map[string]string{
+		"cluster": clusterIDPortal,
+		"node":    workerName,
+		"status":  resp.Status,
+	})
+}
+
 // GetDomainApiConformEnv gets url conform environment
 func GetDomainApiConformEnv(env string) string {
 	var domainAPI string
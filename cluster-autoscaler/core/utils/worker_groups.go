@@ -0,0 +1,203 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/autoscaler/cluster-autoscaler/auditlog"
+	kube_client "k8s.io/client-go/kubernetes"
+	klog "k8s.io/klog/v2"
+)
+
+// Flavor describes a worker flavor the portal can provision, as configured in
+// the autoscaling configmap. Each flavor is described by the keys
+// flavor.<name>.cpu, flavor.<name>.memory and flavor.<name>.price_per_hour.
+type Flavor struct {
+	Name         string
+	CPUMilli     int64
+	MemoryBytes  int64
+	PricePerHour float64
+}
+
+// GetFlavorCatalog returns the worker flavors configured in the autoscaling
+// configmap, sorted by hourly price ascending. Flavors with an unparsable cpu
+// or memory are skipped; a missing price means the price is unknown (zero).
+func GetFlavorCatalog(kubeclient kube_client.Interface) []Flavor {
+	data, err := autoscalingConfigMapData(kubeclient)
+	if err != nil {
+		klog.Errorf("Failed to get information of autoscaling configmap: %v", err)
+		return nil
+	}
+	const flavorPrefix = "flavor."
+	byName := map[string]*Flavor{}
+	flavorFor := func(name string) *Flavor {
+		if _, found := byName[name]; !found {
+			byName[name] = &Flavor{Name: name}
+		}
+		return byName[name]
+	}
+	for key, value := range data {
+		if !strings.HasPrefix(key, flavorPrefix) {
+			continue
+		}
+		rest := strings.TrimPrefix(key, flavorPrefix)
+		idx := strings.LastIndex(rest, ".")
+		if idx <= 0 {
+			continue
+		}
+		name, attribute := rest[:idx], rest[idx+1:]
+		switch attribute {
+		case "cpu", "memory":
+			quantity, err := resource.ParseQuantity(value)
+			if err != nil {
+				klog.Errorf("Failed to parse %s value %q in autoscaling configmap: %v", key, value, err)
+				continue
+			}
+			if attribute == "cpu" {
+				flavorFor(name).CPUMilli = quantity.MilliValue()
+			} else {
+				flavorFor(name).MemoryBytes = quantity.Value()
+			}
+		case "price_per_hour":
+			price, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				klog.Errorf("Failed to parse %s value %q in autoscaling configmap: %v", key, value, err)
+				continue
+			}
+			flavorFor(name).PricePerHour = price
+		}
+	}
+	flavors := make([]Flavor, 0, len(byName))
+	for _, flavor := range byName {
+		if flavor.CPUMilli <= 0 || flavor.MemoryBytes <= 0 {
+			klog.Warningf("Skipping flavor %q with incomplete cpu/memory in autoscaling configmap", flavor.Name)
+			continue
+		}
+		flavors = append(flavors, *flavor)
+	}
+	sort.Slice(flavors, func(i, j int) bool { return flavors[i].PricePerHour < flavors[j].PricePerHour })
+	return flavors
+}
+
+// PerformCreateWorkerGroup asks the portal to create a new worker group with
+// the given flavor and initial worker count.
+func PerformCreateWorkerGroup(domainAPI string, vpcID string, accessToken string, idCluster string, clusterIDPortal string, poolName string, flavorName string, workerCount int) {
+	if InReadOnlyMode() {
+		klog.V(1).Infof("Read-only mode: suppressed portal creation of worker group %q with flavor %q and %v workers", poolName, flavorName, workerCount)
+		auditlog.Log("suppressed-create-worker-group", map[string]string{
+			"cluster":     clusterIDPortal,
+			"workerPool":  poolName,
+			"flavor":      flavorName,
+			"workerCount": strconv.Itoa(workerCount),
+		})
+		return
+	}
+	url := domainAPI + "/api/v1/vmware/vpc/" + vpcID + "/cluster/" + idCluster + "/worker-group"
+	postBody, _ := json.Marshal(map[string]string{
+		"cluster_id":       clusterIDPortal,
+		"worker_pool_name": poolName,
+		"flavor":           flavorName,
+		"worker_count":     strconv.Itoa(workerCount),
+	})
+	responseBody := bytes.NewBuffer(postBody)
+	var bearer = bearerFor(accessToken)
+	client := portalClient
+	req, _ := http.NewRequest("POST", url, responseBody)
+	req.Header.Add("Authorization", bearer)
+	req.Header.Set("Content-Type", "application/json")
+	signPortalRequest(req, postBody)
+	resp, err := client.Do(req)
+	if err != nil {
+		reportPortalFailure("create-worker-group", fmt.Sprintf("cluster=%s workerPool=%s error=%v", clusterIDPortal, poolName, err))
+		return
+	}
+	defer resp.Body.Close()
+	log.Println("Portal create worker group response status:", resp.Status)
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		log.Println("Error while reading the response bytes:", err)
+	}
+	log.Println(RedactSecrets(string(body)))
+	if resp.StatusCode >= 400 {
+		// The portal rejected the request, so no workers were created - keep
+		// the rejection out of the cost ledger and node counters.
+		reportPortalFailure("create-worker-group", fmt.Sprintf("cluster=%s workerPool=%s status=%q response=%q", clusterIDPortal, poolName, resp.Status, strings.TrimSpace(string(body))))
+	} else {
+		recordNodesAdded(poolName, workerCount)
+	}
+	auditlog.Log("portal-create-worker-group", map[string]string{
+		"cluster":     clusterIDPortal,
+		"workerPool":  poolName,
+		"flavor":      flavorName,
+		"workerCount": strconv.Itoa(workerCount),
+		"status":      resp.Status,
+	})
+}
+
+// PerformDeleteWorkerGroup asks the portal to delete the given worker group.
+func PerformDeleteWorkerGroup(domainAPI string, vpcID string, accessToken string, idCluster string, clusterIDPortal string, poolName string) {
+	if InReadOnlyMode() {
+		klog.V(1).Infof("Read-only mode: suppressed portal deletion of worker group %q", poolName)
+		auditlog.Log("suppressed-delete-worker-group", map[string]string{
+			"cluster":    clusterIDPortal,
+			"workerPool": poolName,
+		})
+		return
+	}
+	url := domainAPI + "/api/v1/vmware/vpc/" + vpcID + "/cluster/" + idCluster + "/worker-group/delete"
+	postBody, _ := json.Marshal(map[string]string{
+		"cluster_id":       clusterIDPortal,
+		"worker_pool_name": poolName,
+	})
+	responseBody := bytes.NewBuffer(postBody)
+	var bearer = bearerFor(accessToken)
+	client := portalClient
+	req, _ := http.NewRequest("POST", url, responseBody)
+	req.Header.Add("Authorization", bearer)
+	req.Header.Set("Content-Type", "application/json")
+	signPortalRequest(req, postBody)
+	resp, err := client.Do(req)
+	if err != nil {
+		reportPortalFailure("delete-worker-group", fmt.Sprintf("cluster=%s workerPool=%s error=%v", clusterIDPortal, poolName, err))
+		return
+	}
+	defer resp.Body.Close()
+	log.Println("Portal delete worker group response status:", resp.Status)
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		log.Println("Error while reading the response bytes:", err)
+	}
+	log.Println(RedactSecrets(string(body)))
+	if resp.StatusCode >= 400 {
+		reportPortalFailure("delete-worker-group", fmt.Sprintf("cluster=%s workerPool=%s status=%q response=%q", clusterIDPortal, poolName, resp.Status, strings.TrimSpace(string(body))))
+	}
+	auditlog.Log("portal-delete-worker-group", map[string]string{
+		"cluster":    clusterIDPortal,
+		"workerPool": poolName,
+		"status":     resp.Status,
+	})
+}
@@ -0,0 +1,51 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"strings"
+
+	apiv1 "k8s.io/api/core/v1"
+)
+
+const (
+	masterRoleLabel       = "node-role.kubernetes.io/master"
+	controlPlaneRoleLabel = "node-role.kubernetes.io/control-plane"
+)
+
+// IsWorkerNode reports whether a node belongs to the autoscaled worker pool.
+// Nodes carrying control-plane labels or taints are never workers, regardless
+// of their name, so a master with an unlucky hostname is not counted or scaled
+// down. Nodes labelled with the FKE worker pool label are always workers; for
+// unlabelled nodes the naming convention is used as a fallback.
+func IsWorkerNode(node *apiv1.Node) bool {
+	if _, found := node.Labels[masterRoleLabel]; found {
+		return false
+	}
+	if _, found := node.Labels[controlPlaneRoleLabel]; found {
+		return false
+	}
+	for _, taint := range node.Spec.Taints {
+		if taint.Key == masterRoleLabel || taint.Key == controlPlaneRoleLabel {
+			return false
+		}
+	}
+	if _, found := node.Labels[WorkerPoolLabel]; found {
+		return true
+	}
+	return strings.Contains(node.Name, "worker")
+}
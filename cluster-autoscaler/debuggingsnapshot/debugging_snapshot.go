@@ -18,13 +18,31 @@ package debuggingsnapshot
 
 import (
 	"encoding/json"
+	"fmt"
 	"time"
 
 	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1beta1"
+	core_utils "k8s.io/autoscaler/cluster-autoscaler/core/utils"
+	"k8s.io/autoscaler/cluster-autoscaler/expander/audit"
 	"k8s.io/klog/v2"
 	"k8s.io/kubernetes/pkg/scheduler/framework"
 )
 
+// Snapshot format versions. The schema is additive only: fields may be added
+// in a new version but never renamed or repurposed, so decoders built for an
+// older version keep working and old snapshots remain readable.
+const (
+	// SnapshotFormatV1 is the original, unversioned snapshot format. Dumps
+	// without a FormatVersion field are decoded as v1.
+	SnapshotFormatV1 = "v1"
+	// SnapshotFormatV2 added the FormatVersion field itself and the expander
+	// decisions.
+	SnapshotFormatV2 = "v2"
+	// SnapshotFormatVersion is the version written by this binary.
+	SnapshotFormatVersion = SnapshotFormatV2
+)
+
 // ClusterNode captures a single entity of nodeInfo. i.e. Node specs and all the pods on that node.
 type ClusterNode struct {
 	Node *v1.Node  `json:"Node"`
@@ -42,6 +60,26 @@ type DebuggingSnapshot interface {
 	// SetTemplateNodes is a setter for all the TemplateNodes present in the cluster
 	// incl. templates for which there are no nodes
 	SetTemplateNodes(map[string]*framework.NodeInfo)
+	// SetExpanderDecisions is a setter for the recent expander decisions
+	SetExpanderDecisions([]audit.Decision)
+	// SetPodDisruptionBudgets is a setter for the PDBs in the cluster,
+	// which constrain which nodes scale down may drain
+	SetPodDisruptionBudgets([]*policyv1.PodDisruptionBudget)
+	// SetFailedSchedulingEvents is a setter for the recent FailedScheduling
+	// events, explaining why the pending pods couldn't be placed
+	SetFailedSchedulingEvents([]v1.Event)
+	// SetPendingPodConditions is a setter for the scheduling conditions of
+	// the pods the autoscaler is trying to help
+	SetPendingPodConditions([]*v1.Pod)
+	// SetSchedulabilityReports is a setter for the per-pending-pod
+	// simulation outcomes against every node in the cluster
+	SetSchedulabilityReports([]*PodSchedulabilityReport)
+	// SetPoolEstimations is a setter for the per-pool node count estimations
+	// for the pending pods
+	SetPoolEstimations([]PoolEstimation)
+	// SetFailureReason tags the snapshot with the scale operation failure
+	// that triggered its capture
+	SetFailureReason(string)
 	// SetErrorMessage sets the error message in the snapshot
 	SetErrorMessage(string)
 	// SetEndTimestamp sets the timestamp in the snapshot,
@@ -61,12 +99,122 @@ type DebuggingSnapshot interface {
 // Please add all new output fields in this struct. This is to make the data
 // encoding/decoding easier as the single object going into the decoder
 type DebuggingSnapshotImpl struct {
+	FormatVersion                 string                  `json:"FormatVersion,omitempty"`
+	FailureReason                 string                  `json:"FailureReason,omitempty"`
 	NodeList                      []*ClusterNode          `json:"NodeList"`
 	UnscheduledPodsCanBeScheduled []*v1.Pod               `json:"UnscheduledPodsCanBeScheduled"`
 	Error                         string                  `json:"Error,omitempty"`
 	StartTimestamp                time.Time               `json:"StartTimestamp"`
 	EndTimestamp                  time.Time               `json:"EndTimestamp"`
 	TemplateNodes                 map[string]*ClusterNode `json:"TemplateNodes"`
+	ExpanderDecisions             []audit.Decision        `json:"ExpanderDecisions,omitempty"`
+
+	PodDisruptionBudgets   []*policyv1.PodDisruptionBudget `json:"PodDisruptionBudgets,omitempty"`
+	FailedSchedulingEvents []*v1.Event                     `json:"FailedSchedulingEvents,omitempty"`
+	PendingPodConditions   []*PendingPodConditions         `json:"PendingPodConditions,omitempty"`
+	SchedulabilityReports  []*PodSchedulabilityReport      `json:"SchedulabilityReports,omitempty"`
+	PoolEstimations        []PoolEstimation                `json:"PoolEstimations,omitempty"`
+}
+
+// NodePredicateFailure tells why one node was rejected for a pending pod by
+// the scheduler predicate simulation.
+type NodePredicateFailure struct {
+	Node    string   `json:"Node"`
+	Reasons []string `json:"Reasons,omitempty"`
+}
+
+// PodSchedulabilityReport is the simulation outcome for a single pending pod:
+// every node it was rejected for and why. Nodes absent from NodeFailures
+// passed the predicates.
+type PodSchedulabilityReport struct {
+	Pod          string                 `json:"Pod"`
+	Pool         string                 `json:"Pool,omitempty"`
+	NodeFailures []NodePredicateFailure `json:"NodeFailures,omitempty"`
+}
+
+// PoolEstimation is the estimated number of nodes a worker pool needs for its
+// share of the pending pods.
+type PoolEstimation struct {
+	Pool               string `json:"Pool"`
+	PendingPods        int    `json:"PendingPods"`
+	EstimatedNodeCount int    `json:"EstimatedNodeCount"`
+}
+
+// PendingPodConditions captures the scheduling-relevant status of a single
+// pending pod without duplicating the whole pod spec in the snapshot.
+type PendingPodConditions struct {
+	Name       string            `json:"Name"`
+	Namespace  string            `json:"Namespace"`
+	Conditions []v1.PodCondition `json:"Conditions,omitempty"`
+}
+
+// SetExpanderDecisions is the setter for ExpanderDecisions
+func (s *DebuggingSnapshotImpl) SetExpanderDecisions(decisions []audit.Decision) {
+	s.ExpanderDecisions = decisions
+}
+
+// SetFailureReason is the setter for FailureReason
+func (s *DebuggingSnapshotImpl) SetFailureReason(reason string) {
+	s.FailureReason = reason
+}
+
+// SetPodDisruptionBudgets is the setter for PodDisruptionBudgets
+func (s *DebuggingSnapshotImpl) SetPodDisruptionBudgets(pdbs []*policyv1.PodDisruptionBudget) {
+	if pdbs == nil {
+		return
+	}
+
+	s.PodDisruptionBudgets = nil
+	for _, pdb := range pdbs {
+		s.PodDisruptionBudgets = append(s.PodDisruptionBudgets, pdb.DeepCopy())
+	}
+}
+
+// SetFailedSchedulingEvents is the setter for FailedSchedulingEvents
+func (s *DebuggingSnapshotImpl) SetFailedSchedulingEvents(events []v1.Event) {
+	if events == nil {
+		return
+	}
+
+	s.FailedSchedulingEvents = nil
+	for i := range events {
+		s.FailedSchedulingEvents = append(s.FailedSchedulingEvents, events[i].DeepCopy())
+	}
+}
+
+// SetPendingPodConditions is the setter for PendingPodConditions
+func (s *DebuggingSnapshotImpl) SetPendingPodConditions(podList []*v1.Pod) {
+	if podList == nil {
+		return
+	}
+
+	s.PendingPodConditions = nil
+	for _, pod := range podList {
+		conditions := &PendingPodConditions{
+			Name:      pod.Name,
+			Namespace: pod.Namespace,
+		}
+		for _, condition := range pod.Status.Conditions {
+			conditions.Conditions = append(conditions.Conditions, *condition.DeepCopy())
+		}
+		s.PendingPodConditions = append(s.PendingPodConditions, conditions)
+	}
+}
+
+// SetSchedulabilityReports is the setter for SchedulabilityReports
+func (s *DebuggingSnapshotImpl) SetSchedulabilityReports(reports []*PodSchedulabilityReport) {
+	if reports == nil {
+		return
+	}
+	s.SchedulabilityReports = reports
+}
+
+// SetPoolEstimations is the setter for PoolEstimations
+func (s *DebuggingSnapshotImpl) SetPoolEstimations(estimations []PoolEstimation) {
+	if estimations == nil {
+		return
+	}
+	s.PoolEstimations = estimations
 }
 
 // SetUnscheduledPodsCanBeScheduled is the setter for UnscheduledPodsCanBeScheduled
@@ -141,6 +289,7 @@ func (s *DebuggingSnapshotImpl) GetOutputBytes() ([]byte, bool) {
 	}
 
 	klog.Infof("Debugging snapshot flush ready")
+	s.FormatVersion = SnapshotFormatVersion
 	marshalOutput, err := json.Marshal(s)
 
 	// this error captures if the snapshot couldn't be marshalled, hence we create a new object
@@ -156,7 +305,29 @@ func (s *DebuggingSnapshotImpl) GetOutputBytes() ([]byte, bool) {
 		return errorMarshal, true
 	}
 
-	return marshalOutput, errMsgSet
+	// Snapshots can be downloaded by anyone who can reach the debugging
+	// endpoint, so credentials never leave the process unredacted.
+	return []byte(core_utils.RedactSecrets(string(marshalOutput))), errMsgSet
+}
+
+// DecodeSnapshot parses a snapshot dump of any known format version. Dumps
+// without a FormatVersion are decoded as v1; unknown fields of newer minor
+// additions are ignored, so old snapshots remain readable and analysis tools
+// built against an older schema keep working.
+func DecodeSnapshot(body []byte) (*DebuggingSnapshotImpl, error) {
+	snapshot := &DebuggingSnapshotImpl{}
+	if err := json.Unmarshal(body, snapshot); err != nil {
+		return nil, err
+	}
+	if snapshot.FormatVersion == "" {
+		snapshot.FormatVersion = SnapshotFormatV1
+	}
+	switch snapshot.FormatVersion {
+	case SnapshotFormatV1, SnapshotFormatV2:
+		return snapshot, nil
+	default:
+		return nil, fmt.Errorf("unknown snapshot format version %q, produced by a newer cluster autoscaler", snapshot.FormatVersion)
+	}
 }
 
 // SetErrorMessage sets the error message in the snapshot
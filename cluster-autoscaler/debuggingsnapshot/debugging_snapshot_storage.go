@@ -0,0 +1,115 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package debuggingsnapshot
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	// scheduledSnapshotPrefix and scheduledSnapshotSuffix frame the file names
+	// of scheduled snapshots; the timestamp in between keeps them sortable.
+	scheduledSnapshotPrefix = "snapshot-"
+	scheduledSnapshotSuffix = ".json"
+	// scheduledSnapshotTimeLayout is the timestamp format used in file names.
+	scheduledSnapshotTimeLayout = "20060102-150405"
+)
+
+// flushScheduledSnapshot writes the collected snapshot to the configured
+// directory and applies the retention limits. Callers must hold the Mutex.
+func (d *DebuggingSnapshotterImpl) flushScheduledSnapshot() {
+	d.scheduledCapture = false
+	captureTag := d.captureTag
+	d.captureTag = ""
+	defer func() {
+		d.DebuggingSnapshot.Cleanup()
+		*d.State = LISTENING
+	}()
+
+	if *d.State != DATA_COLLECTED {
+		klog.Errorf("No data was collected for the scheduled debugging snapshot in this loop, nothing written")
+		return
+	}
+	d.DebuggingSnapshot.SetEndTimestamp(time.Now().In(time.UTC))
+	body, isErrorMessage := d.DebuggingSnapshot.GetOutputBytes()
+	if isErrorMessage {
+		klog.Errorf("Scheduled debugging snapshot has the error message set, nothing written")
+		return
+	}
+	if err := os.MkdirAll(d.scheduledDir, 0755); err != nil {
+		klog.Errorf("Failed to create the scheduled debugging snapshot directory %s: %v", d.scheduledDir, err)
+		return
+	}
+	fileName := scheduledSnapshotPrefix + captureTag + time.Now().In(time.UTC).Format(scheduledSnapshotTimeLayout) + scheduledSnapshotSuffix
+	filePath := filepath.Join(d.scheduledDir, fileName)
+	if err := os.WriteFile(filePath, body, 0644); err != nil {
+		klog.Errorf("Failed to write the scheduled debugging snapshot %s: %v", filePath, err)
+		return
+	}
+	klog.V(2).Infof("Wrote scheduled debugging snapshot %s (%v bytes)", filePath, len(body))
+	maybeUploadSnapshot(fileName, body)
+	d.applySnapshotRetention()
+}
+
+// applySnapshotRetention deletes the oldest scheduled snapshots until at most
+// scheduledMaxFiles files and scheduledMaxBytes of disk are used. Zero limits
+// are not enforced.
+func (d *DebuggingSnapshotterImpl) applySnapshotRetention() {
+	entries, err := os.ReadDir(d.scheduledDir)
+	if err != nil {
+		klog.Errorf("Failed to list the scheduled debugging snapshot directory %s: %v", d.scheduledDir, err)
+		return
+	}
+	var names []string
+	var totalBytes int64
+	sizes := map[string]int64{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasPrefix(entry.Name(), scheduledSnapshotPrefix) || !strings.HasSuffix(entry.Name(), scheduledSnapshotSuffix) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		names = append(names, entry.Name())
+		sizes[entry.Name()] = info.Size()
+		totalBytes += info.Size()
+	}
+	// The timestamped names sort chronologically, oldest first.
+	sort.Strings(names)
+	for len(names) > 0 {
+		overFileLimit := d.scheduledMaxFiles > 0 && len(names) > d.scheduledMaxFiles
+		overSizeLimit := d.scheduledMaxBytes > 0 && totalBytes > d.scheduledMaxBytes
+		if !overFileLimit && !overSizeLimit {
+			break
+		}
+		oldest := names[0]
+		if err := os.Remove(filepath.Join(d.scheduledDir, oldest)); err != nil {
+			klog.Errorf("Failed to remove the old scheduled debugging snapshot %s: %v", oldest, err)
+			return
+		}
+		klog.V(2).Infof("Removed old scheduled debugging snapshot %s to enforce retention", oldest)
+		totalBytes -= sizes[oldest]
+		names = names[1:]
+	}
+}
@@ -23,6 +23,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/kubernetes/pkg/scheduler/framework"
 )
@@ -96,6 +97,75 @@ func TestBasicSetterWorkflow(t *testing.T) {
 
 }
 
+func TestSnapshotFormatVersioning(t *testing.T) {
+	snapshot := &DebuggingSnapshotImpl{}
+	op, errSet := snapshot.GetOutputBytes()
+	assert.False(t, errSet)
+
+	decoded, err := DecodeSnapshot(op)
+	assert.NoError(t, err)
+	assert.Equal(t, SnapshotFormatVersion, decoded.FormatVersion)
+
+	// Dumps from before the format was versioned decode as v1.
+	legacy, err := DecodeSnapshot([]byte(`{"NodeList":null}`))
+	assert.NoError(t, err)
+	assert.Equal(t, SnapshotFormatV1, legacy.FormatVersion)
+
+	// Snapshots from a newer, unknown format are rejected explicitly.
+	_, err = DecodeSnapshot([]byte(`{"FormatVersion":"v99"}`))
+	assert.Error(t, err)
+
+	_, err = DecodeSnapshot([]byte("not json"))
+	assert.Error(t, err)
+}
+
+func TestSchedulingContextSetters(t *testing.T) {
+	snapshot := &DebuggingSnapshotImpl{}
+	snapshot.SetPodDisruptionBudgets([]*policyv1.PodDisruptionBudget{
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "pdb1",
+				Namespace: "default",
+			},
+		},
+	})
+	snapshot.SetFailedSchedulingEvents([]v1.Event{
+		{
+			Reason:  "FailedScheduling",
+			Message: "0/3 nodes are available: 3 Insufficient cpu.",
+		},
+	})
+	snapshot.SetPendingPodConditions([]*v1.Pod{
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "Pod1",
+				Namespace: "default",
+			},
+			Status: v1.PodStatus{
+				Conditions: []v1.PodCondition{
+					{
+						Type:   v1.PodScheduled,
+						Status: v1.ConditionFalse,
+						Reason: v1.PodReasonUnschedulable,
+					},
+				},
+			},
+		},
+	})
+
+	op, errSet := snapshot.GetOutputBytes()
+	assert.False(t, errSet)
+	decoded, err := DecodeSnapshot(op)
+	assert.NoError(t, err)
+	assert.Len(t, decoded.PodDisruptionBudgets, 1)
+	assert.Equal(t, "pdb1", decoded.PodDisruptionBudgets[0].Name)
+	assert.Len(t, decoded.FailedSchedulingEvents, 1)
+	assert.Contains(t, decoded.FailedSchedulingEvents[0].Message, "Insufficient cpu")
+	assert.Len(t, decoded.PendingPodConditions, 1)
+	assert.Equal(t, "Pod1", decoded.PendingPodConditions[0].Name)
+	assert.Equal(t, v1.PodReasonUnschedulable, decoded.PendingPodConditions[0].Conditions[0].Reason)
+}
+
 func TestEmptyDataNoError(t *testing.T) {
 	snapshot := &DebuggingSnapshotImpl{}
 	op, err := snapshot.GetOutputBytes()
@@ -23,6 +23,8 @@ import (
 	"time"
 
 	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1beta1"
+	"k8s.io/autoscaler/cluster-autoscaler/expander/audit"
 	"k8s.io/klog/v2"
 	"k8s.io/kubernetes/pkg/scheduler/framework"
 )
@@ -66,6 +68,26 @@ type DebuggingSnapshotterImpl struct {
 	// CancelRequest is the cancel function for the snapshot request. It is used to
 	// terminate any ongoing request when CA is shutting down
 	CancelRequest context.CancelFunc
+	// scheduledInterval enables periodic automatic capture when non-zero; the
+	// snapshots are written to scheduledDir instead of an http response
+	scheduledInterval time.Duration
+	// scheduledDir is the directory the scheduled snapshots are written to
+	scheduledDir string
+	// scheduledMaxFiles caps how many scheduled snapshots are retained on disk
+	scheduledMaxFiles int
+	// scheduledMaxBytes caps the total size of the retained scheduled snapshots
+	scheduledMaxBytes int64
+	// scheduledCapture is true while the capture in progress was started by the
+	// schedule or a scale failure rather than an http request
+	scheduledCapture bool
+	// captureTag is inserted in the file name of the capture in progress, so
+	// failure snapshots are recognisable on disk
+	captureTag string
+	// pendingFailureReason is set by RequestFailureSnapshot until the capture
+	// starts on the next loop
+	pendingFailureReason string
+	// lastScheduledCapture is when the last scheduled capture was started
+	lastScheduledCapture time.Time
 }
 
 // DebuggingSnapshotter is the interface for debugging snapshot
@@ -82,6 +104,27 @@ type DebuggingSnapshotter interface {
 	// SetTemplateNodes is a setter for all the TemplateNodes present in the cluster
 	// incl. templates for which there are no nodes
 	SetTemplateNodes(map[string]*framework.NodeInfo)
+	// SetExpanderDecisions is a setter for the recent expander decisions,
+	// incl. how each strategy in the chain narrowed the options down
+	SetExpanderDecisions([]audit.Decision)
+	// SetPodDisruptionBudgets is a setter for the PDBs in the cluster,
+	// which constrain which nodes scale down may drain
+	SetPodDisruptionBudgets([]*policyv1.PodDisruptionBudget)
+	// SetFailedSchedulingEvents is a setter for the recent FailedScheduling
+	// events, explaining why the pending pods couldn't be placed
+	SetFailedSchedulingEvents([]v1.Event)
+	// SetPendingPodConditions is a setter for the scheduling conditions of
+	// the pods the autoscaler is trying to help
+	SetPendingPodConditions([]*v1.Pod)
+	// SetSchedulabilityReports is a setter for the per-pending-pod
+	// simulation outcomes against every node in the cluster
+	SetSchedulabilityReports([]*PodSchedulabilityReport)
+	// SetPoolEstimations is a setter for the per-pool node count estimations
+	// for the pending pods
+	SetPoolEstimations([]PoolEstimation)
+	// RequestFailureSnapshot arms an automatic snapshot capture tagged with
+	// the given scale operation failure, written to disk on the next loop
+	RequestFailureSnapshot(reason string)
 	// ResponseHandler is the http response handler to manage incoming requests
 	ResponseHandler(http.ResponseWriter, *http.Request)
 	// IsDataCollectionAllowed checks the internal State of the snapshotter
@@ -96,16 +139,33 @@ type DebuggingSnapshotter interface {
 
 // NewDebuggingSnapshotter returns a new instance of DebuggingSnapshotter
 func NewDebuggingSnapshotter(isDebuggerEnabled bool) DebuggingSnapshotter {
+	return NewDebuggingSnapshotterWithScheduledCapture(isDebuggerEnabled, 0, "", 0, 0)
+}
+
+// NewDebuggingSnapshotterWithScheduledCapture returns a snapshotter that, on
+// top of serving http requests, automatically captures a snapshot to dir every
+// interval, retaining at most maxFiles snapshots and maxBytes on disk so the
+// state before an incident is available even if nobody triggered a snapshot.
+// A zero interval disables the schedule.
+func NewDebuggingSnapshotterWithScheduledCapture(isDebuggerEnabled bool, interval time.Duration, dir string, maxFiles int, maxBytes int64) DebuggingSnapshotter {
 	state := SNAPSHOTTER_DISABLED
 	if isDebuggerEnabled {
 		klog.Infof("Debugging Snapshot is enabled")
 		state = LISTENING
+		if interval > 0 {
+			klog.Infof("Scheduled debugging snapshot capture is enabled every %v into %s", interval, dir)
+		}
 	}
 	return &DebuggingSnapshotterImpl{
-		State:             &state,
-		Mutex:             &sync.Mutex{},
-		DebuggingSnapshot: &DebuggingSnapshotImpl{},
-		Trigger:           make(chan struct{}, 1),
+		State:                &state,
+		Mutex:                &sync.Mutex{},
+		DebuggingSnapshot:    &DebuggingSnapshotImpl{},
+		Trigger:              make(chan struct{}, 1),
+		scheduledInterval:    interval,
+		scheduledDir:         dir,
+		scheduledMaxFiles:    maxFiles,
+		scheduledMaxBytes:    maxBytes,
+		lastScheduledCapture: time.Now(),
 	}
 }
 
@@ -139,6 +199,8 @@ func (d *DebuggingSnapshotterImpl) ResponseHandler(w http.ResponseWriter, r *htt
 			w.WriteHeader(http.StatusInternalServerError)
 		} else {
 			w.WriteHeader(http.StatusOK)
+			fileName := scheduledSnapshotPrefix + time.Now().In(time.UTC).Format(scheduledSnapshotTimeLayout) + scheduledSnapshotSuffix
+			maybeUploadSnapshot(fileName, body)
 		}
 		w.Write(body)
 
@@ -192,15 +254,59 @@ func (d *DebuggingSnapshotterImpl) StartDataCollection() {
 		*d.State = START_DATA_COLLECTION
 		klog.Infof("Trigger Enabled for Debugging Snapshot, starting data collection")
 		d.DebuggingSnapshot.SetStartTimestamp(time.Now().In(time.UTC))
+		return
+	}
+	// A pending http request always wins over the schedule; a scheduled or
+	// failure capture only starts when the snapshotter would otherwise be idle.
+	if d.pendingFailureReason != "" && *d.State == LISTENING {
+		*d.State = START_DATA_COLLECTION
+		d.scheduledCapture = true
+		d.captureTag = "failure-"
+		klog.Infof("Starting debugging snapshot data collection after a scale failure: %s", d.pendingFailureReason)
+		d.DebuggingSnapshot.SetFailureReason(d.pendingFailureReason)
+		d.DebuggingSnapshot.SetStartTimestamp(time.Now().In(time.UTC))
+		d.pendingFailureReason = ""
+		return
+	}
+	if d.scheduledInterval > 0 && *d.State == LISTENING && time.Since(d.lastScheduledCapture) >= d.scheduledInterval {
+		*d.State = START_DATA_COLLECTION
+		d.scheduledCapture = true
+		d.lastScheduledCapture = time.Now()
+		klog.Infof("Starting scheduled debugging snapshot data collection")
+		d.DebuggingSnapshot.SetStartTimestamp(time.Now().In(time.UTC))
 	}
 }
 
+// RequestFailureSnapshot arms an automatic capture on the next loop, tagged
+// with the failure that triggered it. The first failure wins until its
+// snapshot has been captured; later ones are only logged.
+func (d *DebuggingSnapshotterImpl) RequestFailureSnapshot(reason string) {
+	d.Mutex.Lock()
+	defer d.Mutex.Unlock()
+	if *d.State == SNAPSHOTTER_DISABLED || d.scheduledDir == "" {
+		return
+	}
+	if d.pendingFailureReason != "" {
+		klog.V(2).Infof("Debugging snapshot already armed for a previous failure, dropping: %s", reason)
+		return
+	}
+	d.pendingFailureReason = reason
+	klog.Infof("Armed a debugging snapshot capture for the next loop after a scale failure: %s", reason)
+}
+
 // Flush is the impl for DebuggingSnapshotter.Flush
 // It checks if any data has been collected or data collection failed
 func (d *DebuggingSnapshotterImpl) Flush() {
 	d.Mutex.Lock()
 	defer d.Mutex.Unlock()
 
+	// Scheduled captures are written to disk instead of an http response and
+	// the snapshotter returns to listening for the next request.
+	if d.scheduledCapture {
+		d.flushScheduledSnapshot()
+		return
+	}
+
 	// Case where Data Collection was started but no data was collected, needs to
 	// be stated as an error and reset to pre-trigger State
 	if *d.State == START_DATA_COLLECTION {
@@ -228,6 +334,78 @@ func (d *DebuggingSnapshotterImpl) SetClusterNodes(nodeInfos []*framework.NodeIn
 	*d.State = DATA_COLLECTED
 }
 
+// SetExpanderDecisions is the setter for ExpanderDecisions
+func (d *DebuggingSnapshotterImpl) SetExpanderDecisions(decisions []audit.Decision) {
+	d.Mutex.Lock()
+	defer d.Mutex.Unlock()
+	if !d.IsDataCollectionAllowedNoLock() {
+		return
+	}
+	klog.V(4).Infof("ExpanderDecisions are being set for the debugging snapshot")
+	d.DebuggingSnapshot.SetExpanderDecisions(decisions)
+	*d.State = DATA_COLLECTED
+}
+
+// SetPodDisruptionBudgets is the setter for PodDisruptionBudgets
+func (d *DebuggingSnapshotterImpl) SetPodDisruptionBudgets(pdbs []*policyv1.PodDisruptionBudget) {
+	d.Mutex.Lock()
+	defer d.Mutex.Unlock()
+	if !d.IsDataCollectionAllowedNoLock() {
+		return
+	}
+	klog.V(4).Infof("PodDisruptionBudgets are being set for the debugging snapshot")
+	d.DebuggingSnapshot.SetPodDisruptionBudgets(pdbs)
+	*d.State = DATA_COLLECTED
+}
+
+// SetFailedSchedulingEvents is the setter for FailedSchedulingEvents
+func (d *DebuggingSnapshotterImpl) SetFailedSchedulingEvents(events []v1.Event) {
+	d.Mutex.Lock()
+	defer d.Mutex.Unlock()
+	if !d.IsDataCollectionAllowedNoLock() {
+		return
+	}
+	klog.V(4).Infof("FailedSchedulingEvents are being set for the debugging snapshot")
+	d.DebuggingSnapshot.SetFailedSchedulingEvents(events)
+	*d.State = DATA_COLLECTED
+}
+
+// SetPendingPodConditions is the setter for PendingPodConditions
+func (d *DebuggingSnapshotterImpl) SetPendingPodConditions(podList []*v1.Pod) {
+	d.Mutex.Lock()
+	defer d.Mutex.Unlock()
+	if !d.IsDataCollectionAllowedNoLock() {
+		return
+	}
+	klog.V(4).Infof("PendingPodConditions are being set for the debugging snapshot")
+	d.DebuggingSnapshot.SetPendingPodConditions(podList)
+	*d.State = DATA_COLLECTED
+}
+
+// SetSchedulabilityReports is the setter for SchedulabilityReports
+func (d *DebuggingSnapshotterImpl) SetSchedulabilityReports(reports []*PodSchedulabilityReport) {
+	d.Mutex.Lock()
+	defer d.Mutex.Unlock()
+	if !d.IsDataCollectionAllowedNoLock() {
+		return
+	}
+	klog.V(4).Infof("SchedulabilityReports are being set for the debugging snapshot")
+	d.DebuggingSnapshot.SetSchedulabilityReports(reports)
+	*d.State = DATA_COLLECTED
+}
+
+// SetPoolEstimations is the setter for PoolEstimations
+func (d *DebuggingSnapshotterImpl) SetPoolEstimations(estimations []PoolEstimation) {
+	d.Mutex.Lock()
+	defer d.Mutex.Unlock()
+	if !d.IsDataCollectionAllowedNoLock() {
+		return
+	}
+	klog.V(4).Infof("PoolEstimations are being set for the debugging snapshot")
+	d.DebuggingSnapshot.SetPoolEstimations(estimations)
+	*d.State = DATA_COLLECTED
+}
+
 // SetUnscheduledPodsCanBeScheduled is the setter for UnscheduledPodsCanBeScheduled
 func (d *DebuggingSnapshotterImpl) SetUnscheduledPodsCanBeScheduled(podList []*v1.Pod) {
 	d.Mutex.Lock()
@@ -19,8 +19,11 @@ package debuggingsnapshot
 import (
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	v1 "k8s.io/api/core/v1"
@@ -83,6 +86,75 @@ func TestBasicSnapshotRequest(t *testing.T) {
 	assert.Greater(t, int64(0), resp.ContentLength)
 }
 
+func TestScheduledSnapshotCaptureWithRetention(t *testing.T) {
+	dir := t.TempDir()
+	snapshotter := NewDebuggingSnapshotterWithScheduledCapture(true, time.Nanosecond, dir, 1, 0)
+
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "testNode",
+		},
+	}
+	nodeInfo := &framework.NodeInfo{
+		Requested:        &framework.Resource{},
+		NonZeroRequested: &framework.Resource{},
+		Allocatable:      &framework.Resource{},
+	}
+	nodeInfo.SetNode(node)
+
+	for i := 0; i < 2; i++ {
+		time.Sleep(time.Millisecond)
+		snapshotter.StartDataCollection()
+		assert.True(t, snapshotter.IsDataCollectionAllowed())
+		snapshotter.SetClusterNodes([]*framework.NodeInfo{nodeInfo})
+		snapshotter.Flush()
+	}
+
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	body, err := os.ReadFile(filepath.Join(dir, entries[0].Name()))
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), "testNode")
+}
+
+func TestFailureSnapshotCapture(t *testing.T) {
+	dir := t.TempDir()
+	snapshotter := NewDebuggingSnapshotterWithScheduledCapture(true, 0, dir, 0, 0)
+
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "testNode",
+		},
+	}
+	nodeInfo := &framework.NodeInfo{
+		Requested:        &framework.Resource{},
+		NonZeroRequested: &framework.Resource{},
+		Allocatable:      &framework.Resource{},
+	}
+	nodeInfo.SetNode(node)
+
+	// Without an armed failure, no snapshot is captured.
+	snapshotter.StartDataCollection()
+	assert.False(t, snapshotter.IsDataCollectionAllowed())
+
+	snapshotter.RequestFailureSnapshot("scale up failed: out of resources")
+	snapshotter.StartDataCollection()
+	assert.True(t, snapshotter.IsDataCollectionAllowed())
+	snapshotter.SetClusterNodes([]*framework.NodeInfo{nodeInfo})
+	snapshotter.Flush()
+
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Contains(t, entries[0].Name(), "failure-")
+	body, err := os.ReadFile(filepath.Join(dir, entries[0].Name()))
+	assert.NoError(t, err)
+	snapshot, err := DecodeSnapshot(body)
+	assert.NoError(t, err)
+	assert.Equal(t, "scale up failed: out of resources", snapshot.FailureReason)
+}
+
 func TestFlushWithoutData(t *testing.T) {
 	var wg sync.WaitGroup
 	wg.Add(1)
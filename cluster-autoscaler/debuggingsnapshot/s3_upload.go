@@ -0,0 +1,133 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package debuggingsnapshot
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kube_client "k8s.io/client-go/kubernetes"
+	klog "k8s.io/klog/v2"
+)
+
+// snapshotUploader pushes debugging snapshots to an S3-compatible bucket using
+// signature v4 authentication, so support engineers can collect state from
+// customer clusters without exec'ing into the pod. Credentials are read from a
+// secret in the cluster rather than the environment, so they can be rotated
+// without restarting CA.
+type snapshotUploader struct {
+	kubeClient kube_client.Interface
+	namespace  string
+	secretName string
+	endpoint   string
+	bucket     string
+	region     string
+	prefix     string
+
+	mutex     sync.Mutex
+	accessKey string
+	secretKey string
+}
+
+var snapshotUploaderInstance *snapshotUploader
+
+// EnableS3Upload makes every captured debugging snapshot, scheduled or
+// requested over http, get uploaded to an S3-compatible bucket. Credentials
+// are read from the access_key and secret_key keys of the named secret.
+func EnableS3Upload(kubeClient kube_client.Interface, namespace string, secretName string,
+	endpoint string, bucket string, region string, prefix string) {
+	snapshotUploaderInstance = &snapshotUploader{
+		kubeClient: kubeClient,
+		namespace:  namespace,
+		secretName: secretName,
+		endpoint:   endpoint,
+		bucket:     bucket,
+		region:     region,
+		prefix:     prefix,
+	}
+	klog.Infof("Debugging snapshot upload to %s/%s is enabled", endpoint, bucket)
+}
+
+// maybeUploadSnapshot uploads the snapshot body in the background when the
+// uploader is configured. Failures are logged and never affect the main loop.
+func maybeUploadSnapshot(fileName string, body []byte) {
+	if snapshotUploaderInstance == nil {
+		return
+	}
+	go snapshotUploaderInstance.upload(fileName, body)
+}
+
+// getCredentials loads the access keys from the configured secret, caching
+// them after the first successful read.
+func (u *snapshotUploader) getCredentials() (string, string, error) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	if u.accessKey != "" && u.secretKey != "" {
+		return u.accessKey, u.secretKey, nil
+	}
+	secret, err := u.kubeClient.CoreV1().Secrets(u.namespace).Get(context.Background(), u.secretName, metav1.GetOptions{})
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read secret %s/%s: %v", u.namespace, u.secretName, err)
+	}
+	accessKey := string(secret.Data["access_key"])
+	secretKey := string(secret.Data["secret_key"])
+	if accessKey == "" || secretKey == "" {
+		return "", "", fmt.Errorf("secret %s/%s is missing the access_key or secret_key key", u.namespace, u.secretName)
+	}
+	u.accessKey = accessKey
+	u.secretKey = secretKey
+	return accessKey, secretKey, nil
+}
+
+// upload puts the snapshot in the bucket under the given name.
+func (u *snapshotUploader) upload(fileName string, body []byte) {
+	accessKey, secretKey, err := u.getCredentials()
+	if err != nil {
+		klog.Errorf("Failed to load debugging snapshot upload credentials: %v", err)
+		return
+	}
+	url := fmt.Sprintf("%s/%s/%s%s", u.endpoint, u.bucket, u.prefix, fileName)
+	request, err := http.NewRequest("PUT", url, bytes.NewReader(body))
+	if err != nil {
+		klog.Errorf("Failed to build debugging snapshot upload request: %v", err)
+		return
+	}
+	signer := v4.NewSigner(credentials.NewStaticCredentials(accessKey, secretKey, ""))
+	if _, err := signer.Sign(request, bytes.NewReader(body), "s3", u.region, time.Now()); err != nil {
+		klog.Errorf("Failed to sign debugging snapshot upload request: %v", err)
+		return
+	}
+	client := &http.Client{Timeout: 60 * time.Second}
+	response, err := client.Do(request)
+	if err != nil {
+		klog.Errorf("Failed to upload debugging snapshot %s: %v", fileName, err)
+		return
+	}
+	defer response.Body.Close()
+	if response.StatusCode >= 300 {
+		klog.Errorf("Debugging snapshot upload of %s rejected: %s", fileName, response.Status)
+		return
+	}
+	klog.V(1).Infof("Uploaded debugging snapshot %s to %s", fileName, url)
+}
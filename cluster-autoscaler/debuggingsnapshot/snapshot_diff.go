@@ -0,0 +1,160 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package debuggingsnapshot
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SnapshotDiff is the compact change summary between two debugging snapshots,
+// making loop-over-loop behavior changes easy to spot without eyeballing two
+// full snapshot dumps.
+type SnapshotDiff struct {
+	NodesAdded           []string `json:"nodesAdded,omitempty"`
+	NodesRemoved         []string `json:"nodesRemoved,omitempty"`
+	PendingPodsAdded     []string `json:"pendingPodsAdded,omitempty"`
+	PendingPodsRemoved   []string `json:"pendingPodsRemoved,omitempty"`
+	TemplateNodesAdded   []string `json:"templateNodesAdded,omitempty"`
+	TemplateNodesRemoved []string `json:"templateNodesRemoved,omitempty"`
+	NewExpanderDecisions int      `json:"newExpanderDecisions,omitempty"`
+}
+
+// IsEmpty reports whether the two snapshots describe the same cluster state.
+func (d *SnapshotDiff) IsEmpty() bool {
+	return len(d.NodesAdded) == 0 && len(d.NodesRemoved) == 0 &&
+		len(d.PendingPodsAdded) == 0 && len(d.PendingPodsRemoved) == 0 &&
+		len(d.TemplateNodesAdded) == 0 && len(d.TemplateNodesRemoved) == 0 &&
+		d.NewExpanderDecisions == 0
+}
+
+// String renders the diff as one compact line for logs.
+func (d *SnapshotDiff) String() string {
+	if d.IsEmpty() {
+		return "no changes"
+	}
+	var parts []string
+	appendPart := func(label string, names []string) {
+		if len(names) > 0 {
+			parts = append(parts, fmt.Sprintf("%s: %s", label, strings.Join(names, ", ")))
+		}
+	}
+	appendPart("nodes added", d.NodesAdded)
+	appendPart("nodes removed", d.NodesRemoved)
+	appendPart("pending pods added", d.PendingPodsAdded)
+	appendPart("pending pods removed", d.PendingPodsRemoved)
+	appendPart("template nodes added", d.TemplateNodesAdded)
+	appendPart("template nodes removed", d.TemplateNodesRemoved)
+	if d.NewExpanderDecisions > 0 {
+		parts = append(parts, fmt.Sprintf("new expander decisions: %v", d.NewExpanderDecisions))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// DiffSnapshots compares two snapshots, typically from consecutive loops, and
+// summarises what changed between them.
+func DiffSnapshots(previous *DebuggingSnapshotImpl, current *DebuggingSnapshotImpl) *SnapshotDiff {
+	diff := &SnapshotDiff{}
+	diff.NodesAdded, diff.NodesRemoved = diffNames(nodeNames(previous), nodeNames(current))
+	diff.PendingPodsAdded, diff.PendingPodsRemoved = diffNames(pendingPodNames(previous), pendingPodNames(current))
+	diff.TemplateNodesAdded, diff.TemplateNodesRemoved = diffNames(templateNodeNames(previous), templateNodeNames(current))
+	// Decisions carry timestamps, so "new" means newer than anything the
+	// previous snapshot had.
+	cutoff := time.Time{}
+	for _, decision := range previous.ExpanderDecisions {
+		if decision.Time.After(cutoff) {
+			cutoff = decision.Time
+		}
+	}
+	for _, decision := range current.ExpanderDecisions {
+		if decision.Time.After(cutoff) {
+			diff.NewExpanderDecisions++
+		}
+	}
+	return diff
+}
+
+// DiffSnapshotBytes decodes two snapshot dumps, e.g. files written by the
+// scheduled capture, and diffs them.
+func DiffSnapshotBytes(previous []byte, current []byte) (*SnapshotDiff, error) {
+	previousSnapshot, err := DecodeSnapshot(previous)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the previous snapshot: %v", err)
+	}
+	currentSnapshot, err := DecodeSnapshot(current)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the current snapshot: %v", err)
+	}
+	return DiffSnapshots(previousSnapshot, currentSnapshot), nil
+}
+
+func nodeNames(s *DebuggingSnapshotImpl) []string {
+	var names []string
+	for _, clusterNode := range s.NodeList {
+		if clusterNode != nil && clusterNode.Node != nil {
+			names = append(names, clusterNode.Node.Name)
+		}
+	}
+	return names
+}
+
+func pendingPodNames(s *DebuggingSnapshotImpl) []string {
+	var names []string
+	for _, pod := range s.UnscheduledPodsCanBeScheduled {
+		if pod != nil {
+			names = append(names, pod.Namespace+"/"+pod.Name)
+		}
+	}
+	return names
+}
+
+func templateNodeNames(s *DebuggingSnapshotImpl) []string {
+	var names []string
+	for name := range s.TemplateNodes {
+		names = append(names, name)
+	}
+	return names
+}
+
+// diffNames returns the names only in current (added) and only in previous
+// (removed), both sorted.
+func diffNames(previous []string, current []string) ([]string, []string) {
+	previousSet := map[string]bool{}
+	for _, name := range previous {
+		previousSet[name] = true
+	}
+	currentSet := map[string]bool{}
+	for _, name := range current {
+		currentSet[name] = true
+	}
+	var added, removed []string
+	for name := range currentSet {
+		if !previousSet[name] {
+			added = append(added, name)
+		}
+	}
+	for name := range previousSet {
+		if !currentSet[name] {
+			removed = append(removed, name)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package debuggingsnapshot
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/expander/audit"
+)
+
+func snapshotWithNodes(nodeNames ...string) *DebuggingSnapshotImpl {
+	s := &DebuggingSnapshotImpl{}
+	for _, name := range nodeNames {
+		s.NodeList = append(s.NodeList, &ClusterNode{
+			Node: &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}},
+		})
+	}
+	return s
+}
+
+func TestDiffSnapshotsNodeChanges(t *testing.T) {
+	previous := snapshotWithNodes("worker-1", "worker-2")
+	current := snapshotWithNodes("worker-2", "worker-3")
+
+	diff := DiffSnapshots(previous, current)
+	assert.Equal(t, []string{"worker-3"}, diff.NodesAdded)
+	assert.Equal(t, []string{"worker-1"}, diff.NodesRemoved)
+	assert.False(t, diff.IsEmpty())
+	assert.Contains(t, diff.String(), "nodes added: worker-3")
+}
+
+func TestDiffSnapshotsPendingPodsAndDecisions(t *testing.T) {
+	now := time.Now()
+	previous := snapshotWithNodes("worker-1")
+	previous.ExpanderDecisions = []audit.Decision{{Time: now.Add(-time.Minute)}}
+	current := snapshotWithNodes("worker-1")
+	current.UnscheduledPodsCanBeScheduled = []*v1.Pod{
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "pending"}},
+	}
+	current.ExpanderDecisions = []audit.Decision{{Time: now.Add(-time.Minute)}, {Time: now}}
+
+	diff := DiffSnapshots(previous, current)
+	assert.Equal(t, []string{"default/pending"}, diff.PendingPodsAdded)
+	assert.Equal(t, 1, diff.NewExpanderDecisions)
+}
+
+func TestDiffSnapshotsNoChanges(t *testing.T) {
+	previous := snapshotWithNodes("worker-1")
+	current := snapshotWithNodes("worker-1")
+
+	diff := DiffSnapshots(previous, current)
+	assert.True(t, diff.IsEmpty())
+	assert.Equal(t, "no changes", diff.String())
+}
+
+func TestDiffSnapshotBytes(t *testing.T) {
+	previous, err := json.Marshal(snapshotWithNodes("worker-1"))
+	assert.NoError(t, err)
+	current, err := json.Marshal(snapshotWithNodes("worker-1", "worker-2"))
+	assert.NoError(t, err)
+
+	diff, err := DiffSnapshotBytes(previous, current)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"worker-2"}, diff.NodesAdded)
+
+	_, err = DiffSnapshotBytes([]byte("not json"), current)
+	assert.Error(t, err)
+}
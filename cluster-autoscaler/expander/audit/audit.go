@@ -0,0 +1,172 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package audit records every expander invocation: the options presented, how
+// each strategy in the chain narrowed them down, and the final choice. The
+// decisions are logged, emitted as an event on a triggering pod and kept in
+// memory so the debugging snapshot can persist them — answering "why did it
+// pick that pool" after the fact.
+package audit
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/autoscaler/cluster-autoscaler/expander"
+	"k8s.io/autoscaler/cluster-autoscaler/metrics"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	klog "k8s.io/klog/v2"
+	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+// keptDecisions is how many past decisions are retained for the snapshot.
+const keptDecisions = 10
+
+// Step records what one strategy in the expander chain did.
+type Step struct {
+	Strategy   string   `json:"strategy"`
+	OptionsIn  []string `json:"optionsIn"`
+	OptionsOut []string `json:"optionsOut"`
+}
+
+// Decision records one full expander invocation.
+type Decision struct {
+	Time        time.Time `json:"time"`
+	Steps       []Step    `json:"steps"`
+	FinalOption string    `json:"finalOption,omitempty"`
+}
+
+var (
+	mutex           sync.Mutex
+	currentDecision *Decision
+	pastDecisions   []Decision
+)
+
+// describeOption renders an option for logs and the snapshot.
+func describeOption(option expander.Option) string {
+	description := fmt.Sprintf("nodeCount=%v podCount=%v", option.NodeCount, len(option.Pods))
+	if option.Debug != "" {
+		description += " " + option.Debug
+	}
+	return description
+}
+
+func describeOptions(options []expander.Option) []string {
+	descriptions := make([]string, 0, len(options))
+	for _, option := range options {
+		descriptions = append(descriptions, describeOption(option))
+	}
+	return descriptions
+}
+
+// beginDecision opens a new decision if none is in progress.
+func beginDecision() *Decision {
+	if currentDecision == nil {
+		currentDecision = &Decision{Time: time.Now()}
+	}
+	return currentDecision
+}
+
+// finishDecision archives the decision in progress.
+func finishDecision(finalOption string) {
+	decision := beginDecision()
+	decision.FinalOption = finalOption
+	pastDecisions = append(pastDecisions, *decision)
+	if len(pastDecisions) > keptDecisions {
+		pastDecisions = pastDecisions[len(pastDecisions)-keptDecisions:]
+	}
+	currentDecision = nil
+}
+
+// LastDecisions returns a copy of the retained expander decisions, newest
+// last.
+func LastDecisions() []Decision {
+	mutex.Lock()
+	defer mutex.Unlock()
+	decisions := make([]Decision, len(pastDecisions))
+	copy(decisions, pastDecisions)
+	return decisions
+}
+
+type auditedFilter struct {
+	name  string
+	inner expander.Filter
+}
+
+// WrapFilter decorates a filter so its invocations are recorded.
+func WrapFilter(name string, inner expander.Filter) expander.Filter {
+	return &auditedFilter{name: name, inner: inner}
+}
+
+func (f *auditedFilter) BestOptions(options []expander.Option, nodeInfo map[string]*schedulerframework.NodeInfo) []expander.Option {
+	start := time.Now()
+	result := f.inner.BestOptions(options, nodeInfo)
+	metrics.UpdateExpanderSelection(f.name, len(options), len(result), time.Since(start))
+	mutex.Lock()
+	decision := beginDecision()
+	decision.Steps = append(decision.Steps, Step{
+		Strategy:   f.name,
+		OptionsIn:  describeOptions(options),
+		OptionsOut: describeOptions(result),
+	})
+	mutex.Unlock()
+	klog.V(2).Infof("Expander %s narrowed %v options to %v: in=[%s] out=[%s]",
+		f.name, len(options), len(result), strings.Join(describeOptions(options), "; "), strings.Join(describeOptions(result), "; "))
+	return result
+}
+
+type auditedStrategy struct {
+	name        string
+	inner       expander.Strategy
+	logRecorder record.EventRecorder
+}
+
+// WrapStrategy decorates the final strategy so the chosen option is recorded
+// and emitted as an event on a pod that triggered the scale up.
+func WrapStrategy(name string, inner expander.Strategy, logRecorder record.EventRecorder) expander.Strategy {
+	return &auditedStrategy{name: name, inner: inner, logRecorder: logRecorder}
+}
+
+func (s *auditedStrategy) BestOption(options []expander.Option) *expander.Option {
+	start := time.Now()
+	chosen := s.inner.BestOption(options)
+	chosenCount := 0
+	if chosen != nil {
+		chosenCount = 1
+	}
+	metrics.UpdateExpanderSelection(s.name, len(options), chosenCount, time.Since(start))
+	finalOption := ""
+	var triggeringPod *apiv1.Pod
+	if chosen != nil {
+		finalOption = describeOption(*chosen)
+		if len(chosen.Pods) > 0 {
+			triggeringPod = chosen.Pods[0]
+		}
+	}
+	mutex.Lock()
+	finishDecision(finalOption)
+	mutex.Unlock()
+	klog.V(1).Infof("Expander %s chose option %q out of %v", s.name, finalOption, len(options))
+	if triggeringPod != nil && s.logRecorder != nil {
+		s.logRecorder.Eventf(triggeringPod, apiv1.EventTypeNormal, "ExpanderChoseOption",
+			"Expander %s chose option %q out of %v", s.name, finalOption, len(options))
+	}
+	return chosen
+}
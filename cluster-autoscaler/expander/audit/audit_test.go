@@ -0,0 +1,85 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"testing"
+
+	"k8s.io/autoscaler/cluster-autoscaler/expander"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kube_record "k8s.io/client-go/tools/record"
+	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+type passthroughFilter struct{}
+
+func (passthroughFilter) BestOptions(options []expander.Option, nodeInfo map[string]*schedulerframework.NodeInfo) []expander.Option {
+	return options
+}
+
+type firstOptionStrategy struct{}
+
+func (firstOptionStrategy) BestOption(options []expander.Option) *expander.Option {
+	if len(options) == 0 {
+		return nil
+	}
+	return &options[0]
+}
+
+func TestWrappersRecordDecisions(t *testing.T) {
+	before := len(LastDecisions())
+	recorder := kube_record.NewFakeRecorder(10)
+	filter := WrapFilter("test-filter", passthroughFilter{})
+	strategy := WrapStrategy("test-chain", firstOptionStrategy{}, recorder)
+
+	pod := &apiv1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "p1", Namespace: "default"}}
+	options := []expander.Option{
+		{NodeCount: 1, Debug: "pool=general nodes=1", Pods: []*apiv1.Pod{pod}},
+		{NodeCount: 2, Debug: "pool=gpu nodes=2"},
+	}
+
+	best := strategy.BestOption(filter.BestOptions(options, nil))
+	assert.NotNil(t, best)
+
+	decisions := LastDecisions()
+	assert.Equal(t, before+1, len(decisions))
+	last := decisions[len(decisions)-1]
+	if assert.Equal(t, 1, len(last.Steps)) {
+		assert.Equal(t, "test-filter", last.Steps[0].Strategy)
+		assert.Equal(t, 2, len(last.Steps[0].OptionsIn))
+		assert.Equal(t, 2, len(last.Steps[0].OptionsOut))
+	}
+	assert.Contains(t, last.FinalOption, "pool=general")
+	// The choice is also surfaced as an event on the triggering pod.
+	select {
+	case event := <-recorder.Events:
+		assert.Contains(t, event, "ExpanderChoseOption")
+	default:
+		t.Error("expected an ExpanderChoseOption event on the triggering pod")
+	}
+}
+
+func TestDecisionRetention(t *testing.T) {
+	strategy := WrapStrategy("test-chain", firstOptionStrategy{}, nil)
+	for i := 0; i < keptDecisions+5; i++ {
+		strategy.BestOption([]expander.Option{{NodeCount: 1, Debug: "pool=general"}})
+	}
+	assert.Equal(t, keptDecisions, len(LastDecisions()))
+}
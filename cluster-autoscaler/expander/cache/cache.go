@@ -0,0 +1,114 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cache implements a strategy wrapper that remembers the last expander
+// choice keyed by a hash of the presented options and their triggering pods.
+// When the same set of pending pods persists across loops (e.g. waiting on
+// quota), the cached choice is reused instead of re-running the chain and
+// re-calling external expanders every loop.
+package cache
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"sync"
+	"time"
+
+	"k8s.io/autoscaler/cluster-autoscaler/expander"
+
+	klog "k8s.io/klog/v2"
+)
+
+type cachingStrategy struct {
+	inner expander.Strategy
+	ttl   time.Duration
+
+	mutex             sync.Mutex
+	cachedOptionsKey  uint64
+	cachedChoiceKey   uint64
+	cachedChoiceFound bool
+	cachedAt          time.Time
+}
+
+// WrapStrategy decorates a strategy so repeated invocations with an identical
+// option set within ttl reuse the previous choice.
+func WrapStrategy(inner expander.Strategy, ttl time.Duration) expander.Strategy {
+	return &cachingStrategy{inner: inner, ttl: ttl}
+}
+
+// optionKey fingerprints one option: its size, debug info and the identities
+// of the pods that triggered it.
+func optionKey(option expander.Option) uint64 {
+	podNames := make([]string, 0, len(option.Pods))
+	for _, pod := range option.Pods {
+		podNames = append(podNames, pod.Namespace+"/"+pod.Name)
+	}
+	sort.Strings(podNames)
+	hash := fnv.New64a()
+	fmt.Fprintf(hash, "%v|%s", option.NodeCount, option.Debug)
+	for _, podName := range podNames {
+		fmt.Fprintf(hash, "|%s", podName)
+	}
+	return hash.Sum64()
+}
+
+// optionsKey fingerprints the whole option set, independent of order.
+func optionsKey(options []expander.Option) uint64 {
+	keys := make([]uint64, 0, len(options))
+	for _, option := range options {
+		keys = append(keys, optionKey(option))
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	hash := fnv.New64a()
+	for _, key := range keys {
+		fmt.Fprintf(hash, "%v|", key)
+	}
+	return hash.Sum64()
+}
+
+func (c *cachingStrategy) BestOption(options []expander.Option) *expander.Option {
+	key := optionsKey(options)
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if key == c.cachedOptionsKey && time.Since(c.cachedAt) <= c.ttl {
+		if cached := c.lookupCachedChoice(options); cached != nil || !c.cachedChoiceFound {
+			klog.V(2).Infof("Reusing cached expander choice for unchanged option set with %v options", len(options))
+			return cached
+		}
+	}
+	choice := c.inner.BestOption(options)
+	c.cachedOptionsKey = key
+	c.cachedChoiceFound = choice != nil
+	c.cachedAt = time.Now()
+	if choice != nil {
+		c.cachedChoiceKey = optionKey(*choice)
+	}
+	return choice
+}
+
+// lookupCachedChoice maps the cached choice back onto the current option set.
+func (c *cachingStrategy) lookupCachedChoice(options []expander.Option) *expander.Option {
+	if !c.cachedChoiceFound {
+		return nil
+	}
+	for i := range options {
+		if optionKey(options[i]) == c.cachedChoiceKey {
+			return &options[i]
+		}
+	}
+	return nil
+}
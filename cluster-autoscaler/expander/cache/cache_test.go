@@ -0,0 +1,92 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/expander"
+)
+
+// countingStrategy picks the first option and counts its invocations.
+type countingStrategy struct {
+	calls int
+}
+
+func (s *countingStrategy) BestOption(options []expander.Option) *expander.Option {
+	s.calls++
+	if len(options) == 0 {
+		return nil
+	}
+	return &options[0]
+}
+
+func pendingPod(name string) *apiv1.Pod {
+	return &apiv1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name}}
+}
+
+func TestCachedChoiceReusedForIdenticalOptions(t *testing.T) {
+	inner := &countingStrategy{}
+	s := WrapStrategy(inner, time.Minute)
+	options := []expander.Option{
+		{NodeCount: 1, Debug: "pool=spot", Pods: []*apiv1.Pod{pendingPod("p1")}},
+		{NodeCount: 2, Debug: "pool=general", Pods: []*apiv1.Pod{pendingPod("p1")}},
+	}
+
+	first := s.BestOption(options)
+	second := s.BestOption(options)
+	assert.Equal(t, 1, inner.calls)
+	assert.Equal(t, first.Debug, second.Debug)
+}
+
+func TestCacheMissOnChangedPodSet(t *testing.T) {
+	inner := &countingStrategy{}
+	s := WrapStrategy(inner, time.Minute)
+	options := []expander.Option{
+		{NodeCount: 1, Debug: "pool=spot", Pods: []*apiv1.Pod{pendingPod("p1")}},
+	}
+	s.BestOption(options)
+
+	options[0].Pods = []*apiv1.Pod{pendingPod("p2")}
+	s.BestOption(options)
+	assert.Equal(t, 2, inner.calls)
+}
+
+func TestCacheExpiresAfterTTL(t *testing.T) {
+	inner := &countingStrategy{}
+	s := WrapStrategy(inner, time.Nanosecond)
+	options := []expander.Option{
+		{NodeCount: 1, Debug: "pool=spot", Pods: []*apiv1.Pod{pendingPod("p1")}},
+	}
+	s.BestOption(options)
+	time.Sleep(time.Millisecond)
+	s.BestOption(options)
+	assert.Equal(t, 2, inner.calls)
+}
+
+func TestNilChoiceIsCached(t *testing.T) {
+	inner := &countingStrategy{}
+	s := WrapStrategy(inner, time.Minute)
+	assert.Nil(t, s.BestOption(nil))
+	assert.Nil(t, s.BestOption(nil))
+	assert.Equal(t, 1, inner.calls)
+}
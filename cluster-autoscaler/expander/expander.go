@@ -23,7 +23,7 @@ import (
 
 var (
 	// AvailableExpanders is a list of available expander options
-	AvailableExpanders = []string{RandomExpanderName, MostPodsExpanderName, LeastWasteExpanderName, PriceBasedExpanderName, PriorityBasedExpanderName, GRPCExpanderName}
+	AvailableExpanders = []string{RandomExpanderName, MostPodsExpanderName, WebhookExpanderName, PolicyExpanderName}
 	// RandomExpanderName selects a node group at random
 	RandomExpanderName = "random"
 	// MostPodsExpanderName selects a node group that fits the most pods
@@ -37,6 +37,10 @@ var (
 	PriorityBasedExpanderName = "priority"
 	// GRPCExpanderName uses the gRPC client expander to call to an external gRPC server to select a node group for scale up
 	GRPCExpanderName = "grpc"
+	// WebhookExpanderName POSTs the options to an external HTTP endpoint as JSON to select a node group for scale up
+	WebhookExpanderName = "webhook"
+	// PolicyExpanderName evaluates a CEL-style expression from a configmap against each option to select a node group for scale up
+	PolicyExpanderName = "policy"
 )
 
 // Option describes an option to expand the cluster.
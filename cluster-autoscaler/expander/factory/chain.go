@@ -17,30 +17,85 @@ limitations under the License.
 package factory
 
 import (
+	"time"
+
 	"k8s.io/autoscaler/cluster-autoscaler/expander"
+	"k8s.io/autoscaler/cluster-autoscaler/metrics"
 
-	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
+	klog "k8s.io/klog/v2"
 )
 
 type chainStrategy struct {
-	filters  []expander.Filter
-	fallback expander.Strategy
+	filters []expander.Filter
+	// filterNames labels the filters in failure metrics; entries align with
+	// filters and may be missing when the chain was built without names.
+	filterNames []string
+	fallback    expander.Strategy
+	// timeBudget bounds the total time spent in the filters per invocation;
+	// once exhausted the remaining filters are skipped and the fallback
+	// strategy decides. Zero means no bound.
+	timeBudget time.Duration
 }
 
 func newChainStrategy(filters []expander.Filter, fallback expander.Strategy) expander.Strategy {
+	return newChainStrategyWithTimeBudget(filters, nil, fallback, 0)
+}
+
+func newChainStrategyWithTimeBudget(filters []expander.Filter, filterNames []string, fallback expander.Strategy, timeBudget time.Duration) expander.Strategy {
 	return &chainStrategy{
-		filters:  filters,
-		fallback: fallback,
+		filters:     filters,
+		filterNames: filterNames,
+		fallback:    fallback,
+		timeBudget:  timeBudget,
+	}
+}
+
+func (c *chainStrategy) filterName(index int) string {
+	if index < len(c.filterNames) {
+		return c.filterNames[index]
 	}
+	return "unknown"
 }
 
-func (c *chainStrategy) BestOption(options []expander.Option, nodeInfo map[string]*schedulerframework.NodeInfo) *expander.Option {
+func (c *chainStrategy) BestOption(options []expander.Option) *expander.Option {
+	deadline := time.Now().Add(c.timeBudget)
 	filteredOptions := options
-	for _, filter := range c.filters {
-		filteredOptions = filter.BestOptions(filteredOptions, nodeInfo)
+	for i, filter := range c.filters {
+		if c.timeBudget > 0 {
+			remaining := time.Until(deadline)
+			if remaining <= 0 {
+				klog.Warningf("Expander chain time budget of %v exhausted, falling back with %v options left", c.timeBudget, len(filteredOptions))
+				break
+			}
+			result, ok := runFilterWithTimeout(filter, filteredOptions, remaining)
+			if !ok {
+				metrics.RegisterExpanderExternalFailure(c.filterName(i))
+				klog.Warningf("Expander chain time budget of %v exhausted waiting for expander %s, falling back with %v options left", c.timeBudget, c.filterName(i), len(filteredOptions))
+				break
+			}
+			filteredOptions = result
+		} else {
+			filteredOptions = filter.BestOptions(filteredOptions, nil)
+		}
 		if len(filteredOptions) == 1 {
 			return &filteredOptions[0]
 		}
 	}
-	return c.fallback.BestOption(filteredOptions, nodeInfo)
+	return c.fallback.BestOption(filteredOptions)
+}
+
+// runFilterWithTimeout runs the filter in its own goroutine so a slow external
+// expander cannot hold the loop past the chain's time budget. On timeout the
+// filter's eventual result is discarded.
+func runFilterWithTimeout(filter expander.Filter, options []expander.Option, timeout time.Duration) ([]expander.Option, bool) {
+	resultChan := make(chan []expander.Option, 1)
+	go func() {
+		resultChan <- filter.BestOptions(options, nil)
+	}()
+	select {
+	case result := <-resultChan:
+		return result, true
+	case <-time.After(timeout):
+		return nil, false
+	}
 }
@@ -20,6 +20,7 @@ import (
 	"k8s.io/autoscaler/cluster-autoscaler/expander"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
@@ -46,8 +47,8 @@ func (s *substringTestFilterStrategy) BestOptions(expansionOptions []expander.Op
 
 }
 
-func (s *substringTestFilterStrategy) BestOption(expansionOptions []expander.Option, nodeInfo map[string]*schedulerframework.NodeInfo) *expander.Option {
-	ret := s.BestOptions(expansionOptions, nodeInfo)
+func (s *substringTestFilterStrategy) BestOption(expansionOptions []expander.Option) *expander.Option {
+	ret := s.BestOptions(expansionOptions, nil)
 	if len(ret) == 0 {
 		return nil
 	}
@@ -120,12 +121,35 @@ func TestChainStrategy_BestOption(t *testing.T) {
 	} {
 		t.Run(name, func(t *testing.T) {
 			subject := newChainStrategy(tc.filters, tc.fallback)
-			actual := subject.BestOption(tc.options, nil)
+			actual := subject.BestOption(tc.options)
 			assert.Equal(t, tc.expected, actual)
 		})
 	}
 }
 
+type slowTestFilter struct {
+	delay time.Duration
+}
+
+func (s *slowTestFilter) BestOptions(expansionOptions []expander.Option, nodeInfo map[string]*schedulerframework.NodeInfo) []expander.Option {
+	time.Sleep(s.delay)
+	return expansionOptions
+}
+
+func TestChainStrategy_TimeBudgetExhausted(t *testing.T) {
+	subject := newChainStrategyWithTimeBudget(
+		[]expander.Filter{&slowTestFilter{delay: time.Second}},
+		[]string{"slow"},
+		newSubstringTestFilterStrategy("a"),
+		10*time.Millisecond)
+	// The slow filter times out, so the fallback decides over all options.
+	actual := subject.BestOption([]expander.Option{
+		*newOption("b"),
+		*newOption("a"),
+	})
+	assert.Equal(t, newOption("a"), actual)
+}
+
 func newOption(debug string) *expander.Option {
 	return &expander.Option{
 		Debug: debug,
@@ -17,13 +17,17 @@ limitations under the License.
 package factory
 
 import (
+	"time"
+
 	"k8s.io/autoscaler/cluster-autoscaler/context"
 	"k8s.io/autoscaler/cluster-autoscaler/expander"
-	"k8s.io/autoscaler/cluster-autoscaler/expander/grpcplugin"
+	"k8s.io/autoscaler/cluster-autoscaler/expander/audit"
+	"k8s.io/autoscaler/cluster-autoscaler/expander/cache"
 	"k8s.io/autoscaler/cluster-autoscaler/expander/mostpods"
-	"k8s.io/autoscaler/cluster-autoscaler/expander/priority"
+	"k8s.io/autoscaler/cluster-autoscaler/expander/perpool"
+	"k8s.io/autoscaler/cluster-autoscaler/expander/policy"
 	"k8s.io/autoscaler/cluster-autoscaler/expander/random"
-	"k8s.io/autoscaler/cluster-autoscaler/expander/waste"
+	"k8s.io/autoscaler/cluster-autoscaler/expander/webhook"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/errors"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
 	kube_client "k8s.io/client-go/kubernetes"
@@ -33,8 +37,34 @@ import (
 // take in whole opts and access stuff here
 func ExpanderStrategyFromStrings(expanderFlags []string,
 	autoscalingKubeClients *context.AutoscalingKubeClients, kubeClient kube_client.Interface,
-	configNamespace string, GRPCExpanderCert string, GRPCExpanderURL string) (expander.Strategy, errors.AutoscalerError) {
+	configNamespace string, GRPCExpanderCert string, GRPCExpanderURL string, WebhookExpanderURL string,
+	ExpanderRandomSeed int64, ExpanderChainTimeBudget time.Duration, ExpanderCacheTTL time.Duration) (expander.Strategy, errors.AutoscalerError) {
+	strategy, err := expanderStrategyFromStrings(expanderFlags, autoscalingKubeClients, kubeClient,
+		configNamespace, WebhookExpanderURL, ExpanderRandomSeed, ExpanderChainTimeBudget)
+	if err != nil {
+		return nil, err
+	}
+	if ExpanderCacheTTL > 0 {
+		strategy = cache.WrapStrategy(strategy, ExpanderCacheTTL)
+	}
+	return strategy, nil
+}
+
+// expanderStrategyFromStrings builds the audited expander chain without the
+// cache wrapper, so callers composing several chains can apply the cache once
+// at the outermost level instead of once per sub-chain.
+func expanderStrategyFromStrings(expanderFlags []string,
+	autoscalingKubeClients *context.AutoscalingKubeClients, kubeClient kube_client.Interface,
+	configNamespace string, WebhookExpanderURL string,
+	ExpanderRandomSeed int64, ExpanderChainTimeBudget time.Duration) (expander.Strategy, errors.AutoscalerError) {
+	newRandomFilter := random.NewFilter
+	newRandomStrategy := random.NewStrategy
+	if ExpanderRandomSeed != 0 {
+		newRandomFilter = func() expander.Filter { return random.NewFilterWithSeed(ExpanderRandomSeed) }
+		newRandomStrategy = func() expander.Strategy { return random.NewStrategyWithSeed(ExpanderRandomSeed) }
+	}
 	var filters []expander.Filter
+	var filterNames []string
 	seenExpanders := map[string]struct{}{}
 	strategySeen := false
 	for i, expanderFlag := range expanderFlags {
@@ -48,25 +78,69 @@ func ExpanderStrategyFromStrings(expanderFlags []string,
 
 		switch expanderFlag {
 		case expander.RandomExpanderName:
-			filters = append(filters, random.NewFilter())
+			filters = append(filters, newRandomFilter())
 		case expander.MostPodsExpanderName:
 			filters = append(filters, mostpods.NewFilter())
-		case expander.LeastWasteExpanderName:
-			filters = append(filters, waste.NewFilter())
-		case expander.PriorityBasedExpanderName:
+		case expander.WebhookExpanderName:
+			if WebhookExpanderURL == "" {
+				return nil, errors.NewAutoscalerError(errors.InternalError, "Expander %s requires --webhook-expander-url to be set", expanderFlag)
+			}
+			filters = append(filters, webhook.NewFilter(WebhookExpanderURL))
+		case expander.PolicyExpanderName:
 			// It seems other listers do the same here - they never receive the termination msg on the ch.
 			// This should be currently OK.
 			stopChannel := make(chan struct{})
 			lister := kubernetes.NewConfigMapListerForNamespace(kubeClient, stopChannel, configNamespace)
-			filters = append(filters, priority.NewFilter(lister.ConfigMaps(configNamespace), autoscalingKubeClients.Recorder))
-		case expander.GRPCExpanderName:
-			filters = append(filters, grpcplugin.NewFilter(GRPCExpanderCert, GRPCExpanderURL))
+			filters = append(filters, policy.NewFilter(lister.ConfigMaps(configNamespace), autoscalingKubeClients.Recorder))
 		default:
 			return nil, errors.NewAutoscalerError(errors.InternalError, "Expander %s not supported", expanderFlag)
 		}
 		if _, ok := filters[len(filters)-1].(expander.Strategy); ok {
 			strategySeen = true
 		}
+		// Record every invocation so expander decisions show up in the log
+		// and the debugging snapshot.
+		filters[len(filters)-1] = audit.WrapFilter(expanderFlag, filters[len(filters)-1])
+		filterNames = append(filterNames, expanderFlag)
+	}
+	return audit.WrapStrategy("chain", newChainStrategyWithTimeBudget(filters, filterNames, newRandomStrategy(), ExpanderChainTimeBudget), autoscalingKubeClients.Recorder), nil
+}
+
+// ExpanderStrategyWithPoolOverrides builds the cluster-wide strategy from
+// expanderFlags and, when ExpanderNamesPerPool is set, wraps it so the pools
+// listed there use their own expander chain instead. Options are resolved to
+// pools via the pool=<name> token in their Debug string.
+func ExpanderStrategyWithPoolOverrides(expanderFlags []string,
+	autoscalingKubeClients *context.AutoscalingKubeClients, kubeClient kube_client.Interface,
+	configNamespace string, GRPCExpanderCert string, GRPCExpanderURL string, WebhookExpanderURL string,
+	ExpanderRandomSeed int64, ExpanderChainTimeBudget time.Duration, ExpanderCacheTTL time.Duration,
+	ExpanderNamesPerPool string) (expander.Strategy, errors.AutoscalerError) {
+	fallback, err := expanderStrategyFromStrings(expanderFlags, autoscalingKubeClients, kubeClient,
+		configNamespace, WebhookExpanderURL, ExpanderRandomSeed, ExpanderChainTimeBudget)
+	if err != nil {
+		return nil, err
+	}
+	perPoolFlags, parseErr := perpool.ParseOverridesFlag(ExpanderNamesPerPool)
+	if parseErr != nil {
+		return nil, errors.ToAutoscalerError(errors.InternalError, parseErr)
+	}
+	strategy := fallback
+	if len(perPoolFlags) > 0 {
+		overrides := map[string]expander.Strategy{}
+		for pool, poolFlags := range perPoolFlags {
+			poolStrategy, err := expanderStrategyFromStrings(poolFlags, autoscalingKubeClients, kubeClient,
+				configNamespace, WebhookExpanderURL, ExpanderRandomSeed, ExpanderChainTimeBudget)
+			if err != nil {
+				return nil, err
+			}
+			overrides[pool] = poolStrategy
+		}
+		strategy = perpool.NewStrategy(overrides, fallback)
+	}
+	// The cache wraps the final strategy exactly once, so per-pool sub-chains
+	// share one cache instead of keeping separate, possibly stale ones.
+	if ExpanderCacheTTL > 0 {
+		strategy = cache.WrapStrategy(strategy, ExpanderCacheTTL)
 	}
-	return newChainStrategy(filters, random.NewStrategy()), nil
+	return strategy, nil
 }
@@ -0,0 +1,108 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package factory
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"k8s.io/autoscaler/cluster-autoscaler/context"
+	"k8s.io/autoscaler/cluster-autoscaler/expander"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	kube_record "k8s.io/client-go/tools/record"
+)
+
+func testStrategyFromStrings(t *testing.T, expanderFlags []string) (expander.Strategy, error) {
+	t.Helper()
+	kubeClients := &context.AutoscalingKubeClients{Recorder: kube_record.NewFakeRecorder(100)}
+	strategy, err := ExpanderStrategyFromStrings(expanderFlags, kubeClients, fake.NewSimpleClientset(),
+		"kube-system", "", "", "http://expander.example.com", 0, time.Duration(0), time.Duration(0))
+	if err != nil {
+		return strategy, err
+	}
+	return strategy, nil
+}
+
+func TestExpanderStrategyFromStringsPolicy(t *testing.T) {
+	strategy, err := testStrategyFromStrings(t, []string{expander.PolicyExpanderName})
+	assert.NoError(t, err)
+	assert.NotNil(t, strategy)
+
+	// Without a policy configmap the filter keeps all options, so the chain's
+	// random fallback still picks one of them.
+	best := strategy.BestOption([]expander.Option{
+		{NodeCount: 1, Debug: "pool=general nodes=1"},
+		{NodeCount: 2, Debug: "pool=gpu nodes=2"},
+	})
+	assert.NotNil(t, best)
+}
+
+func TestExpanderStrategyWithPoolOverrides(t *testing.T) {
+	kubeClients := &context.AutoscalingKubeClients{Recorder: kube_record.NewFakeRecorder(100)}
+	strategy, err := ExpanderStrategyWithPoolOverrides([]string{expander.RandomExpanderName},
+		kubeClients, fake.NewSimpleClientset(), "kube-system", "", "", "", 0, time.Duration(0), time.Duration(0),
+		"gpu:most-pods")
+	assert.NoError(t, err)
+	assert.NotNil(t, strategy)
+
+	// The gpu pool resolves through its most-pods override, so the option
+	// helping more pods wins deterministically.
+	smaller := expander.Option{NodeCount: 1, Debug: "pool=gpu nodes=1", Pods: []*apiv1.Pod{{}}}
+	bigger := expander.Option{NodeCount: 2, Debug: "pool=gpu nodes=2", Pods: []*apiv1.Pod{{}, {}}}
+	best := strategy.BestOption([]expander.Option{smaller, bigger})
+	if assert.NotNil(t, best) {
+		assert.Equal(t, bigger.Debug, best.Debug)
+	}
+
+	_, err = ExpanderStrategyWithPoolOverrides([]string{expander.RandomExpanderName},
+		kubeClients, fake.NewSimpleClientset(), "kube-system", "", "", "", 0, time.Duration(0), time.Duration(0),
+		"not-an-override")
+	assert.Error(t, err)
+}
+
+func TestExpanderStrategyCachesOutermostDecision(t *testing.T) {
+	kubeClients := &context.AutoscalingKubeClients{Recorder: kube_record.NewFakeRecorder(100)}
+	strategy, err := ExpanderStrategyWithPoolOverrides([]string{expander.RandomExpanderName},
+		kubeClients, fake.NewSimpleClientset(), "kube-system", "", "", "", 0, time.Duration(0), time.Minute,
+		"gpu:most-pods")
+	assert.NoError(t, err)
+
+	var options []expander.Option
+	for i := 0; i < 10; i++ {
+		options = append(options, expander.Option{NodeCount: i + 1, Debug: fmt.Sprintf("pool=general nodes=%d", i+1)})
+	}
+	// With the cache wrapped around the whole strategy, repeated calls for the
+	// same options return the first random pick instead of a fresh one.
+	first := strategy.BestOption(options)
+	if assert.NotNil(t, first) {
+		for i := 0; i < 10; i++ {
+			assert.Equal(t, first, strategy.BestOption(options))
+		}
+	}
+}
+
+func TestExpanderStrategyFromStringsRejectsBadFlags(t *testing.T) {
+	_, err := testStrategyFromStrings(t, []string{"unknown"})
+	assert.Error(t, err)
+
+	_, err = testStrategyFromStrings(t, []string{expander.RandomExpanderName, expander.RandomExpanderName})
+	assert.Error(t, err)
+}
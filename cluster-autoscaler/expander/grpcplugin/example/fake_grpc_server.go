@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"log"
 	"net"
+	"strings"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
@@ -33,7 +34,7 @@ import (
 // copy the protos/expander.pb.go to your other application's repo, so it has access to the protobuf definitions
 
 // Serve should be called by the main() function in main.go of the Expander Server repo to start serving
-func Serve(certPath string, keyPath string, port uint) {
+func Serve(certPath string, keyPath string, port uint, policy Policy) {
 
 	var grpcServer *grpc.Server
 
@@ -51,7 +52,7 @@ func Serve(certPath string, keyPath string, port uint) {
 
 	netListener := getNetListener(port)
 
-	expanderServerImpl := NewExpanderServerImpl()
+	expanderServerImpl := NewExpanderServerImpl(policy)
 
 	protos.RegisterExpanderServer(grpcServer, expanderServerImpl)
 
@@ -72,33 +73,86 @@ func getNetListener(port uint) net.Listener {
 	return lis
 }
 
+// Policy selects the best options out of the ones passed by the gRPC client
+// in CA. The sample policies below are tested starting points; replace them
+// with any arbitrary logic.
+type Policy func(options []*protos.Option) []*protos.Option
+
+// LongestNodeGroupIDPolicy chooses the Option with the longest NodeGroupID
+// name.
+func LongestNodeGroupIDPolicy(options []*protos.Option) []*protos.Option {
+	longest := 0
+	var choice *protos.Option
+	for _, opt := range options {
+		if len(opt.NodeGroupId) > longest {
+			longest = len(opt.NodeGroupId)
+			choice = opt
+		}
+	}
+	if choice == nil {
+		return nil
+	}
+	return []*protos.Option{choice}
+}
+
+// FewestNodesPolicy chooses the Option that adds the fewest nodes, breaking
+// ties by the first option received.
+func FewestNodesPolicy(options []*protos.Option) []*protos.Option {
+	var choice *protos.Option
+	for _, opt := range options {
+		if choice == nil || opt.NodeCount < choice.NodeCount {
+			choice = opt
+		}
+	}
+	if choice == nil {
+		return nil
+	}
+	return []*protos.Option{choice}
+}
+
+// PreferredOrderPolicy chooses the first Option whose NodeGroupID starts with
+// one of the given prefixes, in prefix order. Options matching no prefix are
+// only used when nothing matches.
+func PreferredOrderPolicy(prefixes []string) Policy {
+	return func(options []*protos.Option) []*protos.Option {
+		for _, prefix := range prefixes {
+			for _, opt := range options {
+				if strings.HasPrefix(opt.NodeGroupId, prefix) {
+					return []*protos.Option{opt}
+				}
+			}
+		}
+		return options
+	}
+}
+
 // ExpanderServerImpl is an implementation of Expander Server from proto definition
-type ExpanderServerImpl struct{}
+type ExpanderServerImpl struct {
+	policy Policy
+}
 
 // NewExpanderServerImpl is this Expander's implementation of the server
-func NewExpanderServerImpl() *ExpanderServerImpl {
-	return &ExpanderServerImpl{}
+func NewExpanderServerImpl(policy Policy) *ExpanderServerImpl {
+	if policy == nil {
+		policy = LongestNodeGroupIDPolicy
+	}
+	return &ExpanderServerImpl{policy: policy}
 }
 
 // BestOptions method filters out the best options of all options passed from the gRPC Client in CA, according to the defined strategy.
 func (ServerImpl *ExpanderServerImpl) BestOptions(ctx context.Context, req *protos.BestOptionsRequest) (*protos.BestOptionsResponse, error) {
 	opts := req.GetOptions()
 	log.Printf("Received BestOption Request with %v options", len(opts))
-
-	// This strategy simply chooses the Option with the longest NodeGroupID name, but can be replaced with any arbitrary logic
-	longest := 0
-	var choice *protos.Option
 	for _, opt := range opts {
 		log.Println(opt.NodeGroupId)
-		if len(opt.NodeGroupId) > longest {
-			choice = opt
-		}
 	}
 
-	log.Print("returned bestOptions with option: ", choice.NodeGroupId)
+	choices := ServerImpl.policy(opts)
+	for _, choice := range choices {
+		log.Print("returned bestOptions with option: ", choice.NodeGroupId)
+	}
 
-	// Return just one option for now
 	return &protos.BestOptionsResponse{
-		Options: []*protos.Option{choice},
+		Options: choices,
 	}, nil
 }
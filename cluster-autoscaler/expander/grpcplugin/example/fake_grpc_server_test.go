@@ -0,0 +1,79 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package example
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"k8s.io/autoscaler/cluster-autoscaler/expander/grpcplugin/protos"
+)
+
+func makeOptions() []*protos.Option {
+	return []*protos.Option{
+		{NodeGroupId: "spot-pool", NodeCount: 3},
+		{NodeGroupId: "on-demand-pool-large", NodeCount: 2},
+		{NodeGroupId: "gpu-pool", NodeCount: 1},
+	}
+}
+
+func nodeGroupIds(options []*protos.Option) []string {
+	ids := make([]string, 0, len(options))
+	for _, opt := range options {
+		ids = append(ids, opt.NodeGroupId)
+	}
+	return ids
+}
+
+func TestLongestNodeGroupIDPolicy(t *testing.T) {
+	choices := LongestNodeGroupIDPolicy(makeOptions())
+	assert.Equal(t, []string{"on-demand-pool-large"}, nodeGroupIds(choices))
+	assert.Nil(t, LongestNodeGroupIDPolicy(nil))
+}
+
+func TestFewestNodesPolicy(t *testing.T) {
+	choices := FewestNodesPolicy(makeOptions())
+	assert.Equal(t, []string{"gpu-pool"}, nodeGroupIds(choices))
+	assert.Nil(t, FewestNodesPolicy(nil))
+}
+
+func TestPreferredOrderPolicy(t *testing.T) {
+	policy := PreferredOrderPolicy([]string{"gpu-", "spot-"})
+	choices := policy(makeOptions())
+	assert.Equal(t, []string{"gpu-pool"}, nodeGroupIds(choices))
+
+	// When no prefix matches, all options are passed through.
+	policy = PreferredOrderPolicy([]string{"arm-"})
+	choices = policy(makeOptions())
+	assert.Equal(t, []string{"spot-pool", "on-demand-pool-large", "gpu-pool"}, nodeGroupIds(choices))
+}
+
+func TestBestOptionsAppliesPolicy(t *testing.T) {
+	server := NewExpanderServerImpl(FewestNodesPolicy)
+	resp, err := server.BestOptions(context.Background(), &protos.BestOptionsRequest{Options: makeOptions()})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"gpu-pool"}, nodeGroupIds(resp.Options))
+}
+
+func TestBestOptionsEmptyRequest(t *testing.T) {
+	server := NewExpanderServerImpl(nil)
+	resp, err := server.BestOptions(context.Background(), &protos.BestOptionsRequest{})
+	assert.NoError(t, err)
+	assert.Empty(t, resp.Options)
+}
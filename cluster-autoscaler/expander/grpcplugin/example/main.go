@@ -16,15 +16,33 @@ limitations under the License.
 
 package example
 
-import "flag"
+import (
+	"flag"
+	"log"
+	"strings"
+)
 
 func main() {
 
 	certPath := flag.String("cert-path", "", "Path to cert file for gRPC Expander Server")
 	keyPath := flag.String("key-path", "", "Path to private key for gRPC Expander Server")
 	port := flag.Uint("port", 7000, "Port number for server to listen on")
+	policyName := flag.String("policy", "longest-id", "Sample policy to select options with: longest-id, fewest-nodes or preferred-order")
+	preferredOrder := flag.String("preferred-order", "", "Comma separated node group ID prefixes for the preferred-order policy, most preferred first")
 
 	flag.Parse()
 
-	Serve(*certPath, *keyPath, *port)
+	var policy Policy
+	switch *policyName {
+	case "longest-id":
+		policy = LongestNodeGroupIDPolicy
+	case "fewest-nodes":
+		policy = FewestNodesPolicy
+	case "preferred-order":
+		policy = PreferredOrderPolicy(strings.Split(*preferredOrder, ","))
+	default:
+		log.Fatalf("Unknown policy %q", *policyName)
+	}
+
+	Serve(*certPath, *keyPath, *port, policy)
 }
@@ -0,0 +1,109 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package perpool implements a dispatching expander strategy that resolves
+// each expansion option to its worker pool and lets a pool-specific strategy
+// pick among that pool's options, so e.g. the spot pool can use the priority
+// expander while the rest of the cluster uses least-waste. Pools without an
+// override, and the final choice between pool winners, fall through to the
+// cluster-wide strategy.
+package perpool
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/autoscaler/cluster-autoscaler/expander"
+
+	klog "k8s.io/klog/v2"
+)
+
+// ParseOverridesFlag parses the --expander-per-pool flag value into a map from
+// pool name to the expander names configured for it. Entries are comma
+// separated, each of the form pool:expander or pool:expander1;expander2 for a
+// chain, e.g. "spot:priority,general:least-waste;random".
+func ParseOverridesFlag(value string) (map[string][]string, error) {
+	overrides := map[string][]string{}
+	if value == "" {
+		return overrides, nil
+	}
+	for _, entry := range strings.Split(value, ",") {
+		parts := strings.SplitN(entry, ":", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid per-pool expander entry %q, expected pool:expander", entry)
+		}
+		if _, seen := overrides[parts[0]]; seen {
+			return nil, fmt.Errorf("pool %s was specified multiple times in per-pool expander overrides", parts[0])
+		}
+		overrides[parts[0]] = strings.Split(parts[1], ";")
+	}
+	return overrides, nil
+}
+
+type perPoolStrategy struct {
+	overrides map[string]expander.Strategy
+	fallback  expander.Strategy
+}
+
+// NewStrategy returns a strategy that delegates options of the pools listed in
+// overrides to their own strategy and everything else to fallback.
+func NewStrategy(overrides map[string]expander.Strategy, fallback expander.Strategy) expander.Strategy {
+	return &perPoolStrategy{
+		overrides: overrides,
+		fallback:  fallback,
+	}
+}
+
+// PoolOf extracts the worker pool an option belongs to from its Debug string,
+// which by convention in this fork carries space separated key=value tokens
+// including pool=<name>. Options without a pool token return "".
+func PoolOf(option expander.Option) string {
+	for _, token := range strings.Fields(option.Debug) {
+		if strings.HasPrefix(token, "pool=") {
+			return strings.TrimPrefix(token, "pool=")
+		}
+	}
+	return ""
+}
+
+// BestOption partitions the options by pool, asks each overridden pool's
+// strategy for that pool's winner, and lets the fallback strategy decide
+// between the pool winners and the options of pools without an override.
+func (p *perPoolStrategy) BestOption(options []expander.Option) *expander.Option {
+	if len(p.overrides) == 0 {
+		return p.fallback.BestOption(options)
+	}
+	byPool := map[string][]expander.Option{}
+	var candidates []expander.Option
+	for _, option := range options {
+		pool := PoolOf(option)
+		if _, overridden := p.overrides[pool]; overridden {
+			byPool[pool] = append(byPool[pool], option)
+		} else {
+			candidates = append(candidates, option)
+		}
+	}
+	for pool, poolOptions := range byPool {
+		if best := p.overrides[pool].BestOption(poolOptions); best != nil {
+			klog.V(2).Infof("Pool %s expander override selected option %s", pool, best.Debug)
+			candidates = append(candidates, *best)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	return p.fallback.BestOption(candidates)
+}
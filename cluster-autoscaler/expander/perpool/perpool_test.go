@@ -0,0 +1,99 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package perpool
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"k8s.io/autoscaler/cluster-autoscaler/expander"
+)
+
+// firstStrategy deterministically picks the first option it is given.
+type firstStrategy struct{}
+
+func (firstStrategy) BestOption(options []expander.Option) *expander.Option {
+	if len(options) == 0 {
+		return nil
+	}
+	return &options[0]
+}
+
+// lastStrategy deterministically picks the last option it is given.
+type lastStrategy struct{}
+
+func (lastStrategy) BestOption(options []expander.Option) *expander.Option {
+	if len(options) == 0 {
+		return nil
+	}
+	return &options[len(options)-1]
+}
+
+func TestParseOverridesFlag(t *testing.T) {
+	overrides, err := ParseOverridesFlag("spot:priority,general:least-waste;random")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"spot":    {"priority"},
+		"general": {"least-waste", "random"},
+	}, overrides)
+
+	overrides, err = ParseOverridesFlag("")
+	assert.NoError(t, err)
+	assert.Empty(t, overrides)
+
+	_, err = ParseOverridesFlag("spot")
+	assert.Error(t, err)
+	_, err = ParseOverridesFlag("spot:priority,spot:random")
+	assert.Error(t, err)
+}
+
+func TestPoolOf(t *testing.T) {
+	assert.Equal(t, "gpu", PoolOf(expander.Option{Debug: "pool=gpu price=2.5"}))
+	assert.Equal(t, "", PoolOf(expander.Option{Debug: "no pool token"}))
+}
+
+func TestBestOptionWithoutOverrides(t *testing.T) {
+	s := NewStrategy(nil, lastStrategy{})
+	options := []expander.Option{
+		{Debug: "pool=spot"},
+		{Debug: "pool=general"},
+	}
+	assert.Equal(t, &options[1], s.BestOption(options))
+}
+
+func TestBestOptionDispatchesToPoolOverride(t *testing.T) {
+	s := NewStrategy(map[string]expander.Strategy{"spot": firstStrategy{}}, firstStrategy{})
+	options := []expander.Option{
+		{Debug: "pool=general"},
+		{Debug: "pool=spot idx=1"},
+		{Debug: "pool=spot idx=2"},
+	}
+	// The spot override picks its first option; the fallback then decides
+	// between the general option and the spot winner.
+	best := s.BestOption(options)
+	assert.Equal(t, "pool=general", best.Debug)
+
+	onlySpot := []expander.Option{options[1], options[2]}
+	best = s.BestOption(onlySpot)
+	assert.Equal(t, "pool=spot idx=1", best.Debug)
+}
+
+func TestBestOptionNoOptions(t *testing.T) {
+	s := NewStrategy(map[string]expander.Strategy{"spot": firstStrategy{}}, firstStrategy{})
+	assert.Nil(t, s.BestOption(nil))
+}
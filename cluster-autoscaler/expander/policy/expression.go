@@ -0,0 +1,428 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// The policy expander evaluates a small CEL-style boolean expression per
+// expansion option. Supported are the operators || && ! == != < <= > >= + -
+// * /, parentheses, number and quoted string literals, the constants true and
+// false, and identifiers resolved against the option's attributes. No external
+// evaluation library is vendored in this fork, so the subset is implemented
+// here; it intentionally mirrors CEL syntax so policies can later move to a
+// full engine unchanged.
+
+// exprValue is a dynamically typed expression result.
+type exprValue struct {
+	kind   valueKind
+	number float64
+	str    string
+	truth  bool
+}
+
+type valueKind int
+
+const (
+	numberValue valueKind = iota
+	stringValue
+	boolValue
+)
+
+func numberOf(value float64) exprValue { return exprValue{kind: numberValue, number: value} }
+func stringOf(value string) exprValue  { return exprValue{kind: stringValue, str: value} }
+func boolOf(value bool) exprValue      { return exprValue{kind: boolValue, truth: value} }
+
+func (v exprValue) String() string {
+	switch v.kind {
+	case numberValue:
+		return strconv.FormatFloat(v.number, 'f', -1, 64)
+	case stringValue:
+		return v.str
+	default:
+		return strconv.FormatBool(v.truth)
+	}
+}
+
+// exprNode is a parsed expression that can be evaluated against attributes.
+type exprNode interface {
+	eval(attributes map[string]exprValue) (exprValue, error)
+}
+
+type literalNode struct{ value exprValue }
+
+func (n *literalNode) eval(map[string]exprValue) (exprValue, error) { return n.value, nil }
+
+type identNode struct{ name string }
+
+func (n *identNode) eval(attributes map[string]exprValue) (exprValue, error) {
+	value, found := attributes[n.name]
+	if !found {
+		return exprValue{}, fmt.Errorf("unknown attribute %q", n.name)
+	}
+	return value, nil
+}
+
+type unaryNode struct {
+	op      string
+	operand exprNode
+}
+
+func (n *unaryNode) eval(attributes map[string]exprValue) (exprValue, error) {
+	value, err := n.operand.eval(attributes)
+	if err != nil {
+		return exprValue{}, err
+	}
+	switch n.op {
+	case "!":
+		if value.kind != boolValue {
+			return exprValue{}, fmt.Errorf("operator ! needs a boolean, got %v", value)
+		}
+		return boolOf(!value.truth), nil
+	case "-":
+		if value.kind != numberValue {
+			return exprValue{}, fmt.Errorf("operator - needs a number, got %v", value)
+		}
+		return numberOf(-value.number), nil
+	}
+	return exprValue{}, fmt.Errorf("unknown unary operator %q", n.op)
+}
+
+type binaryNode struct {
+	op          string
+	left, right exprNode
+}
+
+func (n *binaryNode) eval(attributes map[string]exprValue) (exprValue, error) {
+	left, err := n.left.eval(attributes)
+	if err != nil {
+		return exprValue{}, err
+	}
+	// Short-circuit the logical operators before evaluating the right side.
+	switch n.op {
+	case "&&", "||":
+		if left.kind != boolValue {
+			return exprValue{}, fmt.Errorf("operator %s needs booleans, got %v", n.op, left)
+		}
+		if n.op == "&&" && !left.truth {
+			return boolOf(false), nil
+		}
+		if n.op == "||" && left.truth {
+			return boolOf(true), nil
+		}
+	}
+	right, err := n.right.eval(attributes)
+	if err != nil {
+		return exprValue{}, err
+	}
+	switch n.op {
+	case "&&", "||":
+		if right.kind != boolValue {
+			return exprValue{}, fmt.Errorf("operator %s needs booleans, got %v", n.op, right)
+		}
+		return boolOf(right.truth), nil
+	case "==", "!=":
+		equal, err := valuesEqual(left, right)
+		if err != nil {
+			return exprValue{}, err
+		}
+		if n.op == "!=" {
+			equal = !equal
+		}
+		return boolOf(equal), nil
+	case "<", "<=", ">", ">=":
+		return compareValues(n.op, left, right)
+	case "+", "-", "*", "/":
+		if n.op == "+" && left.kind == stringValue && right.kind == stringValue {
+			return stringOf(left.str + right.str), nil
+		}
+		if left.kind != numberValue || right.kind != numberValue {
+			return exprValue{}, fmt.Errorf("operator %s needs numbers, got %v and %v", n.op, left, right)
+		}
+		switch n.op {
+		case "+":
+			return numberOf(left.number + right.number), nil
+		case "-":
+			return numberOf(left.number - right.number), nil
+		case "*":
+			return numberOf(left.number * right.number), nil
+		default:
+			if right.number == 0 {
+				return exprValue{}, fmt.Errorf("division by zero")
+			}
+			return numberOf(left.number / right.number), nil
+		}
+	}
+	return exprValue{}, fmt.Errorf("unknown operator %q", n.op)
+}
+
+func valuesEqual(left, right exprValue) (bool, error) {
+	if left.kind != right.kind {
+		return false, fmt.Errorf("cannot compare %v with %v", left, right)
+	}
+	switch left.kind {
+	case numberValue:
+		return left.number == right.number, nil
+	case stringValue:
+		return left.str == right.str, nil
+	default:
+		return left.truth == right.truth, nil
+	}
+}
+
+func compareValues(op string, left, right exprValue) (exprValue, error) {
+	var less, equal bool
+	switch {
+	case left.kind == numberValue && right.kind == numberValue:
+		less, equal = left.number < right.number, left.number == right.number
+	case left.kind == stringValue && right.kind == stringValue:
+		less, equal = left.str < right.str, left.str == right.str
+	default:
+		return exprValue{}, fmt.Errorf("operator %s needs two numbers or two strings, got %v and %v", op, left, right)
+	}
+	switch op {
+	case "<":
+		return boolOf(less), nil
+	case "<=":
+		return boolOf(less || equal), nil
+	case ">":
+		return boolOf(!less && !equal), nil
+	default:
+		return boolOf(!less), nil
+	}
+}
+
+// parseExpression parses the expression text into an evaluable tree.
+func parseExpression(text string) (exprNode, error) {
+	tokens, err := tokenize(text)
+	if err != nil {
+		return nil, err
+	}
+	parser := &exprParser{tokens: tokens}
+	node, err := parser.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if parser.position != len(parser.tokens) {
+		return nil, fmt.Errorf("unexpected %q", parser.tokens[parser.position])
+	}
+	return node, nil
+}
+
+func tokenize(text string) ([]string, error) {
+	var tokens []string
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsDigit(r):
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, string(runes[start:i]))
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, string(runes[start:i]))
+		case r == '\'' || r == '"':
+			quote := r
+			i++
+			start := i
+			for i < len(runes) && runes[i] != quote {
+				i++
+			}
+			if i == len(runes) {
+				return nil, fmt.Errorf("unterminated string literal")
+			}
+			tokens = append(tokens, string(quote)+string(runes[start:i]))
+			i++
+		case strings.ContainsRune("()+-*/", r):
+			tokens = append(tokens, string(r))
+			i++
+		case r == '&' || r == '|':
+			if i+1 >= len(runes) || runes[i+1] != r {
+				return nil, fmt.Errorf("unexpected %q", string(r))
+			}
+			tokens = append(tokens, string(r)+string(r))
+			i += 2
+		case r == '=' || r == '!' || r == '<' || r == '>':
+			if i+1 < len(runes) && runes[i+1] == '=' {
+				tokens = append(tokens, string(r)+"=")
+				i += 2
+			} else if r == '=' {
+				return nil, fmt.Errorf("unexpected %q, did you mean ==", string(r))
+			} else {
+				tokens = append(tokens, string(r))
+				i++
+			}
+		default:
+			return nil, fmt.Errorf("unexpected %q", string(r))
+		}
+	}
+	return tokens, nil
+}
+
+type exprParser struct {
+	tokens   []string
+	position int
+}
+
+func (p *exprParser) peek() string {
+	if p.position < len(p.tokens) {
+		return p.tokens[p.position]
+	}
+	return ""
+}
+
+func (p *exprParser) next() string {
+	token := p.peek()
+	p.position++
+	return token
+}
+
+func (p *exprParser) parseOr() (exprNode, error) {
+	node, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "||" {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		node = &binaryNode{op: "||", left: node, right: right}
+	}
+	return node, nil
+}
+
+func (p *exprParser) parseAnd() (exprNode, error) {
+	node, err := p.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "&&" {
+		p.next()
+		right, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		node = &binaryNode{op: "&&", left: node, right: right}
+	}
+	return node, nil
+}
+
+func (p *exprParser) parseComparison() (exprNode, error) {
+	node, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	switch p.peek() {
+	case "==", "!=", "<", "<=", ">", ">=":
+		op := p.next()
+		right, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		node = &binaryNode{op: op, left: node, right: right}
+	}
+	return node, nil
+}
+
+func (p *exprParser) parseAdditive() (exprNode, error) {
+	node, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "+" || p.peek() == "-" {
+		op := p.next()
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		node = &binaryNode{op: op, left: node, right: right}
+	}
+	return node, nil
+}
+
+func (p *exprParser) parseMultiplicative() (exprNode, error) {
+	node, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "*" || p.peek() == "/" {
+		op := p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		node = &binaryNode{op: op, left: node, right: right}
+	}
+	return node, nil
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if p.peek() == "!" || p.peek() == "-" {
+		op := p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{op: op, operand: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	token := p.next()
+	switch {
+	case token == "":
+		return nil, fmt.Errorf("unexpected end of expression")
+	case token == "(":
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, fmt.Errorf("missing closing parenthesis")
+		}
+		return node, nil
+	case token == "true":
+		return &literalNode{value: boolOf(true)}, nil
+	case token == "false":
+		return &literalNode{value: boolOf(false)}, nil
+	case token[0] == '\'' || token[0] == '"':
+		return &literalNode{value: stringOf(token[1:])}, nil
+	case unicode.IsDigit(rune(token[0])):
+		number, err := strconv.ParseFloat(token, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", token)
+		}
+		return &literalNode{value: numberOf(number)}, nil
+	default:
+		return &identNode{name: token}, nil
+	}
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func evalBool(t *testing.T, text string, attributes map[string]exprValue) bool {
+	node, err := parseExpression(text)
+	assert.NoError(t, err)
+	result, err := node.eval(attributes)
+	assert.NoError(t, err)
+	assert.Equal(t, boolValue, result.kind)
+	return result.truth
+}
+
+func TestExpressionEvaluation(t *testing.T) {
+	attributes := map[string]exprValue{
+		"nodeCount": numberOf(3),
+		"podCount":  numberOf(7),
+		"pool":      stringOf("gpu"),
+		"price":     numberOf(2.5),
+	}
+
+	assert.True(t, evalBool(t, "nodeCount < 5", attributes))
+	assert.False(t, evalBool(t, "nodeCount >= 5", attributes))
+	assert.True(t, evalBool(t, "pool == 'gpu' && price <= 3", attributes))
+	assert.True(t, evalBool(t, `pool != "cpu" || podCount == 0`, attributes))
+	assert.True(t, evalBool(t, "!(pool == 'cpu')", attributes))
+	assert.True(t, evalBool(t, "price * nodeCount < 10", attributes))
+	assert.False(t, evalBool(t, "false", attributes))
+}
+
+func TestExpressionErrors(t *testing.T) {
+	_, err := parseExpression("nodeCount <")
+	assert.Error(t, err)
+	_, err = parseExpression("pool = 'gpu'")
+	assert.Error(t, err)
+	_, err = parseExpression("(nodeCount > 1")
+	assert.Error(t, err)
+	_, err = parseExpression("'unterminated")
+	assert.Error(t, err)
+
+	node, err := parseExpression("missing > 1")
+	assert.NoError(t, err)
+	_, err = node.eval(map[string]exprValue{})
+	assert.Error(t, err)
+
+	node, err = parseExpression("pool > 1")
+	assert.NoError(t, err)
+	_, err = node.eval(map[string]exprValue{"pool": stringOf("gpu")})
+	assert.Error(t, err)
+}
+
+func TestOptionAttributesFromDebug(t *testing.T) {
+	attributes := map[string]exprValue{}
+	for name, value := range optionAttributes(optionWithDebug("pool=gpu price=2.5 zone=HCM-01")) {
+		attributes[name] = value
+	}
+
+	assert.Equal(t, stringOf("gpu"), attributes["pool"])
+	assert.Equal(t, numberOf(2.5), attributes["price"])
+	assert.Equal(t, stringOf("HCM-01"), attributes["zone"])
+	assert.True(t, evalBool(t, "pool == 'gpu' && zone == 'HCM-01'", attributes))
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package policy implements an in-process expander whose selection logic is a
+// CEL-style boolean expression over option attributes, configured through a
+// configmap. Simple placement policies therefore need no external expander
+// service: the expression is evaluated per option and the options it accepts
+// are kept.
+package policy
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/autoscaler/cluster-autoscaler/expander"
+
+	apiv1 "k8s.io/api/core/v1"
+	v1lister "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/record"
+	klog "k8s.io/klog/v2"
+	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+const (
+	// PolicyConfigMapName defines the name of the ConfigMap holding the policy
+	// expander expression.
+	PolicyConfigMapName = "cluster-autoscaler-policy-expander"
+	// ConfigMapKey defines the key under which the expression is stored.
+	ConfigMapKey = "policy"
+)
+
+type policy struct {
+	logRecorder     record.EventRecorder
+	configMapLister v1lister.ConfigMapNamespaceLister
+	// compiledText and compiled cache the last successfully parsed expression
+	// so it is not re-parsed every iteration.
+	compiledText string
+	compiled     exprNode
+}
+
+// NewFilter returns an expansion filter that keeps the options accepted by the
+// configured policy expression.
+func NewFilter(configMapLister v1lister.ConfigMapNamespaceLister,
+	logRecorder record.EventRecorder) expander.Filter {
+	return &policy{
+		logRecorder:     logRecorder,
+		configMapLister: configMapLister,
+	}
+}
+
+func (p *policy) reloadExpression() (exprNode, error) {
+	cm, err := p.configMapLister.Get(PolicyConfigMapName)
+	if err != nil {
+		return nil, fmt.Errorf("policy expander config map %s not found: %v", PolicyConfigMapName, err)
+	}
+	text, found := cm.Data[ConfigMapKey]
+	if !found {
+		msg := fmt.Sprintf("Wrong configmap for policy expander, doesn't contain %s key. Ignoring update.", ConfigMapKey)
+		p.logConfigWarning(cm, "PolicyConfigMapInvalid", msg)
+		return nil, fmt.Errorf(msg)
+	}
+	text = strings.TrimSpace(text)
+	if text == p.compiledText && p.compiled != nil {
+		return p.compiled, nil
+	}
+	compiled, err := parseExpression(text)
+	if err != nil {
+		msg := fmt.Sprintf("Wrong expression for policy expander: %v. Ignoring update.", err)
+		p.logConfigWarning(cm, "PolicyConfigMapInvalid", msg)
+		return nil, fmt.Errorf(msg)
+	}
+	p.compiledText = text
+	p.compiled = compiled
+	klog.V(4).Info("Successfully loaded policy expression from configmap.")
+	return compiled, nil
+}
+
+func (p *policy) logConfigWarning(cm *apiv1.ConfigMap, reason, msg string) {
+	p.logRecorder.Event(cm, apiv1.EventTypeWarning, reason, msg)
+	klog.Warning(msg)
+}
+
+// optionAttributes builds the attribute set one expression evaluation sees.
+// Besides nodeCount, podCount and debug, any key=value tokens in the option's
+// Debug string (e.g. pool=gpu price=2.5 zone=HCM-01) are exposed as string
+// attributes, numbers as numbers.
+func optionAttributes(option expander.Option) map[string]exprValue {
+	attributes := map[string]exprValue{
+		"nodeCount": numberOf(float64(option.NodeCount)),
+		"podCount":  numberOf(float64(len(option.Pods))),
+		"debug":     stringOf(option.Debug),
+	}
+	for _, token := range strings.Fields(option.Debug) {
+		parts := strings.SplitN(token, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			continue
+		}
+		if _, reserved := attributes[parts[0]]; reserved {
+			continue
+		}
+		if number, err := parseNumber(parts[1]); err == nil {
+			attributes[parts[0]] = numberOf(number)
+		} else {
+			attributes[parts[0]] = stringOf(parts[1])
+		}
+	}
+	return attributes
+}
+
+func parseNumber(text string) (float64, error) {
+	var number float64
+	_, err := fmt.Sscanf(text, "%g", &number)
+	return number, err
+}
+
+func (p *policy) BestOptions(expansionOptions []expander.Option, nodeInfo map[string]*schedulerframework.NodeInfo) []expander.Option {
+	if len(expansionOptions) == 0 {
+		return expansionOptions
+	}
+	expression, err := p.reloadExpression()
+	if err != nil {
+		klog.Warningf("Policy expander filters no options: %v", err)
+		return expansionOptions
+	}
+
+	var selected []expander.Option
+	for _, option := range expansionOptions {
+		result, err := expression.eval(optionAttributes(option))
+		if err != nil {
+			klog.Warningf("Policy expression failed for an option, filtering no options: %v", err)
+			return expansionOptions
+		}
+		if result.kind != boolValue {
+			klog.Warningf("Policy expression returned %v instead of a boolean, filtering no options", result)
+			return expansionOptions
+		}
+		if result.truth {
+			selected = append(selected, option)
+		}
+	}
+	if len(selected) == 0 {
+		klog.V(4).Info("Policy expression accepted no options, filtering no options")
+		return expansionOptions
+	}
+	return selected
+}
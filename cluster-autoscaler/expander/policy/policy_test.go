@@ -0,0 +1,80 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	"k8s.io/autoscaler/cluster-autoscaler/expander"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
+)
+
+const testNamespace = "default"
+
+func optionWithDebug(debug string) expander.Option {
+	return expander.Option{NodeCount: 1, Debug: debug}
+}
+
+func newTestPolicyFilter(t *testing.T, expression string) expander.Filter {
+	cm := &apiv1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: testNamespace,
+			Name:      PolicyConfigMapName,
+		},
+		Data: map[string]string{ConfigMapKey: expression},
+	}
+	lister, err := kubernetes.NewTestConfigMapLister([]*apiv1.ConfigMap{cm})
+	assert.NoError(t, err)
+	return NewFilter(lister.ConfigMaps(testNamespace), record.NewFakeRecorder(100))
+}
+
+func TestBestOptionsKeepsAcceptedOptions(t *testing.T) {
+	filter := newTestPolicyFilter(t, "pool == 'gpu'")
+	gpuOption := optionWithDebug("pool=gpu price=2.5")
+	cpuOption := optionWithDebug("pool=cpu price=1.0")
+
+	options := filter.BestOptions([]expander.Option{gpuOption, cpuOption}, nil)
+
+	assert.Equal(t, []expander.Option{gpuOption}, options)
+}
+
+func TestBestOptionsFallsBackWhenNothingAccepted(t *testing.T) {
+	filter := newTestPolicyFilter(t, "price < 0.5")
+	allOptions := []expander.Option{
+		optionWithDebug("pool=gpu price=2.5"),
+		optionWithDebug("pool=cpu price=1.0"),
+	}
+
+	options := filter.BestOptions(allOptions, nil)
+
+	assert.Equal(t, allOptions, options)
+}
+
+func TestBestOptionsFallsBackOnBrokenExpression(t *testing.T) {
+	filter := newTestPolicyFilter(t, "pool == ")
+	allOptions := []expander.Option{optionWithDebug("pool=gpu")}
+
+	options := filter.BestOptions(allOptions, nil)
+
+	assert.Equal(t, allOptions, options)
+}
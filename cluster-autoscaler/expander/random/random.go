@@ -24,6 +24,9 @@ import (
 )
 
 type random struct {
+	// rand is the seeded source used when reproducible selection was
+	// requested; nil falls back to the global, time-seeded source.
+	rand *rand.Rand
 }
 
 // NewFilter returns an expansion filter that randomly picks between node groups
@@ -36,9 +39,21 @@ func NewStrategy() expander.Strategy {
 	return &random{}
 }
 
+// NewFilterWithSeed returns an expansion filter whose random choices are
+// reproducible across runs, for integration tests and staging environments.
+func NewFilterWithSeed(seed int64) expander.Filter {
+	return &random{rand: rand.New(rand.NewSource(seed))}
+}
+
+// NewStrategyWithSeed returns an expansion strategy whose random choices are
+// reproducible across runs.
+func NewStrategyWithSeed(seed int64) expander.Strategy {
+	return &random{rand: rand.New(rand.NewSource(seed))}
+}
+
 // BestOptions selects from the expansion options at random
 func (r *random) BestOptions(expansionOptions []expander.Option, nodeInfo map[string]*schedulerframework.NodeInfo) []expander.Option {
-	best := r.BestOption(expansionOptions, nodeInfo)
+	best := r.BestOption(expansionOptions)
 	if best == nil {
 		return nil
 	}
@@ -46,11 +61,16 @@ func (r *random) BestOptions(expansionOptions []expander.Option, nodeInfo map[st
 }
 
 // BestOption selects from the expansion options at random
-func (r *random) BestOption(expansionOptions []expander.Option, nodeInfo map[string]*schedulerframework.NodeInfo) *expander.Option {
+func (r *random) BestOption(expansionOptions []expander.Option) *expander.Option {
 	if len(expansionOptions) <= 0 {
 		return nil
 	}
 
-	pos := rand.Int31n(int32(len(expansionOptions)))
+	var pos int32
+	if r.rand != nil {
+		pos = r.rand.Int31n(int32(len(expansionOptions)))
+	} else {
+		pos = rand.Int31n(int32(len(expansionOptions)))
+	}
 	return &expansionOptions[pos]
 }
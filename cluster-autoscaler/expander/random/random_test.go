@@ -28,13 +28,29 @@ func TestRandomExpander(t *testing.T) {
 	e := NewStrategy()
 
 	eo1a := expander.Option{Debug: "EO1a"}
-	ret := e.BestOption([]expander.Option{eo1a}, nil)
+	ret := e.BestOption([]expander.Option{eo1a})
 	assert.Equal(t, *ret, eo1a)
 
 	eo1b := expander.Option{Debug: "EO1b"}
-	ret = e.BestOption([]expander.Option{eo1a, eo1b}, nil)
+	ret = e.BestOption([]expander.Option{eo1a, eo1b})
 	assert.True(t, assert.ObjectsAreEqual(*ret, eo1a) || assert.ObjectsAreEqual(*ret, eo1b))
 
-	ret = e.BestOption([]expander.Option{}, nil)
+	ret = e.BestOption([]expander.Option{})
 	assert.Nil(t, ret)
 }
+
+func TestRandomExpanderWithSeed(t *testing.T) {
+	options := []expander.Option{
+		{Debug: "EO1"},
+		{Debug: "EO2"},
+		{Debug: "EO3"},
+		{Debug: "EO4"},
+	}
+
+	// Two strategies seeded identically make identical choices.
+	e1 := NewStrategyWithSeed(42)
+	e2 := NewStrategyWithSeed(42)
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, e1.BestOption(options), e2.BestOption(options))
+	}
+}
@@ -0,0 +1,176 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package webhook implements an expander that delegates the node group choice
+// to an external HTTP endpoint, for teams that do not want to host a gRPC
+// server with TLS certificates. The expansion options and node infos are
+// POSTed as JSON and the endpoint answers with the subset of options it
+// selected. Timeout and fallback behavior match the gRPC expander: when the
+// call fails, times out or returns nothing usable, no options are filtered.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/expander"
+	"k8s.io/autoscaler/cluster-autoscaler/metrics"
+	"k8s.io/klog/v2"
+	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+const webhookTimeout = 5 * time.Second
+
+// Option mirrors one expansion option on the wire. Options are identified by
+// their index in the request, since this fork carries no cloud provider node
+// group objects.
+type Option struct {
+	Index     int    `json:"index"`
+	NodeCount int    `json:"nodeCount"`
+	Debug     string `json:"debug"`
+	// Pods lists the namespace/name of the pods that triggered the scale up.
+	Pods []string `json:"pods"`
+}
+
+// BestOptionsRequest is the payload POSTed to the webhook.
+type BestOptionsRequest struct {
+	Options []Option            `json:"options"`
+	NodeMap map[string]*v1.Node `json:"nodeMap"`
+}
+
+// BestOptionsResponse is the payload the webhook answers with. Only the
+// index of each option is used to map back to the original options.
+type BestOptionsResponse struct {
+	Options []Option `json:"options"`
+}
+
+type webhookclientstrategy struct {
+	url    string
+	client *http.Client
+}
+
+// NewFilter returns an expansion filter that POSTs the options to the given
+// URL and keeps the ones the endpoint selected.
+func NewFilter(url string) expander.Filter {
+	if url == "" {
+		klog.Errorf("Webhook expander URL not specified, the expander will filter no options")
+	}
+	return &webhookclientstrategy{
+		url:    url,
+		client: &http.Client{Timeout: webhookTimeout},
+	}
+}
+
+func (w *webhookclientstrategy) BestOptions(expansionOptions []expander.Option, nodeInfo map[string]*schedulerframework.NodeInfo) []expander.Option {
+	if w.url == "" {
+		klog.Errorf("Incorrect webhook expander config, filtering no options")
+		return expansionOptions
+	}
+
+	request := BestOptionsRequest{
+		Options: populateOptionsForWebhook(expansionOptions),
+		NodeMap: populateNodeInfoForWebhook(nodeInfo),
+	}
+	requestBody, err := json.Marshal(request)
+	if err != nil {
+		klog.Errorf("Failed to encode webhook expander request, no options filtered: %v", err)
+		return expansionOptions
+	}
+
+	klog.V(2).Infof("Webhook call of best options to %s with %v options", w.url, len(expansionOptions))
+	requestContext, cancel := context.WithTimeout(context.Background(), webhookTimeout)
+	defer cancel()
+	httpRequest, err := http.NewRequestWithContext(requestContext, http.MethodPost, w.url, bytes.NewBuffer(requestBody))
+	if err != nil {
+		klog.Errorf("Failed to build webhook expander request, no options filtered: %v", err)
+		return expansionOptions
+	}
+	httpRequest.Header.Set("Content-Type", "application/json")
+	httpResponse, err := w.client.Do(httpRequest)
+	if err != nil {
+		metrics.RegisterExpanderExternalFailure(expander.WebhookExpanderName)
+		klog.V(4).Infof("Webhook call failed or timed out, no options filtered: %v", err)
+		return expansionOptions
+	}
+	defer httpResponse.Body.Close()
+	if httpResponse.StatusCode >= 300 {
+		metrics.RegisterExpanderExternalFailure(expander.WebhookExpanderName)
+		klog.V(4).Infof("Webhook returned status %v, no options filtered", httpResponse.StatusCode)
+		return expansionOptions
+	}
+
+	response := BestOptionsResponse{}
+	if err := json.NewDecoder(httpResponse.Body).Decode(&response); err != nil {
+		metrics.RegisterExpanderExternalFailure(expander.WebhookExpanderName)
+		klog.V(4).Infof("Failed to decode webhook response, no options filtered: %v", err)
+		return expansionOptions
+	}
+	if len(response.Options) == 0 {
+		klog.V(4).Info("Webhook returned no bestOptions, no options filtered")
+		return expansionOptions
+	}
+	options := transformAndSanitizeOptionsFromWebhook(response.Options, expansionOptions)
+	if options == nil {
+		klog.V(4).Info("Unable to sanitize webhook returned bestOptions, no options filtered")
+		return expansionOptions
+	}
+	return options
+}
+
+// populateOptionsForWebhook creates the slice of wire options for the webhook
+// call.
+func populateOptionsForWebhook(expansionOptions []expander.Option) []Option {
+	webhookOptions := []Option{}
+	for index, option := range expansionOptions {
+		podNames := make([]string, 0, len(option.Pods))
+		for _, pod := range option.Pods {
+			podNames = append(podNames, pod.Namespace+"/"+pod.Name)
+		}
+		webhookOptions = append(webhookOptions, Option{
+			Index:     index,
+			NodeCount: option.NodeCount,
+			Debug:     option.Debug,
+			Pods:      podNames,
+		})
+	}
+	return webhookOptions
+}
+
+// populateNodeInfoForWebhook looks at the corresponding v1.Node object per
+// NodeInfo object, and populates the node map passed over the wire.
+func populateNodeInfoForWebhook(nodeInfos map[string]*schedulerframework.NodeInfo) map[string]*v1.Node {
+	webhookNodeInfoMap := make(map[string]*v1.Node)
+	for nodeID, nodeInfo := range nodeInfos {
+		webhookNodeInfoMap[nodeID] = nodeInfo.Node()
+	}
+	return webhookNodeInfoMap
+}
+
+func transformAndSanitizeOptionsFromWebhook(bestOptionsResponseOptions []Option, expansionOptions []expander.Option) []expander.Option {
+	var options []expander.Option
+	for _, option := range bestOptionsResponseOptions {
+		if option.Index < 0 || option.Index >= len(expansionOptions) {
+			klog.Errorf("Webhook returned invalid option index: %v", option.Index)
+			continue
+		}
+		options = append(options, expansionOptions[option.Index])
+	}
+	return options
+}
@@ -18,6 +18,7 @@ package main
 
 import (
 	ctx "context"
+	"crypto/subtle"
 	"flag"
 	"fmt"
 	"k8s.io/autoscaler/cluster-autoscaler/core/utils"
@@ -38,17 +39,21 @@ import (
 	"k8s.io/apiserver/pkg/server/mux"
 	"k8s.io/apiserver/pkg/server/routes"
 	utilfeature "k8s.io/apiserver/pkg/util/feature"
+	"k8s.io/autoscaler/cluster-autoscaler/auditlog"
 	"k8s.io/autoscaler/cluster-autoscaler/config"
+	"k8s.io/autoscaler/cluster-autoscaler/controlapi"
 	"k8s.io/autoscaler/cluster-autoscaler/core"
 	"k8s.io/autoscaler/cluster-autoscaler/estimator"
 	"k8s.io/autoscaler/cluster-autoscaler/expander"
 	"k8s.io/autoscaler/cluster-autoscaler/metrics"
 	ca_processors "k8s.io/autoscaler/cluster-autoscaler/processors"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator"
+	"k8s.io/autoscaler/cluster-autoscaler/statusreport"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/errors"
 	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/units"
 	"k8s.io/autoscaler/cluster-autoscaler/version"
+	"k8s.io/client-go/dynamic"
 	kube_client "k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
@@ -84,11 +89,19 @@ func multiStringFlag(name string, usage string) *MultiStringFlag {
 var (
 	clusterName            = flag.String("cluster-name", "", "Autoscaled cluster name, if available")
 	address                = flag.String("address", ":8085", "The address to expose prometheus metrics.")
+	metricsTLSCertFile     = flag.String("metrics-tls-cert-file", "", "Path to the TLS certificate used to serve metrics and debug endpoints. If set together with metrics-tls-key-file, the endpoints are served over HTTPS.")
+	metricsTLSKeyFile      = flag.String("metrics-tls-key-file", "", "Path to the TLS private key used to serve metrics and debug endpoints.")
+	metricsAuthToken       = flag.String("metrics-auth-token", "", "If set, requests to metrics and debug endpoints must present this bearer token in the Authorization header.")
+	controlAPIAddress      = flag.String("control-api-address", "", "The address to expose the gRPC control API on. Empty string disables the control API.")
+	controlAPICertFile     = flag.String("control-api-cert-file", "", "Path to the TLS certificate of the gRPC control API.")
+	controlAPIKeyFile      = flag.String("control-api-key-file", "", "Path to the TLS private key of the gRPC control API.")
+	controlAPIClientCAFile = flag.String("control-api-client-ca-file", "", "Path to the CA bundle used to verify client certificates of control API callers.")
 	kubernetes             = flag.String("kubernetes", "", "Kubernetes master location. Leave blank for default")
 	kubeConfigFile         = flag.String("kubeconfig", "", "Path to kubeconfig file with authorization and master location information.")
 	cloudConfig            = flag.String("cloud-config", "", "The path to the cloud provider configuration file.  Empty string for no configuration file.")
 	namespace              = flag.String("namespace", "kube-system", "Namespace in which cluster-autoscaler run.")
 	scaleDownEnabled       = flag.Bool("scale-down-enabled", true, "Should CA scale down the cluster")
+	scaleDownCordonOnly    = flag.Bool("scale-down-cordon-only", false, "Should CA only cordon and soft taint unneeded nodes, leaving the actual node deletion to an external system")
 	scaleDownDelayAfterAdd = flag.Duration("scale-down-delay-after-add", 10*time.Minute,
 		"How long after scale up that scale down evaluation resumes")
 	scaleDownDelayAfterDelete = flag.Duration("scale-down-delay-after-delete", 0,
@@ -128,15 +141,67 @@ var (
 	gpuTotal                 = multiStringFlag("gpu-total", "Minimum and maximum number of different GPUs in cluster, in the format <gpu_type>:<min>:<max>. Cluster autoscaler will not scale the cluster beyond these numbers. Can be passed multiple times. CURRENTLY THIS FLAG ONLY WORKS ON GKE.")
 	//cloudProviderFlag        = flag.String("cloud-provider", cloudBuilder.DefaultCloudProvider,
 	//	"Cloud provider type. Available values: ["+strings.Join(cloudBuilder.AvailableCloudProviders, ",")+"]")
-	maxBulkSoftTaintCount      = flag.Int("max-bulk-soft-taint-count", 10, "Maximum number of nodes that can be tainted/untainted PreferNoSchedule at the same time. Set to 0 to turn off such tainting.")
-	maxBulkSoftTaintTime       = flag.Duration("max-bulk-soft-taint-time", 3*time.Second, "Maximum duration of tainting/untainting nodes as PreferNoSchedule at the same time.")
-	maxEmptyBulkDeleteFlag     = flag.Int("max-empty-bulk-delete", 10, "Maximum number of empty nodes that can be deleted at the same time.")
-	maxGracefulTerminationFlag = flag.Int("max-graceful-termination-sec", 10*60, "Maximum number of seconds CA waits for pod termination when trying to scale down a node.")
-	maxTotalUnreadyPercentage  = flag.Float64("max-total-unready-percentage", 45, "Maximum percentage of unready nodes in the cluster.  After this is exceeded, CA halts operations")
-	okTotalUnreadyCount        = flag.Int("ok-total-unready-count", 3, "Number of allowed unready nodes, irrespective of max-total-unready-percentage")
-	scaleUpFromZero            = flag.Bool("scale-up-from-zero", true, "Should CA scale up when there 0 ready nodes.")
-	maxNodeProvisionTime       = flag.Duration("max-node-provision-time", 15*time.Minute, "Maximum time CA waits for node to be provisioned")
-	nodeGroupsFlag             = multiStringFlag(
+	maxBulkSoftTaintCount            = flag.Int("max-bulk-soft-taint-count", 10, "Maximum number of nodes that can be tainted/untainted PreferNoSchedule at the same time. Set to 0 to turn off such tainting.")
+	maxBulkSoftTaintTime             = flag.Duration("max-bulk-soft-taint-time", 3*time.Second, "Maximum duration of tainting/untainting nodes as PreferNoSchedule at the same time.")
+	softTaintInterval                = flag.Duration("soft-taint-interval", 0, "Minimum time between two runs of PreferNoSchedule tainting of unneeded nodes. Set to 0 to taint every loop.")
+	deletionCandidateTaintKey        = flag.String("deletion-candidate-taint-key", "", "Overrides the key of the PreferNoSchedule taint put on unneeded nodes. Empty string keeps the default key.")
+	publishScaleDownCandidateLabel   = flag.Bool("publish-scale-down-candidate-label", false, "Keeps a node label in sync with the PreferNoSchedule taint put on unneeded nodes, so descheduler policies can select candidate nodes and rebalance their workloads before deletion.")
+	toBeDeletedTaintKey              = flag.String("to-be-deleted-taint-key", "", "Overrides the key of the taint put on nodes being deleted. Empty string keeps the default key.")
+	toBeDeletedTaintEffect           = flag.String("to-be-deleted-taint-effect", "", "Overrides the effect of the taint put on nodes being deleted, either NoSchedule or NoExecute. Empty string keeps NoSchedule.")
+	disableSoftTaint                 = flag.Bool("disable-soft-taint", false, "Turns off PreferNoSchedule tainting of unneeded nodes entirely for the node group.")
+	maxEmptyBulkDeleteFlag           = flag.Int("max-empty-bulk-delete", 10, "Maximum number of empty nodes that can be deleted at the same time.")
+	maxGracefulTerminationFlag       = flag.Int("max-graceful-termination-sec", 10*60, "Maximum number of seconds CA waits for pod termination when trying to scale down a node.")
+	maxDrainParallelismFlag          = flag.Int("max-drain-parallelism", 1, "Maximum number of nodes that can be drained in parallel when several nodes are selected for removal in one scale down iteration.")
+	forceDrainTimeoutFlag            = flag.Duration("force-drain-timeout", 0, "Maximum time CA waits for a drained node to become empty before force deleting the remaining pods. Set to 0 to disable force draining.")
+	scaleDownRecentPodActivityWindow = flag.Duration("scale-down-recent-pod-activity-window", 0,
+		"Nodes on which pods terminated within this window are not scaled down, so capacity periodically reused e.g. by CronJobs is kept. Set to 0 to disable the check.")
+	scaleDownSimulationTimeout = flag.Duration("scale-down-simulation-timeout", 0,
+		"Maximum time per loop spent simulating node drains for scale down. When exceeded, the remaining candidates are deferred to the next loop so scale up is not delayed. Set to 0 to disable the limit.")
+	scaleDownAllowOwnerlessPods = flag.Bool("scale-down-allow-ownerless-pods", false,
+		"Allow scale down to remove nodes running pods without a controller. Such pods are not recreated elsewhere, so by default they block the removal of their node.")
+	nodeGroupLimitDriftGracePeriod = flag.Duration("node-group-limit-drift-grace-period", 0,
+		"How long the worker count has to stay outside the configured min/max before the limits are enforced through the portal. Set to 0 to enforce immediately.")
+	nodeAutoProvisioningEnabled = flag.Bool("node-auto-provisioning-enabled", false,
+		"Enable automatic creation of new worker groups for pending pods that fit no existing pool, and their deletion once they have been empty for a while.")
+	autoProvisioningFlavorAllowlist = multiStringFlag("auto-provisioning-flavor-allowlist",
+		"Flavor the auto-provisioner may use for new worker groups. Can be used multiple times. Empty means every configured flavor is allowed.")
+	autoProvisioningMaxFlavorCostPerHour = flag.Float64("auto-provisioning-max-flavor-cost-per-hour", 0,
+		"Highest hourly price of a flavor the auto-provisioner may pick. Set to 0 to disable the limit.")
+	proactiveConsolidationEnabled = flag.Bool("proactive-consolidation-enabled", false,
+		"Enable the periodic bin-packing pass that drains sets of underutilized workers whose pods fit on the remaining nodes and shrinks the pool through the portal.")
+	consolidationInterval = flag.Duration("consolidation-interval", 30*time.Minute,
+		"How often the proactive consolidation pass runs.")
+	consolidationUtilizationThreshold = flag.Float64("consolidation-utilization-threshold", 0.5,
+		"Utilization below which a worker is considered for proactive consolidation.")
+	predictiveScalingEnabled = flag.Bool("predictive-scaling-enabled", false,
+		"Enable recording of per-hour demand and pre-scaling of the worker pool shortly before recurring daily/weekly peaks.")
+	predictiveScalingLeadTime = flag.Duration("predictive-scaling-lead-time", 10*time.Minute,
+		"How far ahead of a predicted peak the worker pool is scaled up.")
+	flavorRightSizingWindow = flag.Duration("flavor-right-sizing-window", 0,
+		"How long utilization is accumulated per worker pool before a flavor right-sizing recommendation is published. Set to 0 to disable the analyzer.")
+	loopOverrunPolicy = flag.String("loop-overrun-policy", config.LoopOverrunSkip,
+		"What to do when an autoscaling iteration is triggered while the previous one is still running: 'skip' drops it, 'queue' runs it as soon as the previous one finishes.")
+	preDeletionWebhookURL = flag.String("pre-deletion-webhook-url", "",
+		"URL POSTed the name of every node about to be deleted, so external systems can deregister it. Empty disables the webhook.")
+	preDeletionJobImage = flag.String("pre-deletion-job-image", "",
+		"Image run as a Job pinned to every node about to be deleted; the deletion waits for the Job to complete. Empty disables the Job hook.")
+	preDeletionHookAnnotation = flag.String("pre-deletion-hook-annotation", "",
+		"Node annotation the deletion waits for before proceeding, so agents on the node can signal a clean shutdown. Empty disables the annotation hook.")
+	preDeletionHookTimeout = flag.Duration("pre-deletion-hook-timeout", 5*time.Minute,
+		"Maximum total time the pre-deletion hooks of a single node may take.")
+	preDeletionHookFailPolicy = flag.String("pre-deletion-hook-fail-policy", config.PreDeletionHookSkip,
+		"What to do when a node's pre-deletion hooks fail or time out: 'skip' deletes the node anyway, 'abort' keeps it for a later iteration.")
+	nodeRepairEnabled = flag.Bool("node-repair-enabled", false,
+		"Enable automatic replacement of worker nodes stuck NotReady or under resource pressure beyond node-repair-unhealthy-threshold.")
+	nodeRepairUnhealthyThreshold = flag.Duration("node-repair-unhealthy-threshold", 15*time.Minute,
+		"How long a worker node must be unhealthy before it is replaced.")
+	costReportInterval = flag.Duration("cost-report-interval", 24*time.Hour,
+		"How often a summary of node-hours and estimated money spent and saved per worker pool is published. Set to 0 to disable the report.")
+	maxTotalUnreadyPercentage = flag.Float64("max-total-unready-percentage", 45, "Maximum percentage of unready nodes in the cluster.  After this is exceeded, CA halts operations")
+	okTotalUnreadyCount       = flag.Int("ok-total-unready-count", 3, "Number of allowed unready nodes, irrespective of max-total-unready-percentage")
+	scaleUpFromZero           = flag.Bool("scale-up-from-zero", true, "Should CA scale up when there 0 ready nodes.")
+	maxNodeProvisionTime      = flag.Duration("max-node-provision-time", 15*time.Minute, "Maximum time CA waits for node to be provisioned")
+	nodeGroupsFlag            = multiStringFlag(
 		"nodes",
 		"sets min,max size and other configuration data for a node group in a format accepted by cloud provider. Can be used multiple times. Format: <min>:<max>:<other...>")
 	nodeGroupAutoDiscoveryFlag = multiStringFlag(
@@ -152,15 +217,50 @@ var (
 
 	expanderFlag = flag.String("expander", expander.RandomExpanderName, "Type of node group expander to be used in scale up. Available values: ["+strings.Join(expander.AvailableExpanders, ",")+"]. Specifying multiple values separated by commas will call the expanders in succession until there is only one option remaining. Ties still existing after this process are broken randomly.")
 
-	grpcExpanderCert = flag.String("grpc-expander-cert", "", "Path to cert used by gRPC server over TLS")
-	grpcExpanderURL  = flag.String("grpc-expander-url", "", "URL to reach gRPC expander server.")
+	expanderPerPoolFlag = flag.String("expander-per-pool", "", "Per worker pool expander overrides, as comma separated pool:expander entries, e.g. spot:priority,general:least-waste. Use semicolons to chain expanders within one pool. Pools not listed use --expander.")
+
+	grpcExpanderCert   = flag.String("grpc-expander-cert", "", "Path to cert used by gRPC server over TLS")
+	grpcExpanderURL    = flag.String("grpc-expander-url", "", "URL to reach gRPC expander server.")
+	webhookExpanderURL = flag.String("webhook-expander-url", "", "HTTP endpoint the expansion options are POSTed to as JSON when using the webhook expander.")
+	expanderRandomSeed = flag.Int64("expander-random-seed", 0, "Fixed seed for the random expander, so multi-pool selection is reproducible in tests and staging. 0 uses a time-based seed.")
+
+	expanderChainTimeBudget = flag.Duration("expander-chain-time-budget", 0, "Maximum total time the expander chain may spend selecting an option per scale up. On exhaustion the remaining expanders are skipped and ties are broken randomly. 0 disables the bound.")
+
+	expanderCacheTTL = flag.Duration("expander-cache-ttl", 0, "How long to reuse the expander choice when the set of pending pods is unchanged across loops, avoiding repeated calls to external expanders. 0 disables caching.")
+
+	clusterSnapshotImpl = flag.String("cluster-snapshot-impl", "delta",
+		"Which ClusterSnapshot implementation to use for scheduling simulations. Available values: [delta, basic]. Delta is faster on large clusters, basic has more predictable memory usage.")
 
 	ignoreDaemonSetsUtilization = flag.Bool("ignore-daemonsets-utilization", false,
 		"Should CA ignore DaemonSet pods when calculating resource utilization for scaling down")
 	ignoreMirrorPodsUtilization = flag.Bool("ignore-mirror-pods-utilization", false,
 		"Should CA ignore Mirror pods when calculating resource utilization for scaling down")
+	nodeResourcesFromAllocatable = flag.Bool("node-resources-from-allocatable", false,
+		"Account node cpu and memory from allocatable instead of capacity, excluding what is reserved for the system and the kubelet")
+
+	egressProxyURL = flag.String("egress-proxy-url", "",
+		"If set, route all portal traffic through this inspection proxy. Basic auth credentials can be embedded in the URL. Without the flag the standard HTTP_PROXY/HTTPS_PROXY/NO_PROXY environment variables are honored.")
+	egressNoProxy = flag.String("egress-no-proxy", "", "Comma separated hostnames or domain suffixes that bypass the egress proxy.")
+
+	portalCAFile          = flag.String("portal-ca-file", "", "If set, portal connections only trust server certificates signed by the CAs in this PEM file instead of the system trust store.")
+	portalCertFingerprint = flag.String("portal-cert-fingerprint", "", "If set, the portal's leaf certificate must additionally have this SHA-256 fingerprint (hex, optionally colon separated).")
+	portalTokenExchange   = flag.Bool("portal-token-exchange", false, "Exchange the long-lived bootstrap_token from the fke secret for short-lived portal tokens at runtime instead of using the static access_token.")
+	portalRequestSigning  = flag.Bool("portal-request-signing", false, "Sign portal scale requests with a timestamped HMAC over the body, using the signing_secret key from the fke secret.")
+	readOnlyModeFlag      = flag.Bool("read-only-mode", false,
+		"Run without portal credentials and without performing any mutation. Recommendations, metrics and status are still produced, so the autoscaler only needs get/list/watch RBAC.")
+
+	requiredConfigGracePeriod = flag.Duration("required-config-grace-period", 5*time.Minute,
+		"How long the autoscaling configmap or fke secret may be unreadable before the autoscaler exits. Until the grace period expires iterations are skipped instead.")
+
+	auditLogFile       = flag.String("audit-log-file", "", "If set, append an audit record of every scaling decision and portal mutation to this file as JSON lines.")
+	auditLogMaxSizeMB  = flag.Int("audit-log-max-size-mb", 100, "Maximum size of the audit log file in megabytes before it is rotated.")
+	auditLogS3Endpoint = flag.String("audit-log-s3-endpoint", "",
+		"If set, upload rotated audit log files to this S3-compatible endpoint. Credentials are read from the AUDIT_LOG_S3_ACCESS_KEY and AUDIT_LOG_S3_SECRET_KEY environment variables.")
+	auditLogS3Bucket = flag.String("audit-log-s3-bucket", "", "Bucket receiving rotated audit log files.")
+	auditLogS3Region = flag.String("audit-log-s3-region", "us-east-1", "Region used to sign audit log uploads.")
 
 	writeStatusConfigMapFlag         = flag.Bool("write-status-configmap", true, "Should CA write status information to a configmap")
+	writeStatusCRFlag                = flag.Bool("write-status-cr", false, "Should CA publish a ClusterAutoscalerStatus custom resource with structured conditions per worker pool. Requires the CRD to be installed.")
 	statusConfigMapName              = flag.String("status-config-map-name", "cluster-autoscaler-status", "Status configmap name")
 	maxInactivityTimeFlag            = flag.Duration("max-inactivity", 10*time.Minute, "Maximum time from last recorded autoscaler activity before automatic restart")
 	maxFailingTimeFlag               = flag.Duration("max-failing-time", 15*time.Minute, "Maximum time from last recorded successful autoscaler run before automatic restart")
@@ -174,6 +274,10 @@ var (
 	newPodScaleUpDelay            = flag.Duration("new-pod-scale-up-delay", 0*time.Second, "Pods less than this old will not be considered for scale-up.")
 
 	ignoreTaintsFlag                   = multiStringFlag("ignore-taint", "Specifies a taint to ignore in node templates when considering to scale a node group")
+	scaleDownExcludeTaintsFlag         = multiStringFlag("scale-down-exclude-taint", "Specifies a taint key whose presence on a node excludes the node from scale down. Can be passed multiple times.")
+	equivalenceIgnoreTolerationsFlag   = multiStringFlag("equivalence-ignore-toleration-key", "Specifies a toleration key to drop before comparing pods for equivalence grouping, e.g. one injected by an admission webhook. Can be passed multiple times.")
+	equivalenceIgnoreContainersFlag    = multiStringFlag("equivalence-ignore-container-name", "Specifies a container name to drop before comparing pods for equivalence grouping, e.g. a sidecar injected by an admission webhook. Can be passed multiple times.")
+	equivalenceIgnoreLabelsFlag        = multiStringFlag("equivalence-ignore-label-key", "Specifies a pod label key to drop before comparing pods for equivalence grouping. Can be passed multiple times.")
 	balancingIgnoreLabelsFlag          = multiStringFlag("balancing-ignore-label", "Specifies a label to ignore in addition to the basic and cloud-provider set of labels when comparing if two node groups are similar")
 	awsUseStaticInstanceList           = flag.Bool("aws-use-static-instance-list", false, "Should CA fetch instance types in runtime or use a static list. AWS only")
 	concurrentGceRefreshes             = flag.Int("gce-concurrent-refreshes", 1, "Maximum number of concurrent refreshes per cloud object type.")
@@ -184,9 +288,18 @@ var (
 	daemonSetEvictionForOccupiedNodes  = flag.Bool("daemonset-eviction-for-occupied-nodes", true, "DaemonSet pods will be gracefully terminated from non-empty nodes")
 	userAgent                          = flag.String("user-agent", "cluster-autoscaler", "User agent used for HTTP calls.")
 
-	emitPerNodeGroupMetrics  = flag.Bool("emit-per-nodegroup-metrics", false, "If true, emit per node group metrics.")
-	debuggingSnapshotEnabled = flag.Bool("debugging-snapshot-enabled", false, "Whether the debugging snapshot of cluster autoscaler feature is enabled")
-	nodeInfoCacheExpireTime  = flag.Duration("node-info-cache-expire-time", 87600*time.Hour, "Node Info cache expire time for each item. Default value is 10 years.")
+	emitPerNodeGroupMetrics     = flag.Bool("emit-per-nodegroup-metrics", false, "If true, emit per node group metrics.")
+	debuggingSnapshotEnabled    = flag.Bool("debugging-snapshot-enabled", false, "Whether the debugging snapshot of cluster autoscaler feature is enabled")
+	debuggingSnapshotInterval   = flag.Duration("debugging-snapshot-interval", 0, "Interval for automatic debugging snapshot capture to disk, so the state before an incident is retained without a manual trigger. Requires --debugging-snapshot-enabled. 0 disables scheduled capture.")
+	debuggingSnapshotDir        = flag.String("debugging-snapshot-dir", "/tmp/debugging-snapshots", "Directory the scheduled debugging snapshots are written to.")
+	debuggingSnapshotRetention  = flag.Int("debugging-snapshot-retention", 10, "Maximum number of scheduled debugging snapshots retained on disk, oldest deleted first. 0 means unlimited.")
+	debuggingSnapshotMaxBytes   = flag.Int64("debugging-snapshot-max-bytes", 0, "Maximum total size in bytes of the scheduled debugging snapshots retained on disk, oldest deleted first. 0 means unlimited.")
+	debuggingSnapshotS3Endpoint = flag.String("debugging-snapshot-s3-endpoint", "", "Endpoint of an S3-compatible object store to upload captured debugging snapshots to. Empty string disables uploads.")
+	debuggingSnapshotS3Bucket   = flag.String("debugging-snapshot-s3-bucket", "", "Bucket the debugging snapshots are uploaded to.")
+	debuggingSnapshotS3Region   = flag.String("debugging-snapshot-s3-region", "us-east-1", "Region used to sign the debugging snapshot uploads.")
+	debuggingSnapshotS3Prefix   = flag.String("debugging-snapshot-s3-prefix", "", "Object key prefix for the uploaded debugging snapshots.")
+	debuggingSnapshotS3Secret   = flag.String("debugging-snapshot-s3-secret", "debugging-snapshot-s3", "Name of the secret in the CA namespace holding the access_key and secret_key for the snapshot uploads.")
+	nodeInfoCacheExpireTime     = flag.Duration("node-info-cache-expire-time", 87600*time.Hour, "Node Info cache expire time for each item. Default value is 10 years.")
 )
 
 func createAutoscalingOptions() config.AutoscalingOptions {
@@ -212,35 +325,72 @@ func createAutoscalingOptions() config.AutoscalingOptions {
 			ScaleDownGpuUtilizationThreshold: *scaleDownGpuUtilizationThreshold,
 			ScaleDownUnneededTime:            *scaleDownUnneededTime,
 			ScaleDownUnreadyTime:             *scaleDownUnreadyTime,
+			DisableSoftTaint:                 *disableSoftTaint,
 		},
 		CloudConfig: *cloudConfig,
 		//CloudProviderName:                  *cloudProviderFlag,
-		NodeGroupAutoDiscovery:      *nodeGroupAutoDiscoveryFlag,
-		MaxTotalUnreadyPercentage:   *maxTotalUnreadyPercentage,
-		OkTotalUnreadyCount:         *okTotalUnreadyCount,
-		ScaleUpFromZero:             *scaleUpFromZero,
-		EstimatorName:               *estimatorFlag,
-		ExpanderNames:               *expanderFlag,
-		GRPCExpanderCert:            *grpcExpanderCert,
-		GRPCExpanderURL:             *grpcExpanderURL,
-		IgnoreDaemonSetsUtilization: *ignoreDaemonSetsUtilization,
-		IgnoreMirrorPodsUtilization: *ignoreMirrorPodsUtilization,
-		MaxBulkSoftTaintCount:       *maxBulkSoftTaintCount,
-		MaxBulkSoftTaintTime:        *maxBulkSoftTaintTime,
-		MaxEmptyBulkDelete:          *maxEmptyBulkDeleteFlag,
-		MaxGracefulTerminationSec:   *maxGracefulTerminationFlag,
-		MaxNodeProvisionTime:        *maxNodeProvisionTime,
-		MaxNodesTotal:               *maxNodesTotal,
-		MaxCoresTotal:               maxCoresTotal,
-		MinCoresTotal:               minCoresTotal,
-		MaxMemoryTotal:              maxMemoryTotal,
-		MinMemoryTotal:              minMemoryTotal,
+		NodeGroupAutoDiscovery:               *nodeGroupAutoDiscoveryFlag,
+		MaxTotalUnreadyPercentage:            *maxTotalUnreadyPercentage,
+		OkTotalUnreadyCount:                  *okTotalUnreadyCount,
+		ScaleUpFromZero:                      *scaleUpFromZero,
+		EstimatorName:                        *estimatorFlag,
+		ExpanderNames:                        *expanderFlag,
+		ExpanderNamesPerPool:                 *expanderPerPoolFlag,
+		GRPCExpanderCert:                     *grpcExpanderCert,
+		GRPCExpanderURL:                      *grpcExpanderURL,
+		WebhookExpanderURL:                   *webhookExpanderURL,
+		ExpanderRandomSeed:                   *expanderRandomSeed,
+		ExpanderChainTimeBudget:              *expanderChainTimeBudget,
+		ExpanderCacheTTL:                     *expanderCacheTTL,
+		IgnoreDaemonSetsUtilization:          *ignoreDaemonSetsUtilization,
+		NodeResourcesFromAllocatable:         *nodeResourcesFromAllocatable,
+		IgnoreMirrorPodsUtilization:          *ignoreMirrorPodsUtilization,
+		MaxBulkSoftTaintCount:                *maxBulkSoftTaintCount,
+		MaxBulkSoftTaintTime:                 *maxBulkSoftTaintTime,
+		SoftTaintInterval:                    *softTaintInterval,
+		DeletionCandidateTaintKey:            *deletionCandidateTaintKey,
+		PublishScaleDownCandidateLabel:       *publishScaleDownCandidateLabel,
+		ToBeDeletedTaintKey:                  *toBeDeletedTaintKey,
+		ToBeDeletedTaintEffect:               *toBeDeletedTaintEffect,
+		MaxEmptyBulkDelete:                   *maxEmptyBulkDeleteFlag,
+		MaxGracefulTerminationSec:            *maxGracefulTerminationFlag,
+		MaxDrainParallelism:                  *maxDrainParallelismFlag,
+		ForceDrainTimeout:                    *forceDrainTimeoutFlag,
+		ScaleDownRecentPodActivityWindow:     *scaleDownRecentPodActivityWindow,
+		ScaleDownSimulationTimeout:           *scaleDownSimulationTimeout,
+		ScaleDownAllowOwnerlessPods:          *scaleDownAllowOwnerlessPods,
+		NodeGroupLimitDriftGracePeriod:       *nodeGroupLimitDriftGracePeriod,
+		NodeAutoProvisioningEnabled:          *nodeAutoProvisioningEnabled,
+		AutoProvisioningFlavorAllowlist:      *autoProvisioningFlavorAllowlist,
+		AutoProvisioningMaxFlavorCostPerHour: *autoProvisioningMaxFlavorCostPerHour,
+		ProactiveConsolidationEnabled:        *proactiveConsolidationEnabled,
+		ConsolidationInterval:                *consolidationInterval,
+		ConsolidationUtilizationThreshold:    *consolidationUtilizationThreshold,
+		PredictiveScalingEnabled:             *predictiveScalingEnabled,
+		PredictiveScalingLeadTime:            *predictiveScalingLeadTime,
+		FlavorRightSizingWindow:              *flavorRightSizingWindow,
+		LoopOverrunPolicy:                    *loopOverrunPolicy,
+		PreDeletionWebhookURL:                *preDeletionWebhookURL,
+		PreDeletionJobImage:                  *preDeletionJobImage,
+		PreDeletionHookAnnotation:            *preDeletionHookAnnotation,
+		PreDeletionHookTimeout:               *preDeletionHookTimeout,
+		PreDeletionHookFailPolicy:            *preDeletionHookFailPolicy,
+		NodeRepairEnabled:                    *nodeRepairEnabled,
+		NodeRepairUnhealthyThreshold:         *nodeRepairUnhealthyThreshold,
+		CostReportInterval:                   *costReportInterval,
+		MaxNodeProvisionTime:                 *maxNodeProvisionTime,
+		MaxNodesTotal:                        *maxNodesTotal,
+		MaxCoresTotal:                        maxCoresTotal,
+		MinCoresTotal:                        minCoresTotal,
+		MaxMemoryTotal:                       maxMemoryTotal,
+		MinMemoryTotal:                       minMemoryTotal,
 		//GpuTotal:                           parsedGpuTotal,
 		NodeGroups:                       *nodeGroupsFlag,
 		ScaleDownDelayAfterAdd:           *scaleDownDelayAfterAdd,
 		ScaleDownDelayAfterDelete:        *scaleDownDelayAfterDelete,
 		ScaleDownDelayAfterFailure:       *scaleDownDelayAfterFailure,
 		ScaleDownEnabled:                 *scaleDownEnabled,
+		ScaleDownCordonOnly:              *scaleDownCordonOnly,
 		ScaleDownNonEmptyCandidatesCount: *scaleDownNonEmptyCandidatesCount,
 		ScaleDownCandidatesPoolRatio:     *scaleDownCandidatesPoolRatio,
 		ScaleDownCandidatesPoolMinCount:  *scaleDownCandidatesPoolMinCount,
@@ -256,6 +406,10 @@ func createAutoscalingOptions() config.AutoscalingOptions {
 		Regional:                         *regional,
 		NewPodScaleUpDelay:               *newPodScaleUpDelay,
 		IgnoredTaints:                    *ignoreTaintsFlag,
+		ScaleDownExcludeTaintKeys:        *scaleDownExcludeTaintsFlag,
+		EquivalenceIgnoredTolerationKeys: *equivalenceIgnoreTolerationsFlag,
+		EquivalenceIgnoredContainerNames: *equivalenceIgnoreContainersFlag,
+		EquivalenceIgnoredLabelKeys:      *equivalenceIgnoreLabelsFlag,
 		BalancingExtraIgnoredLabels:      *balancingIgnoreLabelsFlag,
 		KubeConfigPath:                   *kubeConfigFile,
 		NodeDeletionDelayTimeout:         *nodeDeletionDelayTimeout,
@@ -296,6 +450,20 @@ func createKubeClient(kubeConfig *rest.Config) kube_client.Interface {
 	return kube_client.NewForConfigOrDie(kubeConfig)
 }
 
+// waitForRequiredConfig retries reading a required configuration value until it
+// succeeds. Once the grace period expires the failing getter exits the process,
+// so the loop never spins forever.
+func waitForRequiredConfig(what string, get func() (string, error)) string {
+	for {
+		value, err := get()
+		if err == nil {
+			return value
+		}
+		klog.Warningf("Waiting for %s to become readable: %v", what, err)
+		time.Sleep(10 * time.Second)
+	}
+}
+
 func registerSignalHandlers(autoscaler core.Autoscaler) {
 	sigs := make(chan os.Signal, 1)
 	signal.Notify(sigs, os.Interrupt, os.Kill, syscall.SIGTERM, syscall.SIGQUIT)
@@ -311,6 +479,20 @@ func registerSignalHandlers(autoscaler core.Autoscaler) {
 	}()
 }
 
+// createClusterSnapshot builds the ClusterSnapshot implementation selected by
+// the --cluster-snapshot-impl flag.
+func createClusterSnapshot(impl string) simulator.ClusterSnapshot {
+	switch impl {
+	case "basic":
+		return simulator.NewBasicClusterSnapshot()
+	case "delta":
+		return simulator.NewDeltaClusterSnapshot()
+	default:
+		klog.Fatalf("Unknown cluster snapshot implementation %q, available values: [delta, basic]", impl)
+		return nil
+	}
+}
+
 func buildAutoscaler(debuggingSnapshotter debuggingsnapshot.DebuggingSnapshotter) (core.Autoscaler, error) {
 	// Create basic config from flags.
 	autoscalingOptions := createAutoscalingOptions()
@@ -319,7 +501,7 @@ func buildAutoscaler(debuggingSnapshotter debuggingsnapshot.DebuggingSnapshotter
 
 	opts := core.AutoscalerOptions{
 		AutoscalingOptions:   autoscalingOptions,
-		ClusterSnapshot:      simulator.NewDeltaClusterSnapshot(),
+		ClusterSnapshot:      createClusterSnapshot(*clusterSnapshotImpl),
 		KubeClient:           kubeClient,
 		EventsKubeClient:     eventsKubeClient,
 		DebuggingSnapshotter: debuggingSnapshotter,
@@ -357,6 +539,31 @@ func buildAutoscaler(debuggingSnapshotter debuggingsnapshot.DebuggingSnapshotter
 func run(healthCheck *metrics.HealthCheck, debuggingSnapshotter debuggingsnapshot.DebuggingSnapshotter, kubeclient kube_client.Interface, vpcID string, accessToken string, idCluster string, clusterIDPortal string, env string) {
 	metrics.RegisterAll(*emitPerNodeGroupMetrics)
 
+	if kubeclient == nil {
+		kubeclient = createKubeClient(getKubeConfig())
+	}
+	// Serve the autoscaling configmap and fke secret from informer caches, so
+	// the config getters do not GET them on every loop.
+	utils.StartConfigCache(kubeclient)
+
+	if *writeStatusCRFlag {
+		statusreport.EnableCRPublishing(dynamic.NewForConfigOrDie(getKubeConfig()), *namespace)
+	}
+
+	if *debuggingSnapshotS3Endpoint != "" {
+		debuggingsnapshot.EnableS3Upload(kubeclient, *namespace, *debuggingSnapshotS3Secret,
+			*debuggingSnapshotS3Endpoint, *debuggingSnapshotS3Bucket, *debuggingSnapshotS3Region, *debuggingSnapshotS3Prefix)
+	}
+
+	if *auditLogFile != "" {
+		if err := auditlog.Enable(*auditLogFile, *auditLogMaxSizeMB); err != nil {
+			klog.Fatalf("Failed to enable audit log: %v", err)
+		}
+		if *auditLogS3Endpoint != "" {
+			auditlog.EnableS3Upload(*auditLogS3Endpoint, *auditLogS3Bucket, *auditLogS3Region)
+		}
+	}
+
 	autoscaler, err := buildAutoscaler(debuggingSnapshotter)
 	if err != nil {
 		klog.Fatalf("Failed to create autoscaler: %v", err)
@@ -373,24 +580,49 @@ func run(healthCheck *metrics.HealthCheck, debuggingSnapshotter debuggingsnapsho
 		klog.Fatalf("Failed to autoscaler background components: %v", err)
 	}
 
+	// The control API can request an iteration ahead of the scan interval.
+	forceRefresh := make(chan struct{}, 1)
+	controlServer := controlapi.NewServer(func() {
+		select {
+		case forceRefresh <- struct{}{}:
+		default:
+		}
+	}, debuggingSnapshotter.StartDataCollection)
+	if *controlAPIAddress != "" {
+		go func() {
+			klog.Fatalf("Control API terminated: %v",
+				controlServer.Serve(*controlAPIAddress, *controlAPICertFile, *controlAPIKeyFile, *controlAPIClientCAFile))
+		}()
+	}
+
+	runLoop := func() {
+		loopStart := time.Now()
+		metrics.UpdateLastTime(metrics.Main, loopStart)
+		healthCheck.UpdateLastActivity(loopStart)
+
+		if controlServer.IsPaused() {
+			klog.V(1).Info("Scaling is paused through the control API, skipping iteration")
+			healthCheck.UpdateLastSuccessfulRun(time.Now())
+			return
+		}
+
+		err := autoscaler.RunOnce(loopStart, kubeclient, vpcID, accessToken, idCluster, clusterIDPortal, env)
+		if err != nil && err.Type() != errors.TransientError {
+			metrics.RegisterError(err)
+		} else {
+			healthCheck.UpdateLastSuccessfulRun(time.Now())
+		}
+
+		metrics.UpdateDurationFromStart(metrics.Main, loopStart)
+	}
+
 	// Autoscale ad infinitum.
 	for {
 		select {
 		case <-time.After(*scanInterval):
-			{
-				loopStart := time.Now()
-				metrics.UpdateLastTime(metrics.Main, loopStart)
-				healthCheck.UpdateLastActivity(loopStart)
-
-				err := autoscaler.RunOnce(loopStart, kubeclient, vpcID, accessToken, idCluster, clusterIDPortal, env)
-				if err != nil && err.Type() != errors.TransientError {
-					metrics.RegisterError(err)
-				} else {
-					healthCheck.UpdateLastSuccessfulRun(time.Now())
-				}
-
-				metrics.UpdateDurationFromStart(metrics.Main, loopStart)
-			}
+			runLoop()
+		case <-forceRefresh:
+			runLoop()
 		}
 	}
 }
@@ -409,7 +641,34 @@ func main() {
 
 	klog.V(1).Infof("Cluster Autoscaler %s", version.ClusterAutoscalerVersion)
 
-	debuggingSnapshotter := debuggingsnapshot.NewDebuggingSnapshotter(*debuggingSnapshotEnabled)
+	utils.SetRequiredConfigGracePeriod(*requiredConfigGracePeriod)
+
+	if *readOnlyModeFlag {
+		klog.V(1).Infof("Running in read-only mode, no scaling action will be performed")
+		utils.SetReadOnlyMode(true)
+		// Writing status objects needs more than get/list/watch RBAC.
+		*writeStatusConfigMapFlag = false
+		*writeStatusCRFlag = false
+	}
+
+	if *egressProxyURL != "" {
+		if err := utils.ConfigureEgressProxy(*egressProxyURL, *egressNoProxy); err != nil {
+			klog.Fatalf("Failed to configure egress proxy: %v", err)
+		}
+	}
+	if *portalCAFile != "" {
+		if err := utils.PinPortalCA(*portalCAFile); err != nil {
+			klog.Fatalf("Failed to pin portal CA: %v", err)
+		}
+	}
+	if *portalCertFingerprint != "" {
+		if err := utils.PinPortalCertFingerprint(*portalCertFingerprint); err != nil {
+			klog.Fatalf("Failed to pin portal certificate fingerprint: %v", err)
+		}
+	}
+
+	debuggingSnapshotter := debuggingsnapshot.NewDebuggingSnapshotterWithScheduledCapture(*debuggingSnapshotEnabled,
+		*debuggingSnapshotInterval, *debuggingSnapshotDir, *debuggingSnapshotRetention, *debuggingSnapshotMaxBytes)
 
 	go func() {
 		pathRecorderMux := mux.NewPathRecorderMux("cluster-autoscaler")
@@ -421,10 +680,22 @@ func main() {
 			pathRecorderMux.HandleFunc("/snapshotz", debuggingSnapshotter.ResponseHandler)
 		}
 		pathRecorderMux.HandleFunc("/health-check", healthCheck.ServeHTTP)
+		pathRecorderMux.HandleFunc("/statusz", statusreport.ServeHTTP)
+		pathRecorderMux.HandleFunc("/statusz/stream", statusreport.ServeStreamHTTP)
+		pathRecorderMux.HandleFunc("/explain", statusreport.ServeExplainHTTP)
 		if *enableProfiling {
 			routes.Profiling{}.Install(pathRecorderMux)
 		}
-		err := http.ListenAndServe(*address, pathRecorderMux)
+		handler := http.Handler(pathRecorderMux)
+		if *metricsAuthToken != "" {
+			handler = requireAuthToken(handler, *metricsAuthToken)
+		}
+		var err error
+		if *metricsTLSCertFile != "" && *metricsTLSKeyFile != "" {
+			err = http.ListenAndServeTLS(*address, *metricsTLSCertFile, *metricsTLSKeyFile, handler)
+		} else {
+			err = http.ListenAndServe(*address, handler)
+		}
 		klog.Fatalf("Failed to start metrics: %v", err)
 	}()
 
@@ -437,14 +708,35 @@ func main() {
 		}
 
 		kubeClient := createKubeClient(getKubeConfig())
-		vpcID := utils.GetVPCId(kubeClient)
-		accessToken := utils.GetAccessToken(kubeClient)
-		clusterIDPortal := utils.GetClusterID(kubeClient)
-		env := utils.GetEnv(kubeClient)
-		domainAPI := utils.GetDomainApiConformEnv(env)
-		idCluster := utils.GetIDCluster(domainAPI, vpcID, accessToken, clusterIDPortal)
+		var vpcID, accessToken, clusterIDPortal, env, domainAPI, idCluster string
+		if *readOnlyModeFlag {
+			klog.V(1).Infof("Read-only mode: not reading portal credentials")
+		} else {
+			vpcID = waitForRequiredConfig("vpc_id", func() (string, error) { return utils.GetVPCId(kubeClient) })
+			accessToken = waitForRequiredConfig("access_token", func() (string, error) { return utils.GetAccessToken(kubeClient) })
+			utils.SetAccessToken(accessToken)
+			go utils.WatchAccessToken(kubeClient)
+			clusterIDPortal = waitForRequiredConfig("cluster_id", func() (string, error) { return utils.GetClusterID(kubeClient) })
+			env = waitForRequiredConfig("env_name", func() (string, error) { return utils.GetEnv(kubeClient) })
+			domainAPI = utils.GetDomainApiConformEnv(env)
+			idCluster = utils.GetIDCluster(domainAPI, vpcID, accessToken, clusterIDPortal)
+		}
+		if *portalTokenExchange && !*readOnlyModeFlag {
+			bootstrapToken := utils.GetBootstrapToken(kubeClient)
+			if bootstrapToken == "" {
+				klog.Fatalf("--portal-token-exchange requires a bootstrap_token key in the fke secret")
+			}
+			go utils.RunTokenExchange(domainAPI, bootstrapToken)
+		}
+		if *portalRequestSigning && !*readOnlyModeFlag {
+			signingSecret := utils.GetSigningSecret(kubeClient)
+			if signingSecret == "" {
+				klog.Fatalf("--portal-request-signing requires a signing_secret key in the fke secret")
+			}
+			utils.EnablePortalRequestSigning(signingSecret)
+		}
 		// Validate that the client is ok.
-		_, err = kubeClient.CoreV1().Nodes().List(ctx.TODO(), metav1.ListOptions{})
+		_, err = kubeClient.CoreV1().Nodes().List(ctx.TODO(), metav1.ListOptions{Limit: 1, ResourceVersion: "0"})
 		if err != nil {
 			klog.Fatalf("Failed to get nodes from apiserver: %v", err)
 		}
@@ -484,6 +776,18 @@ func main() {
 	}
 }
 
+// requireAuthToken rejects requests that do not present the configured bearer
+// token, so metrics and debug endpoints can be exposed in locked-down environments.
+func requireAuthToken(next http.Handler, token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(req.Header.Get("Authorization")), []byte("Bearer "+token)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
 func defaultLeaderElectionConfiguration() componentbaseconfig.LeaderElectionConfiguration {
 	return componentbaseconfig.LeaderElectionConfiguration{
 		LeaderElect:   false,
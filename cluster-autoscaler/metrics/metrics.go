@@ -86,6 +86,12 @@ const (
 	ScaleDownMiscOperations    FunctionLabel = "scaleDown:miscOperations"
 	ScaleDownSoftTaintUnneeded FunctionLabel = "scaleDown:softTaintUnneeded"
 	ScaleUp                    FunctionLabel = "scaleUp"
+	ScaleUpPortalWait          FunctionLabel = "scaleUp:portalWait"
+	ScaleDownPortalWait        FunctionLabel = "scaleDown:portalWait"
+	ListNodes                  FunctionLabel = "listNodes"
+	ReadNodeGroupConfig        FunctionLabel = "readNodeGroupConfig"
+	EnforceNodeGroupLimits     FunctionLabel = "enforceNodeGroupLimits"
+	SnapshotInit               FunctionLabel = "snapshotInit"
 	FindUnneeded               FunctionLabel = "findUnneeded"
 	UpdateState                FunctionLabel = "updateClusterState"
 	FilterOutSchedulable       FunctionLabel = "filterOutSchedulable"
@@ -186,6 +192,30 @@ var (
 		}, []string{"node_group"},
 	)
 
+	nodesGroupTargetSize = k8smetrics.NewGaugeVec(
+		&k8smetrics.GaugeOpts{
+			Namespace: caNamespace,
+			Name:      "node_group_target_count",
+			Help:      "Target number of nodes in the node group",
+		}, []string{"node_group"},
+	)
+
+	nodesGroupCurrentSize = k8smetrics.NewGaugeVec(
+		&k8smetrics.GaugeOpts{
+			Namespace: caNamespace,
+			Name:      "node_group_current_count",
+			Help:      "Current number of nodes in the node group",
+		}, []string{"node_group"},
+	)
+
+	flavorRecommendation = k8smetrics.NewGaugeVec(
+		&k8smetrics.GaugeOpts{
+			Namespace: caNamespace,
+			Name:      "flavor_recommendation",
+			Help:      "Set to 1 when the long-term utilization of a worker pool suggests switching to a smaller or larger flavor.",
+		}, []string{"pool", "direction"},
+	)
+
 	/**** Metrics related to autoscaler execution ****/
 	lastActivity = k8smetrics.NewGaugeVec(
 		&k8smetrics.GaugeOpts{
@@ -222,6 +252,71 @@ var (
 		}, []string{"type"},
 	)
 
+	loopOverrunCount = k8smetrics.NewCounterVec(
+		&k8smetrics.CounterOpts{
+			Namespace: caNamespace,
+			Name:      "loop_overruns_total",
+			Help:      "The number of autoscaling iterations that were triggered while the previous one was still running, by the action taken.",
+		}, []string{"action"},
+	)
+
+	pendingPodAge = k8smetrics.NewHistogram(
+		&k8smetrics.HistogramOpts{
+			Namespace: caNamespace,
+			Name:      "pending_pod_age_seconds",
+			Help:      "How long the unschedulable pods considered for scale up have been pending.",
+			Buckets:   k8smetrics.ExponentialBuckets(1, 2, 14), // 1s to ~2.3h
+		},
+	)
+
+	clusterCostPerHour = k8smetrics.NewGauge(
+		&k8smetrics.GaugeOpts{
+			Namespace: caNamespace,
+			Name:      "cluster_cost_per_hour_estimate",
+			Help:      "Estimated hourly cost of all worker nodes, based on the configured flavor pricing.",
+		},
+	)
+
+	lastScaleCostDelta = k8smetrics.NewGaugeVec(
+		&k8smetrics.GaugeOpts{
+			Namespace: caNamespace,
+			Name:      "last_scale_cost_delta_per_hour",
+			Help:      "Estimated hourly cost change caused by the last scale operation, by operation type.",
+		}, []string{"operation"},
+	)
+
+	nodesCreatedCount = k8smetrics.NewCounterVec(
+		&k8smetrics.CounterOpts{
+			Namespace: caNamespace,
+			Name:      "nodes_created_total",
+			Help:      "Number of nodes requested from the portal by scale up, per worker pool. Rate over this counter exposes scale thrash.",
+		}, []string{"worker_pool"},
+	)
+
+	nodesDeletedCount = k8smetrics.NewCounterVec(
+		&k8smetrics.CounterOpts{
+			Namespace: caNamespace,
+			Name:      "nodes_deleted_total",
+			Help:      "Number of nodes removed through the portal by scale down, per worker pool. Rate over this counter exposes scale thrash.",
+		}, []string{"worker_pool"},
+	)
+
+	evictedPodsCount = k8smetrics.NewCounterVec(
+		&k8smetrics.CounterOpts{
+			Namespace: caNamespace,
+			Name:      "evicted_pods_total",
+			Help:      "Number of pods evicted by scale down, by namespace and owning controller.",
+		}, []string{"namespace", "controller"},
+	)
+
+	errorsByPhaseCount = k8smetrics.NewCounterVec(
+		&k8smetrics.CounterOpts{
+			Namespace: caNamespace,
+			Name:      "errors_by_phase_total",
+			Help:      "The number of CA errors by error type and the phase of the main loop they originated in.",
+		}, []string{"type", "phase"},
+	)
+
 	scaleUpCount = k8smetrics.NewCounter(
 		&k8smetrics.CounterOpts{
 			Namespace: caNamespace,
@@ -270,6 +365,49 @@ var (
 		},
 	)
 
+	failedEvictionsCount = k8smetrics.NewCounter(
+		&k8smetrics.CounterOpts{
+			Namespace: caNamespace,
+			Name:      "failed_evictions_total",
+			Help:      "Number of pods CA failed to evict within the per-pod timeout",
+		},
+	)
+
+	scaleOperationDuration = k8smetrics.NewHistogramVec(
+		&k8smetrics.HistogramOpts{
+			Namespace: caNamespace,
+			Name:      "scale_operation_duration_seconds",
+			Help:      "End to end time of scale operations, from detection to completion, including portal wait time.",
+			Buckets:   k8smetrics.ExponentialBuckets(10, 2, 12), // 10s - ~11h
+		}, []string{"node_group", "operation"},
+	)
+
+	nodeDeletionsInProgress = k8smetrics.NewGaugeVec(
+		&k8smetrics.GaugeOpts{
+			Namespace: caNamespace,
+			Name:      "node_deletions_in_progress",
+			Help:      "Number of node deletions currently in progress, per node group.",
+		},
+		[]string{"node_group"},
+	)
+
+	nodeDeletionDuration = k8smetrics.NewHistogram(
+		&k8smetrics.HistogramOpts{
+			Namespace: caNamespace,
+			Name:      "node_deletion_duration_seconds",
+			Help:      "Time taken to fully delete a node, from drain start to portal confirmation.",
+			Buckets:   k8smetrics.ExponentialBuckets(10, 2, 10), // 10s - ~2.8h
+		},
+	)
+
+	scaleDownRejectionCount = k8smetrics.NewCounterVec(
+		&k8smetrics.CounterOpts{
+			Namespace: caNamespace,
+			Name:      "scale_down_rejections_total",
+			Help:      "Number of times a worker node was rejected for scale down, by reason.",
+		}, []string{"reason"},
+	)
+
 	unneededNodesCount = k8smetrics.NewGauge(
 		&k8smetrics.GaugeOpts{
 			Namespace: caNamespace,
@@ -303,6 +441,40 @@ var (
 		},
 	)
 
+	/**** Metrics related to expander decisions ****/
+	expanderConsideredOptionsCount = k8smetrics.NewCounterVec(
+		&k8smetrics.CounterOpts{
+			Namespace: caNamespace,
+			Name:      "expander_considered_options_total",
+			Help:      "Number of expansion options passed to each expander strategy.",
+		}, []string{"strategy"},
+	)
+
+	expanderFilteredOptionsCount = k8smetrics.NewCounterVec(
+		&k8smetrics.CounterOpts{
+			Namespace: caNamespace,
+			Name:      "expander_filtered_options_total",
+			Help:      "Number of expansion options discarded by each expander strategy.",
+		}, []string{"strategy"},
+	)
+
+	expanderSelectionDuration = k8smetrics.NewHistogramVec(
+		&k8smetrics.HistogramOpts{
+			Namespace: caNamespace,
+			Name:      "expander_selection_duration_seconds",
+			Help:      "Time taken by each expander strategy to select among the options.",
+			Buckets:   k8smetrics.ExponentialBuckets(0.001, 4, 8), // 1ms - ~16s
+		}, []string{"strategy"},
+	)
+
+	expanderExternalFailuresCount = k8smetrics.NewCounterVec(
+		&k8smetrics.CounterOpts{
+			Namespace: caNamespace,
+			Name:      "expander_external_failures_total",
+			Help:      "Number of failed calls to external expander backends, by expander.",
+		}, []string{"expander"},
+	)
+
 	/**** Metrics related to NodeAutoprovisioning ****/
 	napEnabled = k8smetrics.NewGauge(
 		&k8smetrics.GaugeOpts{
@@ -344,23 +516,43 @@ func RegisterAll(emitPerNodeGroupMetrics bool) {
 	legacyregistry.MustRegister(functionDuration)
 	legacyregistry.MustRegister(functionDurationSummary)
 	legacyregistry.MustRegister(errorsCount)
+	legacyregistry.MustRegister(loopOverrunCount)
+	legacyregistry.MustRegister(pendingPodAge)
+	legacyregistry.MustRegister(evictedPodsCount)
+	legacyregistry.MustRegister(clusterCostPerHour)
+	legacyregistry.MustRegister(lastScaleCostDelta)
+	legacyregistry.MustRegister(nodesCreatedCount)
+	legacyregistry.MustRegister(nodesDeletedCount)
+	legacyregistry.MustRegister(errorsByPhaseCount)
 	legacyregistry.MustRegister(scaleUpCount)
 	legacyregistry.MustRegister(gpuScaleUpCount)
 	legacyregistry.MustRegister(failedScaleUpCount)
 	legacyregistry.MustRegister(scaleDownCount)
 	legacyregistry.MustRegister(gpuScaleDownCount)
 	legacyregistry.MustRegister(evictionsCount)
+	legacyregistry.MustRegister(failedEvictionsCount)
+	legacyregistry.MustRegister(scaleOperationDuration)
+	legacyregistry.MustRegister(scaleDownRejectionCount)
+	legacyregistry.MustRegister(nodeDeletionsInProgress)
+	legacyregistry.MustRegister(nodeDeletionDuration)
 	legacyregistry.MustRegister(unneededNodesCount)
 	legacyregistry.MustRegister(unremovableNodesCount)
 	legacyregistry.MustRegister(scaleDownInCooldown)
 	legacyregistry.MustRegister(oldUnregisteredNodesRemovedCount)
+	legacyregistry.MustRegister(expanderConsideredOptionsCount)
+	legacyregistry.MustRegister(expanderFilteredOptionsCount)
+	legacyregistry.MustRegister(expanderSelectionDuration)
+	legacyregistry.MustRegister(expanderExternalFailuresCount)
 	legacyregistry.MustRegister(napEnabled)
 	legacyregistry.MustRegister(nodeGroupCreationCount)
 	legacyregistry.MustRegister(nodeGroupDeletionCount)
+	legacyregistry.MustRegister(flavorRecommendation)
 
 	if emitPerNodeGroupMetrics {
 		legacyregistry.MustRegister(nodesGroupMinNodes)
 		legacyregistry.MustRegister(nodesGroupMaxNodes)
+		legacyregistry.MustRegister(nodesGroupTargetSize)
+		legacyregistry.MustRegister(nodesGroupCurrentSize)
 	}
 }
 
@@ -453,12 +645,82 @@ func UpdateNodeGroupMax(nodeGroup string, maxNodes int) {
 	nodesGroupMaxNodes.WithLabelValues(nodeGroup).Set(float64(maxNodes))
 }
 
+// UpdateNodeGroupTargetSize records the node group target number of nodes
+func UpdateNodeGroupTargetSize(nodeGroup string, targetNodes int) {
+	nodesGroupTargetSize.WithLabelValues(nodeGroup).Set(float64(targetNodes))
+}
+
+// UpdateNodeGroupCurrentSize records the node group current number of nodes
+func UpdateNodeGroupCurrentSize(nodeGroup string, currentNodes int) {
+	nodesGroupCurrentSize.WithLabelValues(nodeGroup).Set(float64(currentNodes))
+}
+
+// UpdateFlavorRecommendation records whether the long-term utilization of the
+// worker pool suggests switching to a smaller or larger flavor.
+func UpdateFlavorRecommendation(pool string, direction string, active bool) {
+	value := 0.0
+	if active {
+		value = 1.0
+	}
+	flavorRecommendation.WithLabelValues(pool, direction).Set(value)
+}
+
 // RegisterError records any errors preventing Cluster Autoscaler from working.
 // No more than one error should be recorded per loop.
 func RegisterError(err errors.AutoscalerError) {
 	errorsCount.WithLabelValues(string(err.Type())).Add(1.0)
 }
 
+// RegisterLoopOverrun records an autoscaling iteration that was triggered while
+// the previous one was still running, and whether it was skipped or queued.
+func RegisterLoopOverrun(action string) {
+	loopOverrunCount.WithLabelValues(action).Add(1.0)
+}
+
+// ObservePendingPodAge records how long an unschedulable pod considered for
+// scale up has been pending, measuring scale up latency as experienced by
+// workloads rather than by the autoscaler loop.
+func ObservePendingPodAge(age time.Duration) {
+	pendingPodAge.Observe(age.Seconds())
+}
+
+// UpdateClusterCostPerHour records the estimated hourly cost of the worker
+// nodes currently in the cluster.
+func UpdateClusterCostPerHour(cost float64) {
+	clusterCostPerHour.Set(cost)
+}
+
+// ObserveScaleCostDelta records the estimated hourly cost change caused by a
+// scale operation. Negative values mean savings from scale down.
+func ObserveScaleCostDelta(operation string, delta float64) {
+	lastScaleCostDelta.WithLabelValues(operation).Set(delta)
+}
+
+// RegisterNodesCreated records nodes requested from the portal for the given
+// worker pool during scale up.
+func RegisterNodesCreated(workerPool string, nodesCount int) {
+	nodesCreatedCount.WithLabelValues(workerPool).Add(float64(nodesCount))
+}
+
+// RegisterNodesDeleted records nodes removed through the portal for the given
+// worker pool during scale down.
+func RegisterNodesDeleted(workerPool string, nodesCount int) {
+	nodesDeletedCount.WithLabelValues(workerPool).Add(float64(nodesCount))
+}
+
+// RegisterEvictedPod records a pod evicted during scale down, attributed to its
+// namespace and owning controller so disruption per workload can be tracked.
+func RegisterEvictedPod(namespace string, controller string) {
+	evictedPodsCount.WithLabelValues(namespace, controller).Add(1.0)
+}
+
+// RegisterErrorForPhase records an error together with the phase of the main
+// loop it originated in, so portal outages can be told apart from Kubernetes
+// API problems and internal bugs.
+func RegisterErrorForPhase(err errors.AutoscalerError, phase FunctionLabel) {
+	errorsByPhaseCount.WithLabelValues(string(err.Type()), string(phase)).Add(1.0)
+}
+
 // RegisterScaleUp records number of nodes added by scale up
 func RegisterScaleUp(nodesCount int, gpuType string) {
 	scaleUpCount.Add(float64(nodesCount))
@@ -485,6 +747,32 @@ func RegisterEvictions(podsCount int) {
 	evictionsCount.Add(float64(podsCount))
 }
 
+// RegisterFailedEvictions records number of pods that failed to be evicted
+func RegisterFailedEvictions(podsCount int) {
+	failedEvictionsCount.Add(float64(podsCount))
+}
+
+// ObserveScaleOperationDuration records the end to end time of a scale operation,
+// from detection (first pending pod or node marked unneeded) to completion.
+func ObserveScaleOperationDuration(nodeGroup string, operation string, duration time.Duration) {
+	scaleOperationDuration.WithLabelValues(nodeGroup, operation).Observe(duration.Seconds())
+}
+
+// RegisterScaleDownRejection records a worker node being rejected for scale down
+func RegisterScaleDownRejection(reason string) {
+	scaleDownRejectionCount.WithLabelValues(reason).Inc()
+}
+
+// UpdateNodeDeletionsInProgress records the number of in flight node deletions for a node group
+func UpdateNodeDeletionsInProgress(nodeGroup string, deletionsCount int) {
+	nodeDeletionsInProgress.WithLabelValues(nodeGroup).Set(float64(deletionsCount))
+}
+
+// ObserveNodeDeletionDuration records how long a finished node deletion took
+func ObserveNodeDeletionDuration(duration time.Duration) {
+	nodeDeletionDuration.Observe(duration.Seconds())
+}
+
 // UpdateUnneededNodesCount records number of currently unneeded nodes
 func UpdateUnneededNodesCount(nodesCount int) {
 	unneededNodesCount.Set(float64(nodesCount))
@@ -497,6 +785,23 @@ func UpdateUnremovableNodesCount(unremovableReasonCounts map[simulator.Unremovab
 	}
 }
 
+// UpdateExpanderSelection records one invocation of an expander strategy: the
+// number of options it saw, the number it discarded and how long it took.
+func UpdateExpanderSelection(strategy string, optionsIn int, optionsOut int, duration time.Duration) {
+	expanderConsideredOptionsCount.WithLabelValues(strategy).Add(float64(optionsIn))
+	if filtered := optionsIn - optionsOut; filtered > 0 {
+		expanderFilteredOptionsCount.WithLabelValues(strategy).Add(float64(filtered))
+	}
+	expanderSelectionDuration.WithLabelValues(strategy).Observe(duration.Seconds())
+}
+
+// RegisterExpanderExternalFailure records a failed call to an external
+// expander backend, e.g. a webhook expander request that errored or a chain
+// filter that exceeded the expander time budget.
+func RegisterExpanderExternalFailure(expanderName string) {
+	expanderExternalFailuresCount.WithLabelValues(expanderName).Add(1.0)
+}
+
 // UpdateNapEnabled records if NodeAutoprovisioning is enabled
 func UpdateNapEnabled(enabled bool) {
 	if enabled {
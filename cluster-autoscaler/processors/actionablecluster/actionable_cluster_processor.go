@@ -24,6 +24,7 @@ import (
 	"k8s.io/autoscaler/cluster-autoscaler/context"
 	"k8s.io/autoscaler/cluster-autoscaler/metrics"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/errors"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/events"
 	"k8s.io/klog/v2"
 )
 
@@ -74,7 +75,7 @@ func OnEmptyCluster(context *context.AutoscalingContext, status string, emitEven
 		utils.WriteStatusConfigMap(context.ClientSet, context.ConfigNamespace, status, context.LogRecorder, context.StatusConfigMapName)
 	}
 	if emitEvent {
-		context.LogRecorder.Eventf(apiv1.EventTypeWarning, "ClusterUnhealthy", status)
+		context.LogRecorder.Eventf(apiv1.EventTypeWarning, events.ClusterUnhealthy, status)
 	}
 }
 
@@ -20,10 +20,10 @@ import (
 	apiv1 "k8s.io/api/core/v1"
 	kube_client "k8s.io/client-go/kubernetes"
 	klog "k8s.io/klog/v2"
-	"strings"
 
 	"k8s.io/autoscaler/cluster-autoscaler/context"
-	"k8s.io/autoscaler/cluster-autoscaler/utils"
+	core_utils "k8s.io/autoscaler/cluster-autoscaler/core/utils"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/deletetaint"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/errors"
 )
 
@@ -45,9 +45,16 @@ func (n *PreFilteringScaleDownNodeProcessor) GetScaleDownCandidates(ctx *context
 	nodes []*apiv1.Node, kubeclient kube_client.Interface) ([]*apiv1.Node, errors.AutoscalerError) {
 	result := make([]*apiv1.Node, 0, len(nodes))
 
+	minSizeNodeGroup, minSizeErr := core_utils.GetMinSizeNodeGroup(kubeclient)
+	if minSizeErr != nil {
+		return nil, errors.ToAutoscalerError(errors.TransientError, minSizeErr)
+	}
+
 	var numberWorkerNode int = 0
 	for _, node := range nodes {
-		if strings.Contains(node.Name, "worker") {
+		// Workers already tainted for deletion no longer count toward the group
+		// size, matching how the scale down path counts them.
+		if core_utils.IsWorkerNode(node) && !deletetaint.HasToBeDeletedTaint(node) {
 			numberWorkerNode += 1
 		}
 	}
@@ -67,7 +74,7 @@ func (n *PreFilteringScaleDownNodeProcessor) GetScaleDownCandidates(ctx *context
 		//	klog.Errorf("Error while checking node group size %s: group size not found", nodeGroup.Id())
 		//	continue
 		//}
-		if size <= utils.GetMinSizeNodeGroup(kubeclient) {
+		if size <= minSizeNodeGroup {
 			klog.V(1).Infof("Skipping %s - node group min size reached", node.Name)
 			continue
 		}
@@ -25,6 +25,8 @@ import (
 	"strings"
 
 	"k8s.io/autoscaler/cluster-autoscaler/context"
+	"k8s.io/autoscaler/cluster-autoscaler/statusreport"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/events"
 )
 
 // EventingScaleUpStatusProcessor processes the state of the cluster after
@@ -40,16 +42,17 @@ func (p *EventingScaleUpStatusProcessor) Process(context *context.AutoscalingCon
 	//for _, pod := range status.PodsRemainUnschedulable {
 	//	fmt.Println(pod.Pod.Name)
 	//}
+	statusreport.UpdatePodFitAnalysis(buildPodFitAnalysis(status.PodsRemainUnschedulable))
 	for _, pod := range status.PodsRemainUnschedulable {
-		events, _ := kubeclient.CoreV1().Events(pod.Pod.Namespace).List(ctx.TODO(), metav1.ListOptions{FieldSelector: "involvedObject.name=" + pod.Pod.Name, TypeMeta: metav1.TypeMeta{Kind: "Pod"}})
+		podEvents, _ := kubeclient.CoreV1().Events(pod.Pod.Namespace).List(ctx.TODO(), metav1.ListOptions{FieldSelector: "involvedObject.name=" + pod.Pod.Name, TypeMeta: metav1.TypeMeta{Kind: "Pod"}, ResourceVersion: "0"})
 		//fmt.Println("first event of ", pod.Pod.Name, " is: ", events.Items[0].Message)
 
-		if strings.Contains(events.Items[0].Message, "Insufficient") == false {
-			context.Recorder.Event(pod.Pod, apiv1.EventTypeNormal, "NotTriggerScaleUp",
-				fmt.Sprintf("pod didn't trigger scale-up"))
+		if strings.Contains(podEvents.Items[0].Message, "Insufficient") == false {
+			events.Record(context.Recorder, pod.Pod, apiv1.EventTypeNormal, events.ScaleUpNotTriggered,
+				fmt.Sprintf("pod did not trigger scale-up: pod=%s/%s", pod.Pod.Namespace, pod.Pod.Name))
 		} else {
-			context.Recorder.Event(pod.Pod, apiv1.EventTypeNormal, "TriggerScaleUp",
-				fmt.Sprintf("pod trigger scale-up"))
+			events.Record(context.Recorder, pod.Pod, apiv1.EventTypeNormal, events.ScaleUpTriggered,
+				fmt.Sprintf("pod triggered scale-up: pod=%s/%s", pod.Pod.Namespace, pod.Pod.Name))
 		}
 	}
 }
@@ -58,6 +61,32 @@ func (p *EventingScaleUpStatusProcessor) Process(context *context.AutoscalingCon
 func (p *EventingScaleUpStatusProcessor) CleanUp() {
 }
 
+// buildPodFitAnalysis converts NoScaleUpInfos to the per-pod fit analysis
+// served from the status endpoint, listing which node groups were rejected or
+// skipped for each pod and why.
+func buildPodFitAnalysis(noScaleUpInfos []NoScaleUpInfo) []statusreport.PodFitAnalysis {
+	analysis := make([]statusreport.PodFitAnalysis, 0, len(noScaleUpInfos))
+	for _, info := range noScaleUpInfos {
+		podFit := statusreport.PodFitAnalysis{Pod: info.Pod.Namespace + "/" + info.Pod.Name}
+		for nodeGroup, reasons := range info.RejectedNodeGroups {
+			podFit.NodeGroups = append(podFit.NodeGroups, statusreport.NodeGroupFit{
+				NodeGroup: nodeGroup,
+				Outcome:   "rejected",
+				Reasons:   reasons.Reasons(),
+			})
+		}
+		for nodeGroup, reasons := range info.SkippedNodeGroups {
+			podFit.NodeGroups = append(podFit.NodeGroups, statusreport.NodeGroupFit{
+				NodeGroup: nodeGroup,
+				Outcome:   "skipped",
+				Reasons:   reasons.Reasons(),
+			})
+		}
+		analysis = append(analysis, podFit)
+	}
+	return analysis
+}
+
 // ReasonsMessage aggregates reasons from NoScaleUpInfos.
 func ReasonsMessage(noScaleUpInfo NoScaleUpInfo) string {
 	messages := []string{}
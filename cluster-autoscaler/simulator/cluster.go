@@ -29,7 +29,6 @@ import (
 
 	apiv1 "k8s.io/api/core/v1"
 	policyv1 "k8s.io/api/policy/v1beta1"
-	"k8s.io/apimachinery/pkg/api/resource"
 	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
 
 	klog "k8s.io/klog/v2"
@@ -95,6 +94,18 @@ const (
 	BlockedByPod
 	// UnexpectedError - node can't be removed because of an unexpected error.
 	UnexpectedError
+	// RecentPodActivity - node can't be removed because pods terminated on it recently
+	// and the capacity is likely to be reused soon, e.g. by a CronJob.
+	RecentPodActivity
+	// AwaitingPreemption - node can't be removed because it is the nominated
+	// target of a pod waiting for lower priority pods preemption.
+	AwaitingPreemption
+	// HeadroomRequired - node can't be removed because its pool would fall
+	// below the configured free capacity watermark.
+	HeadroomRequired
+	// ScaleDownExcludedTaint - node can't be removed because it carries a taint
+	// configured to exclude it from scale down.
+	ScaleDownExcludedTaint
 )
 
 // UtilizationInfo contains utilization information for a node.
@@ -109,7 +120,9 @@ type UtilizationInfo struct {
 }
 
 // FindNodesToRemove finds nodes that can be removed. Returns also an information about good
-// rescheduling location for each of the pods.
+// rescheduling location for each of the pods. A non-zero deadline caps the time
+// spent simulating drains: candidates not checked before it passes are left out
+// of both result lists, so the caller re-checks them on the next loop.
 func FindNodesToRemove(
 	candidates []string,
 	destinations []string,
@@ -120,6 +133,7 @@ func FindNodesToRemove(
 	usageTracker *UsageTracker,
 	timestamp time.Time,
 	podDisruptionBudgets []*policyv1.PodDisruptionBudget,
+	deadline time.Time,
 ) (nodesToRemove []NodeToBeRemoved, unremovableNodes []*UnremovableNode, podReschedulingHints map[string]string, finalError errors.AutoscalerError) {
 	result := make([]NodeToBeRemoved, 0)
 	unremovable := make([]*UnremovableNode, 0)
@@ -130,7 +144,11 @@ func FindNodesToRemove(
 		destinationMap[destination] = true
 	}
 
-	for _, nodeName := range candidates {
+	for i, nodeName := range candidates {
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			klog.V(1).Infof("Scale-down simulation deadline exceeded after checking %v of %v candidates, deferring the rest to the next loop", i, len(candidates))
+			break
+		}
 		nodeInfo, err := clusterSnapshot.NodeInfos().Get(nodeName)
 		if err != nil {
 			klog.Errorf("Can't retrieve node %s from snapshot, err: %v", nodeName, err)
@@ -190,61 +208,46 @@ func FindEmptyNodesToRemove(snapshot ClusterSnapshot, candidates []string, times
 	return result
 }
 
+// ResourceNvidiaGPU is the extended resource exposed by the nvidia device plugin.
+const ResourceNvidiaGPU = apiv1.ResourceName("nvidia.com/gpu")
+
 // CalculateUtilization calculates utilization of a node, defined as maximum of (cpu, memory) or gpu utilization
 // based on if the node has GPU or not. Per resource utilization is the sum of requests for it divided by allocatable.
 // It also returns the individual cpu, memory and gpu utilization.
+//
+// It is called for every scale-down candidate on every loop, so the requests
+// of all pods are accumulated in a single pass over nodeInfo.Pods instead of
+// one pass per resource.
 func CalculateUtilization(node *apiv1.Node, nodeInfo *schedulerframework.NodeInfo, skipDaemonSetPods, skipMirrorPods bool, currentTime time.Time) (utilInfo UtilizationInfo, err error) {
-	cpu, err := calculateUtilizationOfResource(node, nodeInfo, apiv1.ResourceCPU, skipDaemonSetPods, skipMirrorPods, currentTime)
-	if err != nil {
-		return UtilizationInfo{}, err
+	cpuAllocatable, found := node.Status.Allocatable[apiv1.ResourceCPU]
+	if !found {
+		return UtilizationInfo{}, fmt.Errorf("failed to get %v from %s", apiv1.ResourceCPU, node.Name)
 	}
-	mem, err := calculateUtilizationOfResource(node, nodeInfo, apiv1.ResourceMemory, skipDaemonSetPods, skipMirrorPods, currentTime)
-	if err != nil {
-		return UtilizationInfo{}, err
+	if cpuAllocatable.MilliValue() == 0 {
+		return UtilizationInfo{}, fmt.Errorf("%v is 0 at %s", apiv1.ResourceCPU, node.Name)
 	}
-
-	utilization := UtilizationInfo{CpuUtil: cpu, MemUtil: mem}
-
-	if cpu > mem {
-		utilization.ResourceName = apiv1.ResourceCPU
-		utilization.Utilization = cpu
-	} else {
-		utilization.ResourceName = apiv1.ResourceMemory
-		utilization.Utilization = mem
-	}
-
-	return utilization, nil
-}
-
-func calculateUtilizationOfResource(node *apiv1.Node, nodeInfo *schedulerframework.NodeInfo, resourceName apiv1.ResourceName, skipDaemonSetPods, skipMirrorPods bool, currentTime time.Time) (float64, error) {
-	nodeAllocatable, found := node.Status.Allocatable[resourceName]
+	memAllocatable, found := node.Status.Allocatable[apiv1.ResourceMemory]
 	if !found {
-		return 0, fmt.Errorf("failed to get %v from %s", resourceName, node.Name)
+		return UtilizationInfo{}, fmt.Errorf("failed to get %v from %s", apiv1.ResourceMemory, node.Name)
 	}
-	if nodeAllocatable.MilliValue() == 0 {
-		return 0, fmt.Errorf("%v is 0 at %s", resourceName, node.Name)
+	if memAllocatable.MilliValue() == 0 {
+		return UtilizationInfo{}, fmt.Errorf("%v is 0 at %s", apiv1.ResourceMemory, node.Name)
 	}
-	podsRequest := resource.MustParse("0")
 
 	// if skipDaemonSetPods = True, DaemonSet pods resourses will be subtracted
 	// from the node allocatable and won't be added to pods requests
 	// the same with the Mirror pod.
-	daemonSetAndMirrorPodsUtilization := resource.MustParse("0")
+	var cpuRequest, memRequest, gpuRequest, cpuDaemonSetAndMirror, memDaemonSetAndMirror int64
 	for _, podInfo := range nodeInfo.Pods {
-		// factor daemonset pods out of the utilization calculations
-		if skipDaemonSetPods && pod_util.IsDaemonSetPod(podInfo.Pod) {
+		// factor daemonset and mirror pods out of the utilization calculations
+		if (skipDaemonSetPods && pod_util.IsDaemonSetPod(podInfo.Pod)) ||
+			(skipMirrorPods && pod_util.IsMirrorPod(podInfo.Pod)) {
 			for _, container := range podInfo.Pod.Spec.Containers {
-				if resourceValue, found := container.Resources.Requests[resourceName]; found {
-					daemonSetAndMirrorPodsUtilization.Add(resourceValue)
+				if resourceValue, found := container.Resources.Requests[apiv1.ResourceCPU]; found {
+					cpuDaemonSetAndMirror += resourceValue.MilliValue()
 				}
-			}
-			continue
-		}
-		// factor mirror pods out of the utilization calculations
-		if skipMirrorPods && pod_util.IsMirrorPod(podInfo.Pod) {
-			for _, container := range podInfo.Pod.Spec.Containers {
-				if resourceValue, found := container.Resources.Requests[resourceName]; found {
-					daemonSetAndMirrorPodsUtilization.Add(resourceValue)
+				if resourceValue, found := container.Resources.Requests[apiv1.ResourceMemory]; found {
+					memDaemonSetAndMirror += resourceValue.MilliValue()
 				}
 			}
 			continue
@@ -254,12 +257,41 @@ func calculateUtilizationOfResource(node *apiv1.Node, nodeInfo *schedulerframewo
 			continue
 		}
 		for _, container := range podInfo.Pod.Spec.Containers {
-			if resourceValue, found := container.Resources.Requests[resourceName]; found {
-				podsRequest.Add(resourceValue)
+			if resourceValue, found := container.Resources.Requests[apiv1.ResourceCPU]; found {
+				cpuRequest += resourceValue.MilliValue()
+			}
+			if resourceValue, found := container.Resources.Requests[apiv1.ResourceMemory]; found {
+				memRequest += resourceValue.MilliValue()
+			}
+			if resourceValue, found := container.Resources.Requests[ResourceNvidiaGPU]; found {
+				gpuRequest += resourceValue.Value()
 			}
 		}
 	}
-	return float64(podsRequest.MilliValue()) / float64(nodeAllocatable.MilliValue()-daemonSetAndMirrorPodsUtilization.MilliValue()), nil
+
+	cpu := float64(cpuRequest) / float64(cpuAllocatable.MilliValue()-cpuDaemonSetAndMirror)
+	mem := float64(memRequest) / float64(memAllocatable.MilliValue()-memDaemonSetAndMirror)
+	utilization := UtilizationInfo{CpuUtil: cpu, MemUtil: mem}
+
+	// On GPU nodes the expensive resource is the GPU, so its utilization alone
+	// drives the default scale-down decision.
+	if gpuAllocatable, found := node.Status.Allocatable[ResourceNvidiaGPU]; found && gpuAllocatable.Value() > 0 {
+		gpu := float64(gpuRequest) / float64(gpuAllocatable.Value())
+		utilization.GpuUtil = gpu
+		utilization.ResourceName = ResourceNvidiaGPU
+		utilization.Utilization = gpu
+		return utilization, nil
+	}
+
+	if cpu > mem {
+		utilization.ResourceName = apiv1.ResourceCPU
+		utilization.Utilization = cpu
+	} else {
+		utilization.ResourceName = apiv1.ResourceMemory
+		utilization.Utilization = mem
+	}
+
+	return utilization, nil
 }
 
 func findPlaceFor(removedNode string, pods []*apiv1.Pod, nodes map[string]bool,
@@ -0,0 +1,68 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulator
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// The target scale for a single loop is 5,000 nodes with 50,000 pods. These
+// benchmarks cover the per-candidate hot path of scale down, which visits
+// every node each iteration. Run with -cpuprofile/-memprofile to find
+// regressions:
+//
+//	go test ./simulator/ -run=none -bench=BenchmarkCalculateUtilization -cpuprofile=cpu.out
+func BenchmarkCalculateUtilization(b *testing.B) {
+	testCases := []struct {
+		nodeCount   int
+		podsPerNode int
+	}{
+		{nodeCount: 1000, podsPerNode: 10},
+		{nodeCount: 5000, podsPerNode: 10},
+	}
+
+	for _, tc := range testCases {
+		nodes := createTestNodes(tc.nodeCount)
+		pods := createTestPods(tc.nodeCount * tc.podsPerNode)
+		assignPodsToNodes(pods, nodes)
+		clusterSnapshot := NewDeltaClusterSnapshot()
+		err := clusterSnapshot.AddNodes(nodes)
+		assert.NoError(b, err)
+		for _, pod := range pods {
+			err = clusterSnapshot.AddPod(pod, pod.Spec.NodeName)
+			assert.NoError(b, err)
+		}
+		nodeInfos, err := clusterSnapshot.NodeInfos().List()
+		assert.NoError(b, err)
+		timestamp := time.Now()
+		b.ResetTimer()
+		b.Run(fmt.Sprintf("%d nodes, %d pods each", tc.nodeCount, tc.podsPerNode), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for _, nodeInfo := range nodeInfos {
+					_, err := CalculateUtilization(nodeInfo.Node(), nodeInfo, true, true, timestamp)
+					if err != nil {
+						assert.NoError(b, err)
+					}
+				}
+			}
+		})
+	}
+}
@@ -268,3 +268,44 @@ func BenchmarkBuildNodeInfoList(b *testing.B) {
 		})
 	}
 }
+
+func BenchmarkForkRemoveRevert(b *testing.B) {
+	// Scale down simulation forks the snapshot, removes a candidate node and
+	// reverts, so this shape dominates loop time on large clusters.
+	nodeTestCases := []int{1000, 5000}
+	podTestCases := []int{0, 30}
+
+	for snapshotName, snapshotFactory := range snapshots {
+		for _, ntc := range nodeTestCases {
+			nodes := createTestNodes(ntc)
+			for _, ptc := range podTestCases {
+				pods := createTestPods(ntc * ptc)
+				assignPodsToNodes(pods, nodes)
+				clusterSnapshot := snapshotFactory()
+				err := clusterSnapshot.AddNodes(nodes)
+				assert.NoError(b, err)
+				for _, pod := range pods {
+					err = clusterSnapshot.AddPod(pod, pod.Spec.NodeName)
+					assert.NoError(b, err)
+				}
+				b.ResetTimer()
+				b.Run(fmt.Sprintf("%s: ForkRemoveRevert (%d nodes, %d pods)", snapshotName, ntc, ptc), func(b *testing.B) {
+					for i := 0; i < b.N; i++ {
+						err = clusterSnapshot.Fork()
+						if err != nil {
+							assert.NoError(b, err)
+						}
+						err = clusterSnapshot.RemoveNode(nodes[len(nodes)-1].Name)
+						if err != nil {
+							assert.NoError(b, err)
+						}
+						err = clusterSnapshot.Revert()
+						if err != nil {
+							assert.NoError(b, err)
+						}
+					}
+				})
+			}
+		}
+	}
+}
@@ -24,6 +24,7 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	apiv1 "k8s.io/api/core/v1"
 	policyv1 "k8s.io/api/policy/v1beta1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/drain"
 	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
@@ -36,7 +37,6 @@ import (
 
 func TestUtilization(t *testing.T) {
 	testTime := time.Date(2020, time.December, 18, 17, 0, 0, 0, time.UTC)
-	gpuLabel := GetGPULabel()
 	pod := BuildTestPod("p1", 100, 200000)
 	pod2 := BuildTestPod("p2", -1, -1)
 
@@ -44,13 +44,13 @@ func TestUtilization(t *testing.T) {
 	node := BuildTestNode("node1", 2000, 2000000)
 	SetNodeReadyState(node, true, time.Time{})
 
-	utilInfo, err := CalculateUtilization(node, nodeInfo, false, false, gpuLabel, testTime)
+	utilInfo, err := CalculateUtilization(node, nodeInfo, false, false, testTime)
 	assert.NoError(t, err)
 	assert.InEpsilon(t, 2.0/10, utilInfo.Utilization, 0.01)
 
 	node2 := BuildTestNode("node1", 2000, -1)
 
-	_, err = CalculateUtilization(node2, nodeInfo, false, false, gpuLabel, testTime)
+	_, err = CalculateUtilization(node2, nodeInfo, false, false, testTime)
 	assert.Error(t, err)
 
 	daemonSetPod3 := BuildTestPod("p3", 100, 200000)
@@ -61,19 +61,19 @@ func TestUtilization(t *testing.T) {
 	daemonSetPod4.Annotations = map[string]string{"cluster-autoscaler.kubernetes.io/daemonset-pod": "true"}
 
 	nodeInfo = schedulerframework.NewNodeInfo(pod, pod, pod2, daemonSetPod3, daemonSetPod4)
-	utilInfo, err = CalculateUtilization(node, nodeInfo, true, false, gpuLabel, testTime)
+	utilInfo, err = CalculateUtilization(node, nodeInfo, true, false, testTime)
 	assert.NoError(t, err)
 	assert.InEpsilon(t, 2.5/10, utilInfo.Utilization, 0.01)
 
 	nodeInfo = schedulerframework.NewNodeInfo(pod, pod2, daemonSetPod3)
-	utilInfo, err = CalculateUtilization(node, nodeInfo, false, false, gpuLabel, testTime)
+	utilInfo, err = CalculateUtilization(node, nodeInfo, false, false, testTime)
 	assert.NoError(t, err)
 	assert.InEpsilon(t, 2.0/10, utilInfo.Utilization, 0.01)
 
 	terminatedPod := BuildTestPod("podTerminated", 100, 200000)
 	terminatedPod.DeletionTimestamp = &metav1.Time{Time: testTime.Add(-10 * time.Minute)}
 	nodeInfo = schedulerframework.NewNodeInfo(pod, pod, pod2, terminatedPod)
-	utilInfo, err = CalculateUtilization(node, nodeInfo, false, false, gpuLabel, testTime)
+	utilInfo, err = CalculateUtilization(node, nodeInfo, false, false, testTime)
 	assert.NoError(t, err)
 	assert.InEpsilon(t, 2.0/10, utilInfo.Utilization, 0.01)
 
@@ -83,37 +83,37 @@ func TestUtilization(t *testing.T) {
 	}
 
 	nodeInfo = schedulerframework.NewNodeInfo(pod, pod, pod2, mirrorPod)
-	utilInfo, err = CalculateUtilization(node, nodeInfo, false, true, gpuLabel, testTime)
+	utilInfo, err = CalculateUtilization(node, nodeInfo, false, true, testTime)
 	assert.NoError(t, err)
 	assert.InEpsilon(t, 2.0/9.0, utilInfo.Utilization, 0.01)
 
 	nodeInfo = schedulerframework.NewNodeInfo(pod, pod2, mirrorPod)
-	utilInfo, err = CalculateUtilization(node, nodeInfo, false, false, gpuLabel, testTime)
+	utilInfo, err = CalculateUtilization(node, nodeInfo, false, false, testTime)
 	assert.NoError(t, err)
 	assert.InEpsilon(t, 2.0/10, utilInfo.Utilization, 0.01)
 
 	nodeInfo = schedulerframework.NewNodeInfo(pod, mirrorPod, daemonSetPod3)
-	utilInfo, err = CalculateUtilization(node, nodeInfo, true, true, gpuLabel, testTime)
+	utilInfo, err = CalculateUtilization(node, nodeInfo, true, true, testTime)
 	assert.NoError(t, err)
 	assert.InEpsilon(t, 1.0/8.0, utilInfo.Utilization, 0.01)
+}
 
-	gpuNode := BuildTestNode("gpu_node", 2000, 2000000)
-	AddGpusToNode(gpuNode, 1)
-	gpuPod := BuildTestPod("gpu_pod", 100, 200000)
-	RequestGpuForPod(gpuPod, 1)
-	TolerateGpuForPod(gpuPod)
-	nodeInfo = schedulerframework.NewNodeInfo(pod, pod, gpuPod)
-	utilInfo, err = CalculateUtilization(gpuNode, nodeInfo, false, false, gpuLabel, testTime)
-	assert.NoError(t, err)
-	assert.InEpsilon(t, 1/1, utilInfo.Utilization, 0.01)
+func TestGpuNodeUtilization(t *testing.T) {
+	testTime := time.Date(2020, time.December, 18, 17, 0, 0, 0, time.UTC)
+	pod := BuildTestPod("p1", 1500, 200000)
+	pod.Spec.Containers[0].Resources.Requests[ResourceNvidiaGPU] = *resource.NewQuantity(1, resource.DecimalSI)
+
+	nodeInfo := schedulerframework.NewNodeInfo(pod)
+	node := BuildTestNode("node1", 2000, 2000000)
+	node.Status.Allocatable[ResourceNvidiaGPU] = *resource.NewQuantity(4, resource.DecimalSI)
+	SetNodeReadyState(node, true, time.Time{})
 
-	// Node with Unready GPU
-	gpuNode = BuildTestNode("gpu_node", 2000, 2000000)
-	AddGpuLabelToNode(gpuNode)
-	nodeInfo = schedulerframework.NewNodeInfo(pod, pod)
-	utilInfo, err = CalculateUtilization(gpuNode, nodeInfo, false, false, gpuLabel, testTime)
+	// The GPU utilization alone drives the decision, even though cpu is higher.
+	utilInfo, err := CalculateUtilization(node, nodeInfo, false, false, testTime)
 	assert.NoError(t, err)
-	assert.Zero(t, utilInfo.Utilization)
+	assert.Equal(t, ResourceNvidiaGPU, utilInfo.ResourceName)
+	assert.InEpsilon(t, 1.0/4, utilInfo.Utilization, 0.01)
+	assert.InEpsilon(t, 1.5/2, utilInfo.CpuUtil, 0.01)
 }
 
 func nodeInfos(nodes []*apiv1.Node) []*schedulerframework.NodeInfo {
@@ -412,7 +412,7 @@ func TestFindNodesToRemove(t *testing.T) {
 			toRemove, unremovable, _, err := FindNodesToRemove(
 				test.candidates, destinations, registry,
 				clusterSnapshot, predicateChecker, map[string]string{},
-				tracker, time.Now(), []*policyv1.PodDisruptionBudget{})
+				tracker, time.Now(), []*policyv1.PodDisruptionBudget{}, time.Time{})
 			assert.NoError(t, err)
 			fmt.Printf("Test scenario: %s, found len(toRemove)=%v, expected len(test.toRemove)=%v\n", test.name, len(toRemove), len(test.toRemove))
 			assert.Equal(t, toRemove, test.toRemove)
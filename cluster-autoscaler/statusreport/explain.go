@@ -0,0 +1,86 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package statusreport
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	klog "k8s.io/klog/v2"
+)
+
+// NodeExplanation tells why a specific node is or is not being scaled down,
+// built from the scale down state of the last loop iteration.
+type NodeExplanation struct {
+	Node string `json:"node"`
+	// CpuUtilization and MemUtilization are fractions of the node's capacity
+	// requested by its pods.
+	CpuUtilization float64 `json:"cpuUtilization"`
+	MemUtilization float64 `json:"memUtilization"`
+	// UnneededSince is set when the node is currently considered unneeded.
+	UnneededSince *time.Time `json:"unneededSince,omitempty"`
+	// Reason tells why the node cannot be removed, empty when nothing blocks it.
+	Reason string `json:"reason,omitempty"`
+	// BlockingPod names the pod preventing the drain, when the reason is a
+	// blocked drain.
+	BlockingPod string `json:"blockingPod,omitempty"`
+	// BlockingPodReason tells why that pod cannot be moved.
+	BlockingPodReason string `json:"blockingPodReason,omitempty"`
+	// ScaleDownInCooldown is true when scale down is globally suspended, for
+	// example shortly after a scale up.
+	ScaleDownInCooldown bool `json:"scaleDownInCooldown"`
+}
+
+var (
+	explainMutex        sync.Mutex
+	currentExplanations map[string]NodeExplanation
+)
+
+// UpdateExplanations replaces the per-node explanations served from the
+// explain endpoint.
+func UpdateExplanations(explanations map[string]NodeExplanation) {
+	explainMutex.Lock()
+	defer explainMutex.Unlock()
+	currentExplanations = explanations
+}
+
+// ServeExplainHTTP serves per-node scale down explanations as JSON. With a
+// node query parameter it returns the explanation for that node only,
+// otherwise all of them.
+func ServeExplainHTTP(w http.ResponseWriter, req *http.Request) {
+	explainMutex.Lock()
+	explanations := currentExplanations
+	explainMutex.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if nodeName := req.URL.Query().Get("node"); nodeName != "" {
+		explanation, found := explanations[nodeName]
+		if !found {
+			http.Error(w, "no explanation recorded for node "+nodeName, http.StatusNotFound)
+			return
+		}
+		if err := json.NewEncoder(w).Encode(explanation); err != nil {
+			klog.Errorf("Failed to write node explanation: %v", err)
+		}
+		return
+	}
+	if err := json.NewEncoder(w).Encode(explanations); err != nil {
+		klog.Errorf("Failed to write node explanations: %v", err)
+	}
+}
@@ -0,0 +1,170 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package statusreport
+
+import (
+	ctx "context"
+	"fmt"
+	"sync"
+	"time"
+
+	kube_errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	klog "k8s.io/klog/v2"
+)
+
+// statusCRResource identifies the ClusterAutoscalerStatus custom resource. The
+// CRD is expected to be installed by the platform; publishing is skipped with
+// an error log when it is missing.
+var statusCRResource = schema.GroupVersionResource{
+	Group:    "autoscaling.fptcloud.com",
+	Version:  "v1",
+	Resource: "clusterautoscalerstatuses",
+}
+
+// StatusCRName is the name of the published ClusterAutoscalerStatus object.
+const StatusCRName = "cluster-autoscaler-status"
+
+var (
+	crMutex     sync.Mutex
+	crClient    dynamic.Interface
+	crNamespace string
+)
+
+// EnableCRPublishing makes every Update also publish a ClusterAutoscalerStatus
+// custom resource in the given namespace, with structured conditions per worker
+// pool, so other controllers can react to autoscaler state without parsing the
+// free-text status configmap.
+func EnableCRPublishing(client dynamic.Interface, namespace string) {
+	crMutex.Lock()
+	defer crMutex.Unlock()
+	crClient = client
+	crNamespace = namespace
+}
+
+// poolConditions builds the ScaleUp, ScaleDown and Health conditions for one
+// worker pool from the report.
+func poolConditions(report Report) []interface{} {
+	scaleUpStatus := "NoActivity"
+	scaleUpMessage := fmt.Sprintf("lastScaleUp=%s", report.LastScaleUpTime.Format(time.RFC3339))
+	if len(report.PendingPods) > 0 {
+		scaleUpStatus = "Needed"
+		scaleUpMessage = fmt.Sprintf("pendingPods=%d", len(report.PendingPods))
+	}
+
+	scaleDownStatus := "NoCandidates"
+	scaleDownMessage := fmt.Sprintf("lastScaleDown=%s", report.LastScaleDownDeleteTime.Format(time.RFC3339))
+	switch {
+	case len(report.DeletionsInProgress) > 0:
+		scaleDownStatus = "InProgress"
+		scaleDownMessage = fmt.Sprintf("deletionsInProgress=%d", len(report.DeletionsInProgress))
+	case report.ScaleDownInCooldown:
+		scaleDownStatus = "InCooldown"
+	case len(report.UnneededNodes) > 0:
+		scaleDownStatus = "CandidatesPresent"
+		scaleDownMessage = fmt.Sprintf("candidates=%d blocked=%d", len(report.UnneededNodes), len(report.UnremovableNodes))
+	}
+
+	return []interface{}{
+		map[string]interface{}{
+			"type":    "ScaleUp",
+			"status":  scaleUpStatus,
+			"message": scaleUpMessage,
+		},
+		map[string]interface{}{
+			"type":    "ScaleDown",
+			"status":  scaleDownStatus,
+			"message": scaleDownMessage,
+		},
+		healthCondition(report),
+	}
+}
+
+// healthCondition reports InvalidConfiguration instead of Healthy while the
+// configuration is inconsistent and scaling is suspended.
+func healthCondition(report Report) map[string]interface{} {
+	if report.InvalidConfiguration != "" {
+		return map[string]interface{}{
+			"type":    "Health",
+			"status":  "InvalidConfiguration",
+			"message": report.InvalidConfiguration,
+		}
+	}
+	return map[string]interface{}{
+		"type":   "Health",
+		"status": "Healthy",
+	}
+}
+
+// buildStatusCR converts the report to an unstructured ClusterAutoscalerStatus.
+func buildStatusCR(report Report) *unstructured.Unstructured {
+	pools := make([]interface{}, 0, len(report.WorkerPoolSizes))
+	for pool, size := range report.WorkerPoolSizes {
+		pools = append(pools, map[string]interface{}{
+			"name":       pool,
+			"nodes":      int64(size),
+			"conditions": poolConditions(report),
+		})
+	}
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": statusCRResource.Group + "/" + statusCRResource.Version,
+			"kind":       "ClusterAutoscalerStatus",
+			"metadata": map[string]interface{}{
+				"name":      StatusCRName,
+				"namespace": crNamespace,
+			},
+			"status": map[string]interface{}{
+				"time":  report.Time.Format(time.RFC3339),
+				"pools": pools,
+			},
+		},
+	}
+}
+
+// publishStatusCR creates or updates the ClusterAutoscalerStatus object.
+// Failures are logged and do not affect the main loop.
+func publishStatusCR(report Report) {
+	crMutex.Lock()
+	client := crClient
+	namespace := crNamespace
+	crMutex.Unlock()
+	if client == nil {
+		return
+	}
+
+	statusCR := buildStatusCR(report)
+	resource := client.Resource(statusCRResource).Namespace(namespace)
+	existing, err := resource.Get(ctx.TODO(), StatusCRName, metav1.GetOptions{})
+	if err != nil {
+		if !kube_errors.IsNotFound(err) {
+			klog.Errorf("Failed to read ClusterAutoscalerStatus: %v", err)
+			return
+		}
+		if _, err := resource.Create(ctx.TODO(), statusCR, metav1.CreateOptions{}); err != nil {
+			klog.Errorf("Failed to create ClusterAutoscalerStatus: %v", err)
+		}
+		return
+	}
+	statusCR.SetResourceVersion(existing.GetResourceVersion())
+	if _, err := resource.Update(ctx.TODO(), statusCR, metav1.UpdateOptions{}); err != nil {
+		klog.Errorf("Failed to update ClusterAutoscalerStatus: %v", err)
+	}
+}
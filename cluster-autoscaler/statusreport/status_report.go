@@ -0,0 +1,177 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package statusreport
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	klog "k8s.io/klog/v2"
+)
+
+// UnneededNode describes a node that is currently unneeded and therefore a
+// scale down candidate.
+type UnneededNode struct {
+	Name  string    `json:"name"`
+	Since time.Time `json:"since"`
+}
+
+// UnremovableNode describes a node that cannot be scaled down and why.
+type UnremovableNode struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// Deletion describes an in-flight portal node deletion.
+type Deletion struct {
+	Node    string `json:"node"`
+	Elapsed string `json:"elapsed"`
+}
+
+// NodeGroupFit tells why one node group was not used to schedule a pod during
+// the last scale up evaluation.
+type NodeGroupFit struct {
+	NodeGroup string   `json:"nodeGroup"`
+	Outcome   string   `json:"outcome"`
+	Reasons   []string `json:"reasons"`
+}
+
+// PodFitAnalysis lists the node groups that were rejected or skipped for one
+// pod that remained unschedulable.
+type PodFitAnalysis struct {
+	Pod        string         `json:"pod"`
+	NodeGroups []NodeGroupFit `json:"nodeGroups"`
+}
+
+// FlavorRecommendation advises switching a worker pool to a smaller or larger
+// flavor based on its long-term utilization.
+type FlavorRecommendation struct {
+	Pool               string  `json:"pool"`
+	Direction          string  `json:"direction"`
+	AverageUtilization float64 `json:"averageUtilization"`
+	RecommendedFlavor  string  `json:"recommendedFlavor,omitempty"`
+}
+
+// CostReportEntry summarizes the autoscaling activity and its estimated cost
+// for one worker pool over the last reporting window.
+type CostReportEntry struct {
+	Pool             string  `json:"pool"`
+	NodesAdded       int     `json:"nodesAdded"`
+	NodesRemoved     int     `json:"nodesRemoved"`
+	NodeHours        float64 `json:"nodeHours"`
+	EstimatedCost    float64 `json:"estimatedCost"`
+	EstimatedSavings float64 `json:"estimatedSavings"`
+}
+
+// Report is a machine readable snapshot of the autoscaler state, refreshed at
+// the end of every main loop iteration and served from the status endpoint.
+type Report struct {
+	Time                    time.Time              `json:"time"`
+	WorkerPoolSizes         map[string]int         `json:"workerPoolSizes"`
+	PendingPods             []string               `json:"pendingPods"`
+	PodFitAnalysis          []PodFitAnalysis       `json:"podFitAnalysis,omitempty"`
+	UnneededNodes           []UnneededNode         `json:"unneededNodes"`
+	UnremovableNodes        []UnremovableNode      `json:"unremovableNodes"`
+	DeletionsInProgress     []Deletion             `json:"deletionsInProgress"`
+	ScaleDownInCooldown     bool                   `json:"scaleDownInCooldown"`
+	InvalidConfiguration    string                 `json:"invalidConfiguration,omitempty"`
+	FlavorRecommendations   []FlavorRecommendation `json:"flavorRecommendations,omitempty"`
+	CostReport              []CostReportEntry      `json:"costReport,omitempty"`
+	LastScaleUpTime         time.Time              `json:"lastScaleUpTime"`
+	LastScaleDownDeleteTime time.Time              `json:"lastScaleDownDeleteTime"`
+	LastScaleDownFailTime   time.Time              `json:"lastScaleDownFailTime"`
+}
+
+var (
+	mutex                 sync.Mutex
+	currentReport         Report
+	currentPodFits        []PodFitAnalysis
+	invalidConfiguration  string
+	flavorRecommendations []FlavorRecommendation
+	costReport            []CostReportEntry
+)
+
+// Update replaces the report served from the status endpoint and, when
+// enabled, publishes the ClusterAutoscalerStatus custom resource.
+func Update(report Report) {
+	mutex.Lock()
+	report.PodFitAnalysis = currentPodFits
+	report.InvalidConfiguration = invalidConfiguration
+	report.FlavorRecommendations = flavorRecommendations
+	report.CostReport = costReport
+	currentReport = report
+	mutex.Unlock()
+	broadcastReport(report)
+	publishStatusCR(report)
+}
+
+// UpdateInvalidConfiguration records a configuration inconsistency that keeps
+// the autoscaler from acting, or clears it when passed an empty string.
+func UpdateInvalidConfiguration(message string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	invalidConfiguration = message
+	currentReport.InvalidConfiguration = message
+}
+
+// UpdateFlavorRecommendations replaces the flavor right-sizing recommendations
+// attached to the report. They persist across loops until the next analysis
+// window produces a new set.
+func UpdateFlavorRecommendations(recommendations []FlavorRecommendation) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	flavorRecommendations = recommendations
+	currentReport.FlavorRecommendations = recommendations
+}
+
+// UpdateCostReport replaces the cost and savings summary attached to the
+// report. It persists across loops until the next reporting window closes.
+func UpdateCostReport(entries []CostReportEntry) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	costReport = entries
+	currentReport.CostReport = entries
+}
+
+// UpdatePodFitAnalysis replaces the per-pod fit analysis attached to the
+// report, produced after every scale up evaluation.
+func UpdatePodFitAnalysis(fits []PodFitAnalysis) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	currentPodFits = fits
+	currentReport.PodFitAnalysis = fits
+}
+
+// Current returns the latest report.
+func Current() Report {
+	mutex.Lock()
+	defer mutex.Unlock()
+	return currentReport
+}
+
+// ServeHTTP serves the latest report as JSON.
+func ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	mutex.Lock()
+	report := currentReport
+	mutex.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		klog.Errorf("Failed to write status report: %v", err)
+	}
+}
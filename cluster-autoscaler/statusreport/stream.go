@@ -0,0 +1,115 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package statusreport
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	klog "k8s.io/klog/v2"
+)
+
+// streamSubscriberBuffer is how many reports a slow subscriber may fall
+// behind before reports are dropped for it. The stream is best effort; a
+// dropped report is superseded by the next loop anyway.
+const streamSubscriberBuffer = 4
+
+var (
+	streamMutex       sync.Mutex
+	streamSubscribers = map[chan Report]bool{}
+)
+
+// subscribeStream registers a new subscriber channel that receives the report
+// of every loop iteration until unsubscribeStream is called.
+func subscribeStream() chan Report {
+	ch := make(chan Report, streamSubscriberBuffer)
+	streamMutex.Lock()
+	defer streamMutex.Unlock()
+	streamSubscribers[ch] = true
+	return ch
+}
+
+func unsubscribeStream(ch chan Report) {
+	streamMutex.Lock()
+	defer streamMutex.Unlock()
+	delete(streamSubscribers, ch)
+}
+
+// broadcastReport fans the report out to all stream subscribers. Sends never
+// block the main loop; a subscriber with a full buffer misses this report.
+func broadcastReport(report Report) {
+	streamMutex.Lock()
+	defer streamMutex.Unlock()
+	for ch := range streamSubscribers {
+		select {
+		case ch <- report:
+		default:
+		}
+	}
+}
+
+// writeReportEvent writes one report as a server-sent event.
+func writeReportEvent(w http.ResponseWriter, report Report) error {
+	body, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: report\ndata: %s\n\n", body)
+	return err
+}
+
+// ServeStreamHTTP streams the per-loop report as server-sent events, so the
+// autoscaler's decisions can be watched live during an incident instead of
+// polling the status endpoint. The connection stays open until the client
+// disconnects.
+func ServeStreamHTTP(w http.ResponseWriter, req *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming is not supported by this connection", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	ch := subscribeStream()
+	defer unsubscribeStream(ch)
+	klog.V(2).Infof("Status stream subscriber connected from %s", req.RemoteAddr)
+
+	// The latest report is sent immediately, so the watcher doesn't have to
+	// wait a full loop iteration for the first event.
+	if err := writeReportEvent(w, Current()); err != nil {
+		return
+	}
+	flusher.Flush()
+
+	for {
+		select {
+		case report := <-ch:
+			if err := writeReportEvent(w, report); err != nil {
+				klog.V(2).Infof("Status stream subscriber %s disconnected: %v", req.RemoteAddr, err)
+				return
+			}
+			flusher.Flush()
+		case <-req.Context().Done():
+			klog.V(2).Infof("Status stream subscriber %s disconnected", req.RemoteAddr)
+			return
+		}
+	}
+}
@@ -20,6 +20,8 @@ import (
 	"context"
 	"fmt"
 	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	apiv1 "k8s.io/api/core/v1"
@@ -27,16 +29,54 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	kube_client "k8s.io/client-go/kubernetes"
 	kube_record "k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/flowcontrol"
 
 	klog "k8s.io/klog/v2"
 )
 
-const (
-	// ToBeDeletedTaint is a taint used to make the node unschedulable.
-	ToBeDeletedTaint = "ToBeDeletedByClusterAutoscaler"
-	// DeletionCandidateTaint is a taint used to mark unneeded node as preferably unschedulable.
-	DeletionCandidateTaint = "DeletionCandidateOfClusterAutoscaler"
-)
+// ToBeDeletedTaint is a taint used to make the node unschedulable.
+// The key and effect can be overridden with SetToBeDeletedTaint.
+var ToBeDeletedTaint = "ToBeDeletedByClusterAutoscaler"
+
+// toBeDeletedTaintEffect is the effect of the taint put on nodes being deleted.
+var toBeDeletedTaintEffect = apiv1.TaintEffectNoSchedule
+
+// SetToBeDeletedTaint overrides the key and effect of the taint put on nodes
+// being deleted, for clusters with admission policies or tooling keyed on a
+// custom taint. An empty key or effect keeps the respective default.
+func SetToBeDeletedTaint(key string, effect apiv1.TaintEffect) {
+	if key != "" {
+		ToBeDeletedTaint = key
+	}
+	if effect != "" {
+		toBeDeletedTaintEffect = effect
+	}
+}
+
+// DeletionCandidateTaint is a taint used to mark unneeded node as preferably unschedulable.
+// The key can be overridden with SetDeletionCandidateTaint.
+var DeletionCandidateTaint = "DeletionCandidateOfClusterAutoscaler"
+
+// SetDeletionCandidateTaint overrides the key of the soft taint put on unneeded nodes.
+func SetDeletionCandidateTaint(key string) {
+	DeletionCandidateTaint = key
+}
+
+// ScaleDownCandidateLabel marks nodes that currently carry the deletion
+// candidate soft taint. Descheduler policies select nodes by label, not by
+// taint, so publishing the label lets them drain candidate nodes
+// cooperatively before the autoscaler deletes them.
+const ScaleDownCandidateLabel = "cluster-autoscaler.kubernetes.io/scale-down-candidate"
+
+// publishCandidateLabel tells if ScaleDownCandidateLabel is kept in sync with
+// the deletion candidate soft taint.
+var publishCandidateLabel = false
+
+// SetPublishScaleDownCandidateLabel enables or disables publishing
+// ScaleDownCandidateLabel together with the deletion candidate soft taint.
+func SetPublishScaleDownCandidateLabel(enabled bool) {
+	publishCandidateLabel = enabled
+}
 
 // Mutable only in unit tests
 var (
@@ -58,7 +98,7 @@ func getKeyShortName(key string) string {
 
 // MarkToBeDeleted sets a taint that makes the node unschedulable.
 func MarkToBeDeleted(node *apiv1.Node, client kube_client.Interface, cordonNode bool) error {
-	return addTaint(node, client, ToBeDeletedTaint, apiv1.TaintEffectNoSchedule, cordonNode)
+	return addTaint(node, client, ToBeDeletedTaint, toBeDeletedTaintEffect, cordonNode)
 }
 
 // MarkDeletionCandidate sets a soft taint that makes the node preferably unschedulable.
@@ -117,6 +157,12 @@ func addTaintToSpec(node *apiv1.Node, taintKey string, effect apiv1.TaintEffect,
 		Value:  fmt.Sprint(time.Now().Unix()),
 		Effect: effect,
 	})
+	if publishCandidateLabel && taintKey == DeletionCandidateTaint {
+		if node.Labels == nil {
+			node.Labels = map[string]string{}
+		}
+		node.Labels[ScaleDownCandidateLabel] = "true"
+	}
 	if cordonNode {
 		klog.V(1).Infof("Marking node %v to be cordoned by Cluster Autoscaler", node.Name)
 		node.Spec.Unschedulable = true
@@ -209,6 +255,9 @@ func cleanTaint(node *apiv1.Node, client kube_client.Interface, taintKey string,
 		}
 
 		freshNode.Spec.Taints = newTaints
+		if taintKey == DeletionCandidateTaint {
+			delete(freshNode.Labels, ScaleDownCandidateLabel)
+		}
 		if cordonNode {
 			klog.V(1).Infof("Marking node %v to be uncordoned by Cluster Autoscaler", freshNode.Name)
 			freshNode.Spec.Unschedulable = false
@@ -230,29 +279,71 @@ func cleanTaint(node *apiv1.Node, client kube_client.Interface, taintKey string,
 	}
 }
 
-// CleanAllToBeDeleted cleans ToBeDeleted taints from given nodes.
-func CleanAllToBeDeleted(nodes []*apiv1.Node, client kube_client.Interface, recorder kube_record.EventRecorder, cordonNode bool) {
-	cleanAllTaints(nodes, client, recorder, ToBeDeletedTaint, cordonNode)
+// Parallelism and client-side rate limit of the bulk taint cleanup. The
+// cleanup runs on startup, when a large cluster recovering from a crash can
+// have hundreds of tainted nodes, so the patches are bounded rather than
+// issued sequentially or all at once.
+const (
+	bulkCleanTaintWorkers = 10
+	bulkCleanTaintQPS     = 20
+	bulkCleanTaintBurst   = 20
+)
+
+// CleanAllToBeDeleted cleans ToBeDeleted taints from given nodes and returns
+// how many nodes were cleaned.
+func CleanAllToBeDeleted(nodes []*apiv1.Node, client kube_client.Interface, recorder kube_record.EventRecorder, cordonNode bool) int {
+	return cleanAllTaints(nodes, client, recorder, ToBeDeletedTaint, cordonNode)
 }
 
-// CleanAllDeletionCandidates cleans DeletionCandidate taints from given nodes.
-func CleanAllDeletionCandidates(nodes []*apiv1.Node, client kube_client.Interface, recorder kube_record.EventRecorder) {
-	cleanAllTaints(nodes, client, recorder, DeletionCandidateTaint, false)
+// CleanAllDeletionCandidates cleans DeletionCandidate taints from given nodes
+// and returns how many nodes were cleaned.
+func CleanAllDeletionCandidates(nodes []*apiv1.Node, client kube_client.Interface, recorder kube_record.EventRecorder) int {
+	return cleanAllTaints(nodes, client, recorder, DeletionCandidateTaint, false)
 }
 
 func cleanAllTaints(nodes []*apiv1.Node, client kube_client.Interface, recorder kube_record.EventRecorder,
-	taintKey string, cordonNode bool) {
+	taintKey string, cordonNode bool) int {
+	var taintedNodes []*apiv1.Node
 	for _, node := range nodes {
-		if !hasTaint(node, taintKey) {
-			continue
-		}
-		cleaned, err := cleanTaint(node, client, taintKey, cordonNode)
-		if err != nil {
-			recorder.Eventf(node, apiv1.EventTypeWarning, "ClusterAutoscalerCleanup",
-				"failed to clean %v on node %v: %v", getKeyShortName(taintKey), node.Name, err)
-		} else if cleaned {
-			recorder.Eventf(node, apiv1.EventTypeNormal, "ClusterAutoscalerCleanup",
-				"removed %v taint from node %v", getKeyShortName(taintKey), node.Name)
+		if hasTaint(node, taintKey) {
+			taintedNodes = append(taintedNodes, node)
 		}
 	}
+	if len(taintedNodes) == 0 {
+		return 0
+	}
+
+	limiter := flowcontrol.NewTokenBucketRateLimiter(bulkCleanTaintQPS, bulkCleanTaintBurst)
+	workers := bulkCleanTaintWorkers
+	if workers > len(taintedNodes) {
+		workers = len(taintedNodes)
+	}
+	nodeChan := make(chan *apiv1.Node, len(taintedNodes))
+	for _, node := range taintedNodes {
+		nodeChan <- node
+	}
+	close(nodeChan)
+
+	var cleanedCount int64
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for node := range nodeChan {
+				limiter.Accept()
+				cleaned, err := cleanTaint(node, client, taintKey, cordonNode)
+				if err != nil {
+					recorder.Eventf(node, apiv1.EventTypeWarning, "ClusterAutoscalerCleanup",
+						"failed to clean %v on node %v: %v", getKeyShortName(taintKey), node.Name, err)
+				} else if cleaned {
+					atomic.AddInt64(&cleanedCount, 1)
+					recorder.Eventf(node, apiv1.EventTypeNormal, "ClusterAutoscalerCleanup",
+						"removed %v taint from node %v", getKeyShortName(taintKey), node.Name)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return int(cleanedCount)
 }
@@ -62,6 +62,46 @@ func TestSoftMarkNodes(t *testing.T) {
 	assert.True(t, HasDeletionCandidateTaint(updatedNode))
 }
 
+func TestSoftMarkNodesCandidateLabel(t *testing.T) {
+	defer setConflictRetryInterval(setConflictRetryInterval(time.Millisecond))
+	SetPublishScaleDownCandidateLabel(true)
+	defer SetPublishScaleDownCandidateLabel(false)
+
+	node := BuildTestNode("node", 1000, 1000)
+	fakeClient := buildFakeClientWithConflicts(t, node)
+	err := MarkDeletionCandidate(node, fakeClient)
+	assert.NoError(t, err)
+
+	updatedNode := getNode(t, fakeClient, "node")
+	assert.True(t, HasDeletionCandidateTaint(updatedNode))
+	assert.Equal(t, "true", updatedNode.Labels[ScaleDownCandidateLabel])
+
+	cleaned, err := CleanDeletionCandidate(updatedNode, fakeClient)
+	assert.True(t, cleaned)
+	assert.NoError(t, err)
+
+	updatedNode = getNode(t, fakeClient, "node")
+	assert.False(t, HasDeletionCandidateTaint(updatedNode))
+	assert.NotContains(t, updatedNode.Labels, ScaleDownCandidateLabel)
+}
+
+func TestMarkNodesCustomTaint(t *testing.T) {
+	defer setConflictRetryInterval(setConflictRetryInterval(time.Millisecond))
+	defaultKey := ToBeDeletedTaint
+	SetToBeDeletedTaint("company.com/to-be-deleted", apiv1.TaintEffectNoExecute)
+	defer SetToBeDeletedTaint(defaultKey, apiv1.TaintEffectNoSchedule)
+
+	node := BuildTestNode("node", 1000, 1000)
+	fakeClient := buildFakeClientWithConflicts(t, node)
+	err := MarkToBeDeleted(node, fakeClient, false)
+	assert.NoError(t, err)
+
+	updatedNode := getNode(t, fakeClient, "node")
+	assert.True(t, HasToBeDeletedTaint(updatedNode))
+	assert.Equal(t, "company.com/to-be-deleted", updatedNode.Spec.Taints[0].Key)
+	assert.Equal(t, apiv1.TaintEffectNoExecute, updatedNode.Spec.Taints[0].Effect)
+}
+
 func TestCheckNodes(t *testing.T) {
 	defer setConflictRetryInterval(setConflictRetryInterval(time.Millisecond))
 	node := BuildTestNode("node", 1000, 1000)
@@ -204,8 +244,9 @@ func TestCleanAllToBeDeleted(t *testing.T) {
 
 	assert.Equal(t, 1, len(getNode(t, fakeClient, "n2").Spec.Taints))
 
-	CleanAllToBeDeleted([]*apiv1.Node{n1, n2}, fakeClient, fakeRecorder, false)
+	cleaned := CleanAllToBeDeleted([]*apiv1.Node{n1, n2}, fakeClient, fakeRecorder, false)
 
+	assert.Equal(t, 1, cleaned)
 	assert.Equal(t, 0, len(getNode(t, fakeClient, "n1").Spec.Taints))
 	assert.Equal(t, 0, len(getNode(t, fakeClient, "n2").Spec.Taints))
 }
@@ -0,0 +1,123 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package events
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	kube_record "k8s.io/client-go/tools/record"
+)
+
+// DefaultAggregationWindow is how long an emitted event suppresses identical
+// ones for the same object and reason.
+const DefaultAggregationWindow = 5 * time.Minute
+
+// aggregationKey identifies a stream of similar events: one object and one
+// reason.
+type aggregationKey struct {
+	object string
+	reason string
+}
+
+// Aggregator dedupes events per (object, reason) within a time window.
+// During an incident storm the eventing processors can produce identical
+// events for hundreds of pods on every loop; the aggregator makes sure each
+// stream results in at most one API write per window, carrying a count of the
+// suppressed occurrences on the next emitted event.
+type Aggregator struct {
+	window     time.Duration
+	mutex      sync.Mutex
+	lastEmit   map[aggregationKey]time.Time
+	suppressed map[aggregationKey]int
+}
+
+// NewAggregator returns an Aggregator that suppresses repeated events for the
+// given window.
+func NewAggregator(window time.Duration) *Aggregator {
+	return &Aggregator{
+		window:     window,
+		lastEmit:   make(map[aggregationKey]time.Time),
+		suppressed: make(map[aggregationKey]int),
+	}
+}
+
+// Event emits the event through recorder unless an event with the same object
+// and reason was already emitted within the window. Suppressed occurrences are
+// counted and reported with the next event that does get emitted.
+func (a *Aggregator) Event(recorder kube_record.EventRecorder, object runtime.Object, eventtype, reason, message string) {
+	key := aggregationKey{object: objectKey(object), reason: reason}
+	now := time.Now()
+
+	a.mutex.Lock()
+	if last, found := a.lastEmit[key]; found && now.Sub(last) < a.window {
+		a.suppressed[key]++
+		a.mutex.Unlock()
+		return
+	}
+	suppressed := a.suppressed[key]
+	delete(a.suppressed, key)
+	a.lastEmit[key] = now
+	a.prune(now)
+	a.mutex.Unlock()
+
+	if suppressed > 0 {
+		message = fmt.Sprintf("%s suppressedSimilarEvents=%v", message, suppressed)
+	}
+	recorder.Event(object, eventtype, reason, message)
+}
+
+// Eventf is Event with Sprintf formatting of the message.
+func (a *Aggregator) Eventf(recorder kube_record.EventRecorder, object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	a.Event(recorder, object, eventtype, reason, fmt.Sprintf(messageFmt, args...))
+}
+
+// prune drops dedup state for streams that have been quiet for two windows, so
+// the maps do not grow with every pod the cluster has ever seen. Callers must
+// hold the mutex.
+func (a *Aggregator) prune(now time.Time) {
+	for key, last := range a.lastEmit {
+		if now.Sub(last) > 2*a.window {
+			delete(a.lastEmit, key)
+			delete(a.suppressed, key)
+		}
+	}
+}
+
+// objectKey returns a stable identifier for the event target.
+func objectKey(object runtime.Object) string {
+	accessor, err := meta.Accessor(object)
+	if err != nil {
+		return fmt.Sprintf("%v", object)
+	}
+	return accessor.GetNamespace() + "/" + accessor.GetName()
+}
+
+var defaultAggregator = NewAggregator(DefaultAggregationWindow)
+
+// Record emits the event through the shared default aggregator.
+func Record(recorder kube_record.EventRecorder, object runtime.Object, eventtype, reason, message string) {
+	defaultAggregator.Event(recorder, object, eventtype, reason, message)
+}
+
+// Recordf is Record with Sprintf formatting of the message.
+func Recordf(recorder kube_record.EventRecorder, object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	defaultAggregator.Eventf(recorder, object, eventtype, reason, messageFmt, args...)
+}
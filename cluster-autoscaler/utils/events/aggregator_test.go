@@ -0,0 +1,102 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package events
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kube_record "k8s.io/client-go/tools/record"
+)
+
+func testPod(name string) *apiv1.Pod {
+	return &apiv1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}}
+}
+
+func receivedEvents(recorder *kube_record.FakeRecorder) []string {
+	var events []string
+	for {
+		select {
+		case event := <-recorder.Events:
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+func TestAggregatorDedupesWithinWindow(t *testing.T) {
+	aggregator := NewAggregator(time.Minute)
+	recorder := kube_record.NewFakeRecorder(10)
+	pod := testPod("p1")
+
+	for i := 0; i < 3; i++ {
+		aggregator.Event(recorder, pod, apiv1.EventTypeNormal, "ScaleDown", "node removed")
+	}
+	// A different reason and a different object are separate streams.
+	aggregator.Event(recorder, pod, apiv1.EventTypeNormal, "ScaleUp", "node added")
+	aggregator.Event(recorder, testPod("p2"), apiv1.EventTypeNormal, "ScaleDown", "node removed")
+
+	events := receivedEvents(recorder)
+	assert.Equal(t, 3, len(events))
+}
+
+func TestAggregatorReemitsWithSuppressedCount(t *testing.T) {
+	aggregator := NewAggregator(time.Minute)
+	recorder := kube_record.NewFakeRecorder(10)
+	pod := testPod("p1")
+
+	aggregator.Event(recorder, pod, apiv1.EventTypeNormal, "ScaleDown", "node removed")
+	aggregator.Event(recorder, pod, apiv1.EventTypeNormal, "ScaleDown", "node removed")
+	aggregator.Event(recorder, pod, apiv1.EventTypeNormal, "ScaleDown", "node removed")
+
+	// Age the stream past the window so the next occurrence is emitted again,
+	// carrying the count of the two suppressed ones.
+	key := aggregationKey{object: "default/p1", reason: "ScaleDown"}
+	aggregator.lastEmit[key] = time.Now().Add(-2 * time.Minute)
+	aggregator.Event(recorder, pod, apiv1.EventTypeNormal, "ScaleDown", "node removed")
+
+	events := receivedEvents(recorder)
+	if assert.Equal(t, 2, len(events)) {
+		assert.NotContains(t, events[0], "suppressedSimilarEvents")
+		assert.Contains(t, events[1], "suppressedSimilarEvents=2")
+	}
+}
+
+func TestAggregatorPrunesQuietStreams(t *testing.T) {
+	aggregator := NewAggregator(time.Minute)
+	recorder := kube_record.NewFakeRecorder(10)
+
+	aggregator.Event(recorder, testPod("p1"), apiv1.EventTypeNormal, "ScaleDown", "node removed")
+	aggregator.Event(recorder, testPod("p1"), apiv1.EventTypeNormal, "ScaleDown", "node removed")
+	assert.Equal(t, 1, len(aggregator.lastEmit))
+	assert.Equal(t, 1, len(aggregator.suppressed))
+
+	// Quiet for more than two windows: the next emission for any stream drops
+	// the stale dedup state, including its suppressed count.
+	key := aggregationKey{object: "default/p1", reason: "ScaleDown"}
+	aggregator.lastEmit[key] = time.Now().Add(-3 * time.Minute)
+	aggregator.Event(recorder, testPod("p2"), apiv1.EventTypeNormal, "ScaleDown", "node removed")
+
+	assert.Equal(t, 1, len(aggregator.lastEmit))
+	assert.Equal(t, 0, len(aggregator.suppressed))
+	_, found := aggregator.lastEmit[key]
+	assert.False(t, found)
+}
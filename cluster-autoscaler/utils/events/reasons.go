@@ -0,0 +1,60 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package events defines the stable set of event reasons emitted by the
+// autoscaler. All events use one of these reasons, with key=value detail in
+// the message, so they can be filtered and alerted on reliably.
+package events
+
+const (
+	// ScaleUpTriggered is emitted on a pending pod whose resource requests
+	// triggered a scale up.
+	ScaleUpTriggered = "ScaleUpTriggered"
+	// ScaleUpNotTriggered is emitted on a pending pod that did not trigger a
+	// scale up, for example because it is unschedulable for non-resource reasons.
+	ScaleUpNotTriggered = "ScaleUpNotTriggered"
+	// ScaleDownEviction is emitted on a pod being evicted to empty a node that
+	// is scaling down.
+	ScaleDownEviction = "ScaleDownEviction"
+	// ScaleDownEvictionFailed is emitted on a pod that could not be evicted
+	// within the allowed timeout during a scale down.
+	ScaleDownEvictionFailed = "ScaleDownEvictionFailed"
+	// ScaleDownBlockedPDB is emitted on a pod whose eviction is being rejected
+	// because it would violate a PodDisruptionBudget.
+	ScaleDownBlockedPDB = "ScaleDownBlockedPDB"
+	// ForcedDrain is emitted on a node whose remaining pods are force deleted
+	// after the drain deadline expired.
+	ForcedDrain = "ForcedDrain"
+	// PortalUnavailable is emitted when a scaling request to the FPT portal
+	// fails or the portal reports an error status.
+	PortalUnavailable = "PortalUnavailable"
+	// ClusterUnhealthy is emitted when the cluster fails the autoscaler health
+	// checks and scaling is suspended.
+	ClusterUnhealthy = "ClusterUnhealthy"
+	// RequiredConfigUnavailable is emitted when the autoscaling configmap or
+	// fke secret is momentarily unreadable and the iteration is skipped.
+	RequiredConfigUnavailable = "RequiredConfigUnavailable"
+	// InvalidConfiguration is emitted when the autoscaling configmap holds an
+	// inconsistent configuration, e.g. min_node_group_size above
+	// max_node_group_size, and scaling is suspended until it is fixed.
+	InvalidConfiguration = "InvalidConfiguration"
+	// FlavorRecommendation is emitted when the long-term utilization of a
+	// worker pool suggests switching to a smaller or larger flavor.
+	FlavorRecommendation = "FlavorRecommendation"
+	// CostReport is emitted periodically with the node-hours and estimated
+	// money the autoscaler spent and saved per worker pool.
+	CostReport = "CostReport"
+)
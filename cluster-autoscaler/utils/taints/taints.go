@@ -42,6 +42,30 @@ const (
 // TaintKeySet is a set of taint key
 type TaintKeySet map[string]bool
 
+// extraIgnoreTaintPrefixes are additional taint key prefixes filtered like
+// IgnoreTaintPrefix, e.g. startup taints a CNI removes once the node is ready.
+var extraIgnoreTaintPrefixes []string
+
+// SetExtraIgnoreTaintPrefixes replaces the additional taint key prefixes that
+// are filtered like IgnoreTaintPrefix.
+func SetExtraIgnoreTaintPrefixes(prefixes []string) {
+	extraIgnoreTaintPrefixes = prefixes
+}
+
+// hasIgnorePrefix tells if the taint key starts with IgnoreTaintPrefix or one
+// of the configured extra prefixes.
+func hasIgnorePrefix(taintKey string) bool {
+	if strings.HasPrefix(taintKey, IgnoreTaintPrefix) {
+		return true
+	}
+	for _, prefix := range extraIgnoreTaintPrefixes {
+		if strings.HasPrefix(taintKey, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
 var (
 	// NodeConditionTaints lists taint keys used as node conditions
 	NodeConditionTaints = TaintKeySet{
@@ -89,7 +113,7 @@ func SanitizeTaints(taints []apiv1.Taint, ignoredTaints TaintKeySet) []apiv1.Tai
 			continue
 		}
 
-		if strings.HasPrefix(taint.Key, IgnoreTaintPrefix) {
+		if hasIgnorePrefix(taint.Key) {
 			klog.V(4).Infof("Removing taint %s based on prefix, when creation template from node", taint.Key)
 			continue
 		}
@@ -113,7 +137,7 @@ func FilterOutNodesWithIgnoredTaints(ignoredTaints TaintKeySet, allNodes, readyN
 		ready := true
 		for _, t := range node.Spec.Taints {
 			_, hasIgnoredTaint := ignoredTaints[t.Key]
-			if hasIgnoredTaint || strings.HasPrefix(t.Key, IgnoreTaintPrefix) {
+			if hasIgnoredTaint || hasIgnorePrefix(t.Key) {
 				ready = false
 				nodesWithIgnoredTaints[node.Name] = kubernetes.GetUnreadyNodeCopy(node)
 				klog.V(3).Infof("Overriding status of node %v, which seems to have ignored taint %q", node.Name, t.Key)
@@ -248,3 +248,25 @@ func TestSanitizeTaints(t *testing.T) {
 	require.Equal(t, len(newTaints), 1)
 	assert.Equal(t, newTaints[0].Key, "test-taint")
 }
+
+func TestSanitizeTaintsWithExtraIgnorePrefixes(t *testing.T) {
+	defer SetExtraIgnoreTaintPrefixes(nil)
+	SetExtraIgnoreTaintPrefixes([]string{"node.cilium.io/"})
+
+	taints := []apiv1.Taint{
+		{
+			Key:    "node.cilium.io/agent-not-ready",
+			Value:  "true",
+			Effect: apiv1.TaintEffectNoSchedule,
+		},
+		{
+			Key:    "test-taint",
+			Value:  "test",
+			Effect: apiv1.TaintEffectNoSchedule,
+		},
+	}
+
+	newTaints := SanitizeTaints(taints, map[string]bool{})
+	require.Equal(t, len(newTaints), 1)
+	assert.Equal(t, newTaints[0].Key, "test-taint")
+}
@@ -113,35 +113,117 @@ func GetVPCId(kubeclient kube_client.Interface) string {
 	return vpcID
 }
 
+// NodeNameSet returns the names of the given nodes as a set, so membership
+// checks and list diffs stay linear on large clusters.
+func NodeNameSet(nodes []*apiv1.Node) map[string]bool {
+	names := make(map[string]bool, len(nodes))
+	for _, node := range nodes {
+		names[node.Name] = true
+	}
+	return names
+}
+
 // FilterOutNodes filters out nodesToFilterOut from nodes
 func FilterOutNodes(nodes []*apiv1.Node, nodesToFilterOut []*apiv1.Node) []*apiv1.Node {
+	filterOut := NodeNameSet(nodesToFilterOut)
 	var filtered []*apiv1.Node
 	for _, node := range nodes {
-		found := false
-		for _, nodeToFilter := range nodesToFilterOut {
-			if nodeToFilter.Name == node.Name {
-				found = true
-			}
-		}
-		if !found {
+		if !filterOut[node.Name] {
 			filtered = append(filtered, node)
 		}
 	}
-
 	return filtered
 }
 
+// Admission-injected fields dropped before comparing pods for equivalence.
+// Service-mesh and policy webhooks add tolerations, sidecar containers and
+// labels with per-pod values, which would otherwise put every pod in its own
+// equivalence group.
+var (
+	equivalenceIgnoredTolerationKeys = map[string]bool{}
+	equivalenceIgnoredContainerNames = map[string]bool{}
+	equivalenceIgnoredLabelKeys      = map[string]bool{}
+)
+
+// SetEquivalenceIgnoredFields configures which admission-injected fields are
+// dropped before comparing pods for equivalence: tolerations by key,
+// containers (including init containers) by name and labels by key.
+func SetEquivalenceIgnoredFields(tolerationKeys, containerNames, labelKeys []string) {
+	equivalenceIgnoredTolerationKeys = stringSet(tolerationKeys)
+	equivalenceIgnoredContainerNames = stringSet(containerNames)
+	equivalenceIgnoredLabelKeys = stringSet(labelKeys)
+}
+
+func stringSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[value] = true
+	}
+	return set
+}
+
 // PodSpecSemanticallyEqual returns true if two pod specs are similar after dropping
 // the fields we don't care about
 // Due to the generated suffixes, a strict DeepEquals check will fail and generate
 // an equivalence group per pod which is undesirable.
 // Projected volumes do not impact scheduling so we should ignore them
 func PodSpecSemanticallyEqual(p1 apiv1.PodSpec, p2 apiv1.PodSpec) bool {
-	p1Spec := sanitizeProjectedVolumesAndMounts(p1)
-	p2Spec := sanitizeProjectedVolumesAndMounts(p2)
+	p1Spec := sanitizeInjectedFields(sanitizeProjectedVolumesAndMounts(p1))
+	p2Spec := sanitizeInjectedFields(sanitizeProjectedVolumesAndMounts(p2))
 	return apiequality.Semantic.DeepEqual(p1Spec, p2Spec)
 }
 
+// PodLabelsSemanticallyEqual returns true if two pod label sets are equal
+// after dropping the configured admission-injected label keys.
+func PodLabelsSemanticallyEqual(l1 map[string]string, l2 map[string]string) bool {
+	return apiequality.Semantic.DeepEqual(sanitizeLabels(l1), sanitizeLabels(l2))
+}
+
+func sanitizeLabels(podLabels map[string]string) map[string]string {
+	if len(equivalenceIgnoredLabelKeys) == 0 {
+		return podLabels
+	}
+	labels := make(map[string]string, len(podLabels))
+	for key, value := range podLabels {
+		if !equivalenceIgnoredLabelKeys[key] {
+			labels[key] = value
+		}
+	}
+	if len(labels) == 0 {
+		return nil
+	}
+	return labels
+}
+
+// sanitizeInjectedFields returns a pod spec with the configured
+// admission-injected tolerations and containers removed
+func sanitizeInjectedFields(podSpec apiv1.PodSpec) apiv1.PodSpec {
+	if len(equivalenceIgnoredTolerationKeys) > 0 && len(podSpec.Tolerations) > 0 {
+		var tolerations []apiv1.Toleration
+		for _, toleration := range podSpec.Tolerations {
+			if !equivalenceIgnoredTolerationKeys[toleration.Key] {
+				tolerations = append(tolerations, toleration)
+			}
+		}
+		podSpec.Tolerations = tolerations
+	}
+	if len(equivalenceIgnoredContainerNames) > 0 {
+		podSpec.Containers = dropIgnoredContainers(podSpec.Containers)
+		podSpec.InitContainers = dropIgnoredContainers(podSpec.InitContainers)
+	}
+	return podSpec
+}
+
+func dropIgnoredContainers(podContainers []apiv1.Container) []apiv1.Container {
+	var containers []apiv1.Container
+	for _, container := range podContainers {
+		if !equivalenceIgnoredContainerNames[container.Name] {
+			containers = append(containers, container)
+		}
+	}
+	return containers
+}
+
 // sanitizeProjectedVolumesAndMounts returns a pod spec with projected volumes
 // and their mounts removed
 func sanitizeProjectedVolumesAndMounts(podSpec apiv1.PodSpec) apiv1.PodSpec {
@@ -83,6 +83,44 @@ func TestPodSpecSemanticallyEqual(t *testing.T) {
 	}
 }
 
+func TestPodSpecSemanticallyEqualIgnoredInjectedFields(t *testing.T) {
+	SetEquivalenceIgnoredFields([]string{"mesh.example.com/injected"}, []string{"istio-proxy"}, []string{"security.istio.io/tlsMode"})
+	defer SetEquivalenceIgnoredFields(nil, nil, nil)
+
+	baseSpec := apiv1.PodSpec{
+		Containers: []apiv1.Container{
+			{Image: "foo/bar", Name: "foobar"},
+		},
+	}
+	injectedSpec := apiv1.PodSpec{
+		Tolerations: []apiv1.Toleration{
+			{Key: "mesh.example.com/injected", Operator: apiv1.TolerationOpExists},
+		},
+		Containers: []apiv1.Container{
+			{Image: "foo/bar", Name: "foobar"},
+			{Image: "istio/proxyv2", Name: "istio-proxy"},
+		},
+	}
+	differentSpec := apiv1.PodSpec{
+		Tolerations: []apiv1.Toleration{
+			{Key: "dedicated", Operator: apiv1.TolerationOpExists},
+		},
+		Containers: []apiv1.Container{
+			{Image: "foo/bar", Name: "foobar"},
+		},
+	}
+
+	assert.True(t, PodSpecSemanticallyEqual(baseSpec, injectedSpec))
+	assert.False(t, PodSpecSemanticallyEqual(baseSpec, differentSpec))
+
+	assert.True(t, PodLabelsSemanticallyEqual(
+		map[string]string{"app": "foo", "security.istio.io/tlsMode": "istio"},
+		map[string]string{"app": "foo"}))
+	assert.False(t, PodLabelsSemanticallyEqual(
+		map[string]string{"app": "foo"},
+		map[string]string{"app": "bar"}))
+}
+
 func TestSanitizeProjectedVolumesAndMounts(t *testing.T) {
 	projectedSAVol := test.BuildServiceTokenProjectedVolumeSource("path")
 